@@ -3,8 +3,11 @@ package main
 import (
 	"fmt"
 	"log/slog"
+	"math"
+	"os"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/fmiskovic/go-starter/internal/core/configs"
@@ -15,11 +18,19 @@ var defaultConfig ServerConfig
 
 // ServerConfig holds server configuration.
 type ServerConfig struct {
-	ListenAddr   string
-	DbConnString string
-	MaxOpenConn  int
-	MaxIdleConn  int
-	AuthConfig   configs.AuthConfig
+	ListenAddr      string
+	DbConnString    string
+	MaxOpenConn     int
+	MaxIdleConn     int
+	AuthConfig      configs.AuthConfig
+	MailOutbox      string // Directory where outgoing emails are written, if empty emails are only logged
+	RevocationStore string // Revoked tokens store, either "postgres" or "memory"
+
+	LoginAttemptStore string // Failed sign in attempts store, either "postgres" or "memory"
+	Authenticator     string // Sign in credentials are verified by either "password" or "ldap"
+	SessionStore      string // UI sessions store, either "postgres" or "memory"
+
+	PurgeInterval time.Duration // How often soft deleted users past the retention are purged, 0 disables the background purge
 }
 
 func init() {
@@ -39,7 +50,13 @@ func initDefaultServerConfig() ServerConfig {
 
 		conn = fmt.Sprintf("postgresql://%s:%s@%s/%s?sslmode=disable", user, pass, host, dbName)
 
-		listenAddr = utils.GetEnvOrDefault("HTTP_LISTEN_ADDR", ":8080")
+		listenAddr      = utils.GetEnvOrDefault("HTTP_LISTEN_ADDR", ":8080")
+		mailOutbox      = utils.GetEnvOrDefault("MAIL_OUTBOX_DIR", "")
+		revocationStore = utils.GetEnvOrDefault("AUTH_REVOCATION_STORE", "postgres")
+
+		loginAttemptStore = utils.GetEnvOrDefault("AUTH_LOGIN_ATTEMPT_STORE", "postgres")
+		authenticator     = utils.GetEnvOrDefault("AUTH_AUTHENTICATOR", "password")
+		sessionStore      = utils.GetEnvOrDefault("AUTH_SESSION_STORE", "postgres")
 	)
 
 	numCpu := runtime.NumCPU() + 1
@@ -59,27 +76,240 @@ func initDefaultServerConfig() ServerConfig {
 	slog.Info("default server config is initialized")
 
 	return ServerConfig{
-		ListenAddr:   listenAddr,
-		DbConnString: conn,
-		MaxOpenConn:  maxOpenConn,
-		MaxIdleConn:  maxIdleConn,
-		AuthConfig:   initDefaultAuthConfig(),
+		ListenAddr:      listenAddr,
+		DbConnString:    conn,
+		MaxOpenConn:     maxOpenConn,
+		MaxIdleConn:     maxIdleConn,
+		AuthConfig:      initDefaultAuthConfig(),
+		MailOutbox:      mailOutbox,
+		RevocationStore: revocationStore,
+
+		LoginAttemptStore: loginAttemptStore,
+		Authenticator:     authenticator,
+		SessionStore:      sessionStore,
+
+		PurgeInterval: parseDurationEnv("USER_PURGE_INTERVAL", 24*time.Hour),
 	}
 }
 
 func initDefaultAuthConfig() configs.AuthConfig {
-	// parsing DB_MAX_IDLE_CONN variable
-	expTime, err := strconv.Atoi(utils.GetEnvOrDefault("AUTH_JWT_EXP_TIME", "24"))
+	var (
+		tokenExp        = parseDurationEnv("AUTH_JWT_EXP_TIME", 15*time.Minute)
+		refreshTokenExp = parseDurationEnv("AUTH_REFRESH_TOKEN_EXP_TIME", 30*24*time.Hour)
+		confirmationExp = parseDurationEnv("AUTH_CONFIRMATION_EXP_TIME", 24*time.Hour)
+		resetExp        = parseDurationEnv("AUTH_PASSWORD_RESET_EXP_TIME", time.Hour)
+		mfaChallengeExp = parseDurationEnv("AUTH_MFA_CHALLENGE_EXP_TIME", 5*time.Minute)
+		lockoutDuration = parseDurationEnv("AUTH_LOCKOUT_DURATION", 15*time.Minute)
+		lockoutWindow   = parseDurationEnv("AUTH_LOCKOUT_WINDOW", time.Hour)
+		apiKeyExp       = parseDurationEnv("AUTH_API_KEY_EXP_TIME", 90*24*time.Hour)
+		sessionExp      = parseDurationEnv("AUTH_SESSION_EXP_TIME", 8*time.Hour)
+		deletedUserRet  = parseDurationEnv("AUTH_DELETED_USER_RETENTION", 30*24*time.Hour)
+	)
+
+	secret := utils.GetEnvOrDefault("AUTH_JWT_SECRET", "secret")
+
+	keys, err := loadSigningKeys(utils.GetEnvOrDefault("AUTH_JWT_KEYS", ""))
 	if err != nil {
-		slog.Warn("error parsing AUTH_JWT_EXP_TIME variable, using default", "error", err.Error())
-		expTime = 24
+		// falling back to shared secret would silently issue tokens nobody can verify
+		slog.Error("error loading AUTH_JWT_KEYS", "error", err.Error())
+		os.Exit(1)
+	}
+	activeKeyID := utils.GetEnvOrDefault("AUTH_JWT_ACTIVE_KEY", "")
+	if len(keys) > 0 {
+		// every sign in would fail at runtime otherwise
+		if _, err := configs.ActiveSigningKey(keys, activeKeyID); err != nil {
+			slog.Error("AUTH_JWT_ACTIVE_KEY must name a private key of AUTH_JWT_KEYS, at least one private key is required",
+				"activeKey", activeKeyID, "error", err.Error())
+			os.Exit(1)
+		}
 	}
 
-	secret := utils.GetEnvOrDefault("AUTH_JWT_SECRET", "secret")
+	hashAlgorithm := utils.GetEnvOrDefault("AUTH_PASSWORD_HASH_ALGORITHM", configs.HashArgon2id)
+	if hashAlgorithm != configs.HashArgon2id && hashAlgorithm != configs.HashBcrypt {
+		slog.Error("unsupported AUTH_PASSWORD_HASH_ALGORITHM, expected bcrypt or argon2id", "algorithm", hashAlgorithm)
+		os.Exit(1)
+	}
+
+	argon2Threads := parseRangeEnv("AUTH_PASSWORD_ARGON2_THREADS", 4, 1, math.MaxUint8)
+	argon2Time := parseRangeEnv("AUTH_PASSWORD_ARGON2_TIME", 3, 1, math.MaxUint32)
+	// argon2id needs at least 8 KiB of memory per thread
+	argon2Memory := parseRangeEnv("AUTH_PASSWORD_ARGON2_MEMORY", 64*1024, 8*argon2Threads, math.MaxUint32)
 
 	slog.Info("default auth config is initialized")
 	return configs.AuthConfig{
-		TokenExp: time.Duration(expTime),
-		Secret:   secret,
+		TokenExp:        tokenExp,
+		Secret:          secret,
+		Scopes:          parseListEnv("AUTH_JWT_SCOPES"),
+		Keys:            keys,
+		ActiveKeyID:     activeKeyID,
+		RefreshTokenExp: refreshTokenExp,
+		ConfirmationExp: confirmationExp,
+
+		PasswordResetExp: resetExp,
+		PasswordResetURL: utils.GetEnvOrDefault("AUTH_PASSWORD_RESET_URL", ""),
+
+		MfaIssuer:       utils.GetEnvOrDefault("AUTH_MFA_ISSUER", "go-starter"),
+		MfaChallengeExp: mfaChallengeExp,
+
+		LockoutThreshold:   parseIntEnv("AUTH_LOCKOUT_THRESHOLD", 5),
+		LockoutIPThreshold: parseIntEnv("AUTH_LOCKOUT_IP_THRESHOLD", 20),
+		LockoutDuration:    lockoutDuration,
+		LockoutWindow:      lockoutWindow,
+
+		ApiKeyExp: apiKeyExp,
+
+		SessionExp: sessionExp,
+
+		DeletedUserRetention: deletedUserRet,
+
+		PasswordPolicy: configs.PasswordPolicy{
+			MinLength:        parseIntEnv("AUTH_PASSWORD_MIN_LENGTH", 8),
+			MaxLength:        parseIntEnv("AUTH_PASSWORD_MAX_LENGTH", 72),
+			RequireUpper:     utils.GetEnvOrDefault("AUTH_PASSWORD_REQUIRE_UPPER", "false") == "true",
+			RequireLower:     utils.GetEnvOrDefault("AUTH_PASSWORD_REQUIRE_LOWER", "false") == "true",
+			RequireDigit:     utils.GetEnvOrDefault("AUTH_PASSWORD_REQUIRE_DIGIT", "false") == "true",
+			RequireSymbol:    utils.GetEnvOrDefault("AUTH_PASSWORD_REQUIRE_SYMBOL", "false") == "true",
+			DisallowIdentity: utils.GetEnvOrDefault("AUTH_PASSWORD_DISALLOW_IDENTITY", "true") == "true",
+			History:          parseIntEnv("AUTH_PASSWORD_HISTORY", 0),
+			BreachedList:     utils.GetEnvOrDefault("AUTH_PASSWORD_BREACHED_LIST", ""),
+		},
+		PasswordHashing: configs.PasswordHashing{
+			Algorithm:     hashAlgorithm,
+			BcryptCost:    parseIntEnv("AUTH_PASSWORD_BCRYPT_COST", 14),
+			Argon2Memory:  uint32(argon2Memory),
+			Argon2Time:    uint32(argon2Time),
+			Argon2Threads: uint8(argon2Threads),
+			Concurrency:   parseIntEnv("AUTH_PASSWORD_HASH_CONCURRENCY", runtime.NumCPU()),
+			QueueTimeout:  parseDurationEnv("AUTH_PASSWORD_HASH_QUEUE_TIMEOUT", 5*time.Second),
+		},
+
+		OidcProviders: loadOidcProviders(parseListEnv("AUTH_OIDC_PROVIDERS")),
+
+		Ldap: configs.LdapConfig{
+			URL:               utils.GetEnvOrDefault("AUTH_LDAP_URL", ""),
+			StartTLS:          utils.GetEnvOrDefault("AUTH_LDAP_START_TLS", "false") == "true",
+			BindDN:            utils.GetEnvOrDefault("AUTH_LDAP_BIND_DN", ""),
+			BindPassword:      utils.GetEnvOrDefault("AUTH_LDAP_BIND_PASSWORD", ""),
+			BaseDN:            utils.GetEnvOrDefault("AUTH_LDAP_BASE_DN", ""),
+			Timeout:           parseDurationEnv("AUTH_LDAP_TIMEOUT", 10*time.Second),
+			UserFilter:        utils.GetEnvOrDefault("AUTH_LDAP_USER_FILTER", ""),
+			UsernameAttribute: utils.GetEnvOrDefault("AUTH_LDAP_USERNAME_ATTRIBUTE", ""),
+			EmailAttribute:    utils.GetEnvOrDefault("AUTH_LDAP_EMAIL_ATTRIBUTE", ""),
+			NameAttribute:     utils.GetEnvOrDefault("AUTH_LDAP_NAME_ATTRIBUTE", ""),
+			GroupAttribute:    utils.GetEnvOrDefault("AUTH_LDAP_GROUP_ATTRIBUTE", ""),
+			GroupRoles:        parseGroupRolesEnv("AUTH_LDAP_GROUP_ROLES"),
+			DefaultRoles:      parseListEnv("AUTH_LDAP_DEFAULT_ROLES"),
+		},
+	}
+}
+
+// parseGroupRolesEnv parses comma separated group to role mapping like "admins:ROLE_ADMIN,staff:ROLE_MODERATOR".
+// Group is matched by its DN or CN, group mapped more than once gets all the roles.
+func parseGroupRolesEnv(key string) map[string][]string {
+	res := map[string][]string{}
+	for _, entry := range parseListEnv(key) {
+		group, role, ok := strings.Cut(entry, ":")
+		if !ok || utils.IsBlank(group) || utils.IsBlank(role) {
+			slog.Warn("invalid "+key+" entry, expected group:role", "entry", entry)
+			continue
+		}
+		group = strings.TrimSpace(group)
+		res[group] = append(res[group], strings.TrimSpace(role))
+	}
+	return res
+}
+
+// loadOidcProviders loads configuration of the named identity providers,
+// e.g. provider "google" is configured with AUTH_OIDC_GOOGLE_ISSUER, AUTH_OIDC_GOOGLE_CLIENT_ID etc.
+func loadOidcProviders(names []string) []configs.OidcProvider {
+	var providers []configs.OidcProvider
+	for _, name := range names {
+		prefix := "AUTH_OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		p := configs.OidcProvider{
+			Name:         name,
+			IssuerURL:    utils.GetEnvOrDefault(prefix+"ISSUER", ""),
+			ClientID:     utils.GetEnvOrDefault(prefix+"CLIENT_ID", ""),
+			ClientSecret: utils.GetEnvOrDefault(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  utils.GetEnvOrDefault(prefix+"REDIRECT_URL", ""),
+			Scopes:       parseListEnv(prefix + "SCOPES"),
+			DefaultRoles: parseListEnv(prefix + "DEFAULT_ROLES"),
+		}
+		if utils.IsBlank(p.IssuerURL) || utils.IsBlank(p.ClientID) || utils.IsBlank(p.RedirectURL) {
+			slog.Warn("identity provider is not fully configured, skipping it", "provider", name)
+			continue
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
+
+// loadSigningKeys loads PEM encoded keys from comma separated list like "key-2024=/keys/a.pem,key-2023=/keys/b.pem".
+func loadSigningKeys(v string) ([]configs.SigningKey, error) {
+	var keys []configs.SigningKey
+	for _, entry := range strings.Split(v, ",") {
+		if utils.IsBlank(entry) {
+			continue
+		}
+
+		id, path, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || utils.IsBlank(id) || utils.IsBlank(path) {
+			return nil, fmt.Errorf("invalid key entry %q, expected kid=path", entry)
+		}
+
+		k, err := configs.LoadSigningKey(id, path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// parseListEnv parses comma separated variable, blank items are skipped.
+func parseListEnv(key string) []string {
+	var res []string
+	for _, v := range strings.Split(utils.GetEnvOrDefault(key, ""), ",") {
+		if !utils.IsBlank(v) {
+			res = append(res, strings.TrimSpace(v))
+		}
+	}
+	return res
+}
+
+// parseIntEnv parses integer variable.
+func parseIntEnv(key string, def int) int {
+	n, err := strconv.Atoi(utils.GetEnvOrDefault(key, strconv.Itoa(def)))
+	if err != nil {
+		slog.Warn("error parsing "+key+" variable, using default", "error", err.Error())
+		return def
+	}
+	return n
+}
+
+// parseRangeEnv parses integer variable that must be within min and max, startup fails otherwise.
+func parseRangeEnv(key string, def int64, min int64, max int64) int64 {
+	v := utils.GetEnvOrDefault(key, strconv.FormatInt(def, 10))
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < min || n > max {
+		slog.Error("invalid "+key+" variable", "value", v, "min", min, "max", max)
+		os.Exit(1)
+	}
+	return n
+}
+
+// parseDurationEnv parses duration variable like "15m" or "720h".
+// Plain number is treated as number of hours.
+func parseDurationEnv(key string, def time.Duration) time.Duration {
+	v := utils.GetEnvOrDefault(key, def.String())
+
+	if hours, err := strconv.Atoi(v); err == nil {
+		return time.Duration(hours) * time.Hour
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		slog.Warn("error parsing "+key+" variable, using default", "error", err.Error())
+		return def
 	}
+	return d
 }
@@ -15,6 +15,7 @@ func main() {
 		Commands: []*cli.Command{
 			newServeCmd(),
 			newMigrationCmd(migrations.Migrations),
+			newUsersCmd(),
 		},
 	}
 	if err := app.Run(os.Args); err != nil {
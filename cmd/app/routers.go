@@ -2,11 +2,15 @@ package main
 
 import (
 	"github.com/fmiskovic/go-starter/internal/adapters/handlers"
+	"github.com/fmiskovic/go-starter/internal/adapters/handlers/admin"
 	"github.com/fmiskovic/go-starter/internal/adapters/handlers/auth"
+	"github.com/fmiskovic/go-starter/internal/adapters/handlers/role"
 	"github.com/fmiskovic/go-starter/internal/adapters/handlers/user"
 	"github.com/fmiskovic/go-starter/internal/adapters/repos"
 	"github.com/fmiskovic/go-starter/internal/core/configs"
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
 	"github.com/fmiskovic/go-starter/internal/core/services"
+	"github.com/fmiskovic/go-starter/internal/utils/jwks"
 	"github.com/gofiber/contrib/swagger"
 	"github.com/gofiber/fiber/v2"
 	"github.com/uptrace/bun"
@@ -14,34 +18,86 @@ import (
 
 type Router struct {
 	service        services.UserService
+	roleService    services.RoleService
 	app            *fiber.App
 	authConfig     configs.AuthConfig
 	authMiddleware auth.Middleware
 }
 
 // NewRouter instantiates new user.Router
-func newRouter(db *bun.DB, app *fiber.App, authConfig configs.AuthConfig) Router {
+func newRouter(db *bun.DB, app *fiber.App, config ServerConfig) Router {
+	authConfig := config.AuthConfig
+	revocations := initRevocationStore(db, config)
+
 	repo := repos.NewUserRepo(db)
-	svc := services.NewUserService(repo, authConfig)
-	authMiddleware := auth.NewMiddleware(authConfig)
-	return Router{service: svc, app: app, authConfig: authConfig, authMiddleware: authMiddleware}
+	hashing := initHashingPool(authConfig)
+	svc := services.NewUserService(repo, authConfig,
+		services.WithHashingPool(hashing),
+		services.WithMailer(initMailer(config)),
+		services.WithRefreshTokenRepo(repos.NewRefreshTokenRepo(db)),
+		services.WithRevocationStore(revocations),
+		services.WithMfaRepo(repos.NewMfaRepo(db)),
+		services.WithLoginAttemptStore(initLoginAttemptStore(db, config)),
+		services.WithApiKeyRepo(repos.NewApiKeyRepo(db)),
+		services.WithIdentityProviders(initIdentityProviders(authConfig)),
+		services.WithAuthenticator(initAuthenticator(repo, hashing, config)),
+		services.WithSessionStore(initSessionStore(db, config)),
+		services.WithBreachedPasswords(initBreachedPasswords(authConfig)),
+	)
+	authMiddleware := auth.NewMiddleware(authConfig, revocations, auth.WithApiKeys(svc), auth.WithSessions(svc))
+	roleSvc := services.NewRoleService(repos.NewRoleRepo(db))
+	return Router{service: svc, roleService: roleSvc, app: app, authConfig: authConfig, authMiddleware: authMiddleware}
 }
 
 // initUserRouters initializes user management api.
 func (r Router) initUserRouters() {
 	api := r.app.Group("/api")
 	v1 := api.Group("/v1")
-	userGroup := v1.Group("/user", r.authMiddleware.AdminAuthenticated())
+	userGroup := v1.Group("/user", r.authMiddleware.Authenticated())
 
 	handler := user.NewHandler(r.service)
+	m := r.authMiddleware
+
+	userGroup.Get("/:id", m.RequireScopes(security.PERM_USER_READ), handler.HandleGetById())
+	userGroup.Get("/", m.RequireScopes(security.PERM_USER_READ), handler.HandleGetPage())
+	userGroup.Delete("/:id", m.RequireScopes(security.PERM_USER_DELETE), handler.HandleDeleteById())
+	userGroup.Post("/", m.RequireScopes(security.PERM_USER_WRITE), handler.HandleCreate())
+	userGroup.Put("/", m.RequireScopes(security.PERM_USER_WRITE), handler.HandleUpdate())
+	userGroup.Patch("/:id", m.RequireScopes(security.PERM_USER_WRITE), handler.HandlePatch())
+	userGroup.Post("/roles", m.RequireScopes(security.PERM_USER_ROLES), handler.HandleUserRoles())
+	userGroup.Post("/:id/enabledisable", m.RequireScopes(security.PERM_USER_ENABLE), handler.HandleEnableDisable())
+	userGroup.Post("/:id/restore", m.RequireScopes(security.PERM_USER_DELETE), handler.HandleRestore())
+	userGroup.Post("/:id/unlock", m.RequireScopes(security.PERM_USER_ENABLE), handler.HandleUnlock())
+	userGroup.Post("/:id/logout", m.RequireScopes(security.PERM_USER_LOGOUT), handler.HandleSignOutAll())
+	userGroup.Delete("/:id/mfa", m.RequireScopes(security.PERM_USER_MFA), handler.HandleResetMfa())
+	userGroup.Put("/:id/password", m.RequireScopes(security.PERM_USER_PASSWORD), handler.HandleSetPassword())
 
-	userGroup.Get("/:id", handler.HandleGetById())
-	userGroup.Get("/", handler.HandleGetPage())
-	userGroup.Delete("/:id", handler.HandleDeleteById())
-	userGroup.Post("/", handler.HandleCreate())
-	userGroup.Put("/", handler.HandleUpdate())
-	userGroup.Post("/roles", handler.HandleUserRoles())
-	userGroup.Post("/:id/enabledisable", handler.HandleEnableDisable())
+	// self-service profile of the signed in user, no scopes are required, API keys can only read it
+	meGroup := v1.Group("/me", m.Authenticated())
+	meGroup.Get("/", handler.HandleGetMe())
+	meGroup.Patch("/", handler.HandlePatchMe())
+	meGroup.Delete("/", handler.HandleDeleteMe())
+}
+
+// initRoleRouters initializes role and permission catalog management api.
+func (r Router) initRoleRouters() {
+	v1 := r.app.Group("/api/v1")
+	roleGroup := v1.Group("/role", r.authMiddleware.Authenticated())
+	permissionGroup := v1.Group("/permission", r.authMiddleware.Authenticated())
+
+	handler := role.NewHandler(r.roleService)
+	m := r.authMiddleware
+
+	roleGroup.Get("/", m.RequireScopes(security.PERM_ROLE_READ), handler.HandleGetAll())
+	roleGroup.Get("/:id", m.RequireScopes(security.PERM_ROLE_READ), handler.HandleGetById())
+	roleGroup.Post("/", m.RequireScopes(security.PERM_ROLE_WRITE), handler.HandleCreate())
+	roleGroup.Put("/", m.RequireScopes(security.PERM_ROLE_WRITE), handler.HandleUpdate())
+	roleGroup.Delete("/:id", m.RequireScopes(security.PERM_ROLE_WRITE), handler.HandleDeleteById())
+	roleGroup.Put("/:id/permissions", m.RequireScopes(security.PERM_ROLE_WRITE), handler.HandleSetPermissions())
+
+	permissionGroup.Get("/", m.RequireScopes(security.PERM_ROLE_READ), handler.HandleGetPermissions())
+	permissionGroup.Post("/", m.RequireScopes(security.PERM_ROLE_WRITE), handler.HandleCreatePermission())
+	permissionGroup.Delete("/:id", m.RequireScopes(security.PERM_ROLE_WRITE), handler.HandleDeletePermissionById())
 }
 
 func (r Router) initAuthRouters() {
@@ -49,10 +105,25 @@ func (r Router) initAuthRouters() {
 
 	handler := auth.NewHandler(r.service)
 	a.Post("/login", handler.HandleSignIn())
-	a.Get("/logout", handler.HandleSignOut())
+	a.Post("/refresh", handler.HandleRefresh())
+	a.Get("/logout", r.authMiddleware.Authenticated(), handler.HandleSignOut())
+	a.Post("/logout/all", r.authMiddleware.Authenticated(), handler.HandleSignOutAll())
 	a.Post("/register", handler.HandleSignUp())
 	a.Post("/email", handler.HandleConfirmEmail())
-	a.Post("/password", handler.HandleChangePassword())
+	a.Post("/password", r.authMiddleware.Authenticated(), handler.HandleChangePassword())
+	a.Post("/password/forgot", handler.HandleForgotPassword())
+	a.Post("/password/reset", handler.HandleResetPassword())
+	a.Post("/mfa/verify", handler.HandleVerifyMfa())
+	a.Post("/mfa/enroll", r.authMiddleware.Authenticated(), handler.HandleEnrollMfa())
+	a.Post("/mfa/enable", r.authMiddleware.Authenticated(), handler.HandleEnableMfa())
+	a.Post("/mfa/disable", r.authMiddleware.Authenticated(), handler.HandleDisableMfa())
+	a.Post("/apikeys", r.authMiddleware.Authenticated(), handler.HandleCreateApiKey())
+	a.Get("/apikeys", r.authMiddleware.Authenticated(), handler.HandleGetApiKeys())
+	a.Delete("/apikeys/:id", r.authMiddleware.Authenticated(), handler.HandleRevokeApiKey())
+	a.Get("/oidc/:provider/login", handler.HandleOidcLogin())
+	a.Get("/oidc/:provider/callback", handler.HandleOidcCallback())
+
+	r.app.Get("/.well-known/jwks.json", auth.HandleJWKS(jwks.New(r.authConfig)))
 }
 
 // initStaticRoutes initializes static view handlers to serve the UI.
@@ -60,13 +131,27 @@ func (r Router) initStaticRouters() {
 	r.app.Static("/public", "./public")
 
 	r.app.Use(handlers.FlashMiddleware)
+	r.app.Use(handlers.CsrfMiddleware())
+
+	handler := auth.NewHandler(r.service)
+	adminHandler := admin.NewHandler(r.service, r.roleService)
+	m := r.authMiddleware
 
 	r.app.Get("/", handlers.HandleHome)
 	r.app.Get("/about", handlers.HandleAbout)
-	r.app.Get("/users", handlers.HandleUsers)
 	r.app.Get("/login", handlers.HandleLogin)
+	r.app.Post("/login", handler.HandleSessionLogin())
+	r.app.Post("/logout", handler.HandleSessionLogout())
 	r.app.Get("/flash", handlers.HandleFlash)
 
+	users := r.app.Group("/users", m.SessionAuthenticated())
+	users.Get("/", m.SessionRequireScopes(security.PERM_USER_READ), adminHandler.HandleList())
+	users.Get("/:id", m.SessionRequireScopes(security.PERM_USER_READ), adminHandler.HandleDetails())
+	users.Post("/:id", m.SessionRequireScopes(security.PERM_USER_WRITE), adminHandler.HandleUpdate())
+	users.Post("/:id/enabledisable", m.SessionRequireScopes(security.PERM_USER_ENABLE), adminHandler.HandleEnableDisable())
+	users.Post("/:id/roles", m.SessionRequireScopes(security.PERM_USER_ROLES), adminHandler.HandleRoles())
+	users.Post("/:id/delete", m.SessionRequireScopes(security.PERM_USER_DELETE), adminHandler.HandleDelete())
+
 	r.app.Use(handlers.NotFoundMiddleware)
 }
 
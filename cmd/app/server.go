@@ -1,23 +1,35 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"html/template"
 	"log"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/fmiskovic/go-starter/internal/adapters/breached"
 	"github.com/fmiskovic/go-starter/internal/adapters/db"
+	"github.com/fmiskovic/go-starter/internal/adapters/ldap"
+	"github.com/fmiskovic/go-starter/internal/adapters/mailer"
+	"github.com/fmiskovic/go-starter/internal/adapters/memory"
+	"github.com/fmiskovic/go-starter/internal/adapters/oidc"
+	"github.com/fmiskovic/go-starter/internal/adapters/repos"
 	"github.com/fmiskovic/go-starter/internal/utils"
+	"github.com/fmiskovic/go-starter/internal/utils/password"
 
 	"github.com/fmiskovic/go-starter/internal/core/configs"
+	"github.com/fmiskovic/go-starter/internal/core/ports"
+	"github.com/fmiskovic/go-starter/internal/core/services"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/pprof"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/template/django/v3"
+	"github.com/google/uuid"
 	"github.com/uptrace/bun"
 )
 
@@ -34,7 +46,7 @@ func newServer(config ServerConfig) Server {
 	if err != nil {
 		log.Fatal(err)
 	}
-	app := initApp(bunDb, config.AuthConfig)
+	app := initApp(bunDb, config)
 	return Server{
 		Config: config,
 		Db:     bunDb,
@@ -53,10 +65,36 @@ func (s Server) start() error {
 		return errors.New("server is not ready")
 	}
 
+	if s.Config.PurgeInterval > 0 {
+		go s.purgeDeletedUsers(context.Background())
+	}
+
 	slog.Info("the app is up and running...", "address", s.Config.ListenAddr)
 	return s.App.Listen(s.Config.ListenAddr)
 }
 
+// purgeDeletedUsers periodically removes soft deleted users whose retention has expired.
+func (s Server) purgeDeletedUsers(ctx context.Context) {
+	svc := services.NewUserService(repos.NewUserRepo(s.Db), s.Config.AuthConfig)
+
+	ticker := time.NewTicker(s.Config.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		n, err := svc.PurgeDeleted(ctx)
+		if err != nil {
+			slog.Error("failed to purge deleted users", "error", err.Error())
+		} else if n > 0 {
+			slog.Info("deleted users are purged", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // ----- INITS ----- //
 
 func initDb(config ServerConfig) (*bun.DB, error) {
@@ -67,11 +105,13 @@ func initDb(config ServerConfig) (*bun.DB, error) {
 	}.OpenDb()
 }
 
-func initApp(db *bun.DB, authConfig configs.AuthConfig) *fiber.App {
+func initApp(db *bun.DB, config ServerConfig) *fiber.App {
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: true,
 		PassLocalsToViews:     true,
 		Views:                 initViews(),
+		// client IP is read from the header when running behind reverse proxy, e.g. "X-Forwarded-For"
+		ProxyHeader: utils.GetEnvOrDefault("HTTP_PROXY_HEADER", ""),
 	})
 
 	app.Use(cors.New(cors.Config{
@@ -83,7 +123,10 @@ func initApp(db *bun.DB, authConfig configs.AuthConfig) *fiber.App {
 		app.Use(pprof.New())
 	}
 
-	router := newRouter(db, app, authConfig)
+	// registered before the routers, otherwise panic of a handler is not recovered
+	app.Use(recover.New())
+
+	router := newRouter(db, app, config)
 
 	// init swagger
 	router.initSwaggerRouters()
@@ -91,13 +134,76 @@ func initApp(db *bun.DB, authConfig configs.AuthConfig) *fiber.App {
 	router.initAuthRouters()
 	// init user api handlers
 	router.initUserRouters()
+	// init role api handlers
+	router.initRoleRouters()
 	// init static handlers
 	router.initStaticRouters()
 
-	app.Use(recover.New())
 	return app
 }
 
+func initMailer(config ServerConfig) ports.Mailer {
+	if utils.IsBlank(config.MailOutbox) {
+		return mailer.NewLogMailer()
+	}
+	return mailer.NewFileMailer(config.MailOutbox)
+}
+
+func initRevocationStore(db *bun.DB, config ServerConfig) ports.RevocationStore[uuid.UUID] {
+	if config.RevocationStore == "memory" {
+		return memory.NewRevocationStore()
+	}
+	return repos.NewRevocationRepo(db)
+}
+
+func initSessionStore(db *bun.DB, config ServerConfig) ports.SessionStore[uuid.UUID] {
+	if config.SessionStore == "memory" {
+		return memory.NewSessionStore()
+	}
+	return repos.NewSessionRepo(db)
+}
+
+func initLoginAttemptStore(db *bun.DB, config ServerConfig) ports.LoginAttemptStore {
+	if config.LoginAttemptStore == "memory" {
+		return memory.NewLoginAttemptStore()
+	}
+	return repos.NewLoginAttemptRepo(db)
+}
+
+func initHashingPool(config configs.AuthConfig) *services.HashingPool {
+	h := config.PasswordHashing
+	return services.NewHashingPool(password.NewHasher(h), h.Concurrency, h.QueueTimeout)
+}
+
+func initAuthenticator(repo ports.UserRepo[uuid.UUID], hashing *services.HashingPool, config ServerConfig) ports.Authenticator {
+	if config.Authenticator == "ldap" {
+		return services.NewDirectoryAuthenticator(ldap.NewDirectory(config.AuthConfig.Ldap), repo, config.AuthConfig.Ldap)
+	}
+	return services.NewPasswordAuthenticator(repo, hashing)
+}
+
+func initBreachedPasswords(config configs.AuthConfig) ports.BreachedPasswords {
+	if utils.IsBlank(config.PasswordPolicy.BreachedList) {
+		return nil
+	}
+	l, err := breached.Load(config.PasswordPolicy.BreachedList)
+	if err != nil {
+		// starting without the list would silently accept breached passwords
+		slog.Error("error loading AUTH_PASSWORD_BREACHED_LIST", "error", err.Error())
+		os.Exit(1)
+	}
+	slog.Info("breached password list is loaded", "hashes", l.Len())
+	return l
+}
+
+func initIdentityProviders(config configs.AuthConfig) map[string]ports.IdentityProvider {
+	providers := make(map[string]ports.IdentityProvider, len(config.OidcProviders))
+	for _, p := range config.OidcProviders {
+		providers[p.Name] = oidc.NewProvider(p)
+	}
+	return providers
+}
+
 func initViews() *django.Engine {
 	engine := django.New("./views", ".html")
 	engine.Reload(true)
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fmiskovic/go-starter/internal/adapters/repos"
+	"github.com/fmiskovic/go-starter/internal/core/services"
+	"github.com/urfave/cli/v2"
+)
+
+// newUsersCmd configures set of user maintenance cli commands.
+func newUsersCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "users",
+		Usage: "user maintenance",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "purge",
+				Usage: "permanently remove soft deleted users past the retention",
+				Action: func(c *cli.Context) error {
+					bunDb, err := connectDb()
+					if err != nil {
+						return err
+					}
+					defer bunDb.Close()
+
+					svc := services.NewUserService(repos.NewUserRepo(bunDb), defaultConfig.AuthConfig)
+					n, err := svc.PurgeDeleted(c.Context)
+					if err != nil {
+						return err
+					}
+					fmt.Printf("purged %d deleted users\n", n)
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,95 @@
+package breached
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// prefixLen is length of the hash prefix the hashes are grouped by, the same one k-anonymity range queries use.
+const prefixLen = 5
+
+// HashList is implementation of ports.BreachedPasswords interface backed by locally loaded list of SHA-1 hashes,
+// e.g. downloaded from Have I Been Pwned. Hashes are grouped into ranges by their prefix the same way
+// k-anonymity range API serves them, so password is looked up only within the range of its hash prefix.
+type HashList struct {
+	ranges map[string]map[string]struct{} // hash suffixes keyed by hash prefix
+	size   int
+}
+
+// Load reads hash list from the file, see Parse for the format.
+func Load(path string) (*HashList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Parse reads hash list with one hex encoded SHA-1 hash per line, optionally followed by ":count" of its occurrences.
+// Blank lines and lines starting with # are skipped.
+func Parse(r io.Reader) (*HashList, error) {
+	l := &HashList{ranges: map[string]map[string]struct{}{}}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(strings.TrimSpace(hash))
+		if len(hash) != 2*sha1.Size {
+			return nil, fmt.Errorf("line %d: invalid SHA-1 hash %q", n, hash)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("line %d: invalid SHA-1 hash %q", n, hash)
+		}
+
+		l.add(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// IsBreached returns true if SHA-1 hash of the password is on the list.
+func (l *HashList) IsBreached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	_, ok := l.ranges[hash[:prefixLen]][hash[prefixLen:]]
+	return ok, nil
+}
+
+// Len returns number of hashes on the list.
+func (l *HashList) Len() int {
+	return l.size
+}
+
+func (l *HashList) add(hash string) {
+	prefix, suffix := hash[:prefixLen], hash[prefixLen:]
+
+	r, ok := l.ranges[prefix]
+	if !ok {
+		r = map[string]struct{}{}
+		l.ranges[prefix] = r
+	}
+	if _, ok := r[suffix]; !ok {
+		r[suffix] = struct{}{}
+		l.size++
+	}
+}
@@ -0,0 +1,84 @@
+package breached
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestHashList_IsBreached(t *testing.T) {
+	assert := is.New(t)
+
+	// hashes of "password" and "P@ssw0rd", the latter in lowercase without count
+	list := "# breached passwords\n" +
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\n" +
+		"\n" +
+		"21bd12dc183f740ee76f27b78eb39c8ad972a757\n"
+
+	l, err := Parse(strings.NewReader(list))
+	assert.NoErr(err)
+	assert.Equal(l.Len(), 2)
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{name: "given listed password should return true", password: "password", want: true},
+		{name: "given listed password with lowercase hash should return true", password: "P@ssw0rd", want: true},
+		{name: "given password that is not listed should return false", password: "password1", want: false},
+		{name: "given different letter case should return false", password: "Password", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.IsBreached(context.Background(), tt.password)
+			assert.NoErr(err)
+			assert.Equal(got, tt.want)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		wantLen int
+		wantErr bool
+	}{
+		{name: "given duplicate hashes should count them once", list: "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:1\n5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8:2\n", wantLen: 1},
+		{name: "given empty list should return empty list", list: "", wantLen: 0},
+		{name: "given short hash should return error", list: "5BAA61E4C9B93F3F:1\n", wantErr: true},
+		{name: "given non hex hash should return error", list: "ZBAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := is.New(t)
+
+			l, err := Parse(strings.NewReader(tt.list))
+			if tt.wantErr {
+				assert.True(err != nil)
+				return
+			}
+			assert.NoErr(err)
+			assert.Equal(l.Len(), tt.wantLen)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	assert := is.New(t)
+
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	assert.NoErr(os.WriteFile(path, []byte("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\n"), 0o600))
+
+	l, err := Load(path)
+	assert.NoErr(err)
+	assert.Equal(l.Len(), 1)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.True(err != nil)
+}
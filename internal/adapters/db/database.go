@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"log/slog"
 
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/fmiskovic/go-starter/internal/core/domain/user"
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect/pgdialect"
 	"github.com/uptrace/bun/driver/pgdriver"
@@ -33,6 +35,8 @@ func (db Database) OpenDb() (*bun.DB, error) {
 	sqlDb.SetMaxOpenConns(db.MaxOpenConn)
 	sqlDb.SetMaxIdleConns(db.MaxIdleConn)
 	bunDb := bun.NewDB(sqlDb, pgdialect.New())
+	// many-to-many join models must be registered before they are used in relations
+	bunDb.RegisterModel((*user.UserRole)(nil), (*security.RolePermission)(nil))
 	// if utils.IsDev() {
 	// 	bunDb.AddQueryHook(bundebug.NewQueryHook(bundebug.WithVerbose(true)))
 	// }
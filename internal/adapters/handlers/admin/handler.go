@@ -0,0 +1,342 @@
+package admin
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain"
+	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/core/ports"
+	"github.com/fmiskovic/go-starter/internal/core/validators"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sujit-baniya/flash"
+)
+
+// pageSizes are page sizes the user list can be shown with.
+var pageSizes = []int{10, 25, 50}
+
+// sortable are user.SortFields the user list can be sorted on by clicking the column header.
+var sortable = []string{"email", "fullName", "createdAt"}
+
+var errSelf = errors.New("you can not change roles, disable or delete your own account")
+
+// Handler serves server-rendered admin pages for user management.
+// Pages must be chained after auth.Middleware SessionAuthenticated and forms are protected with CSRF token.
+type Handler struct {
+	service     ports.UserService[uuid.UUID]
+	roleService ports.RoleService[uuid.UUID]
+	validator   validators.Validator
+}
+
+func NewHandler(service ports.UserService[uuid.UUID], roleService ports.RoleService[uuid.UUID]) Handler {
+	return Handler{
+		service:     service,
+		roleService: roleService,
+		validator:   validators.New(),
+	}
+}
+
+// profileForm is the user profile edit form.
+type profileForm struct {
+	Email       string `form:"email"`
+	FullName    string `form:"fullname"`
+	DateOfBirth string `form:"dateOfBirth"` // yyyy-mm-dd as sent by date input
+	Location    string `form:"location"`
+	Gender      string `form:"gender"`
+	Version     int64  `form:"version"` // version of the user the form was rendered with
+}
+
+// HandleList renders page of users, it is sorted by "sort" and "dir" query params.
+func (h Handler) HandleList() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse query params
+		size := c.QueryInt("size", pageSizes[0])
+		if !slices.Contains(pageSizes, size) {
+			size = pageSizes[0]
+		}
+		offset := max(c.QueryInt("offset", 0), 0)
+
+		sort, dir := c.Query("sort", "createdAt"), strings.ToLower(c.Query("dir", "desc"))
+		if !slices.Contains(sortable, sort) {
+			sort = "createdAt"
+		}
+		if dir != "asc" {
+			dir = "desc"
+		}
+		pageSort, err := user.SortFields.ParseSort(sort + " " + dir)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidSort)).Error())
+		}
+
+		// call core service
+		page, err := h.service.GetPage(c.Context(), domain.Pageable{
+			Size:   size,
+			Offset: offset,
+			Sort:   pageSort,
+		}, user.Filter{})
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrGetPage)).Error())
+		}
+
+		// response
+		listURL := func(offset int, sort string, dir string) string {
+			q := url.Values{}
+			q.Set("size", strconv.Itoa(size))
+			q.Set("offset", strconv.Itoa(offset))
+			q.Set("sort", sort)
+			q.Set("dir", dir)
+			return "/users?" + q.Encode()
+		}
+		sortURLs := fiber.Map{}
+		for _, key := range sortable {
+			// clicking the column the list is sorted by reverses the direction
+			next := "asc"
+			if key == sort && dir == "asc" {
+				next = "desc"
+			}
+			sortURLs[key] = listURL(0, key, next)
+		}
+
+		res := fiber.Map{
+			"users":    page.Elements,
+			"total":    page.TotalElements,
+			"from":     min(offset+1, page.TotalElements),
+			"to":       offset + len(page.Elements),
+			"sort":     sort,
+			"dir":      dir,
+			"sortURLs": sortURLs,
+		}
+		if offset > 0 {
+			res["prevURL"] = listURL(max(offset-size, 0), sort, dir)
+		}
+		if offset+size < page.TotalElements {
+			res["nextURL"] = listURL(offset+size, sort, dir)
+		}
+		return c.Render("users/list", res)
+	}
+}
+
+// HandleDetails renders user details with profile edit form and role assignment.
+func (h Handler) HandleDetails() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse path params
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).Render("error/404", nil)
+		}
+
+		// call core service
+		u, err := h.service.GetById(c.Context(), id)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).Render("error/404", nil)
+		}
+		catalog, err := h.roleService.GetAll(c.Context())
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrGetAll)).Error())
+		}
+
+		// response
+		roles := make([]fiber.Map, len(catalog))
+		for i, r := range catalog {
+			roles[i] = fiber.Map{
+				"name":        r.Name,
+				"description": r.Description,
+				"assigned":    slices.Contains(u.Roles, r.Name),
+			}
+		}
+		dateOfBirth := ""
+		if !u.DateOfBirth.IsZero() {
+			dateOfBirth = u.DateOfBirth.Format(time.DateOnly)
+		}
+		return c.Render("users/details", fiber.Map{
+			"u":           u,
+			"dateOfBirth": dateOfBirth,
+			"genders":     []user.GenderDto{"Male", "Female", "Other"},
+			"roles":       roles,
+			"self":        isSelf(c, id),
+		})
+	}
+}
+
+// HandleUpdate updates user profile with the submitted form.
+func (h Handler) HandleUpdate() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse path params
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).Render("error/404", nil)
+		}
+		back := "/users/" + id.String()
+
+		// parse request body
+		form := new(profileForm)
+		if err := c.BodyParser(form); err != nil {
+			return failed(c, back, apiErr.ErrParseReqBody)
+		}
+		// form holds the whole profile, fields left empty are cleared
+		req := &user.PatchRequest{
+			ID:       id.String(),
+			Version:  form.Version,
+			Email:    strings.TrimSpace(form.Email),
+			FullName: strings.TrimSpace(form.FullName),
+			Location: strings.TrimSpace(form.Location),
+			Gender:   user.GenderDto(form.Gender),
+		}
+		if form.DateOfBirth != "" {
+			dateOfBirth, err := time.Parse(time.DateOnly, form.DateOfBirth)
+			if err != nil {
+				return failed(c, back, apiErr.ErrParseReqBody)
+			}
+			req.DateOfBirth = &dateOfBirth
+		}
+
+		// validate request
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			return failed(c, back, errors.New(strings.Join(errs, " and ")))
+		}
+
+		// call core service
+		if _, err := h.service.Patch(c.Context(), req); err != nil {
+			if errors.Is(err, apiErr.ErrVersionMismatch) {
+				return failed(c, back, apiErr.ErrVersionMismatch)
+			}
+			return failed(c, back, apiErr.ErrEntityUpdate)
+		}
+
+		// response
+		return succeeded(c, back, "Profile is updated")
+	}
+}
+
+// HandleEnableDisable enables user if disabled and vice versa.
+func (h Handler) HandleEnableDisable() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse path params
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).Render("error/404", nil)
+		}
+		back := "/users/" + id.String()
+
+		// admin would lock themselves out
+		if isSelf(c, id) {
+			return failed(c, back, errSelf)
+		}
+
+		// call core service
+		if err := h.service.EnableDisable(c.Context(), id); err != nil {
+			return failed(c, back, apiErr.ErrEntityUpdate)
+		}
+
+		// response
+		return succeeded(c, back, "User is enabled or disabled")
+	}
+}
+
+// HandleRoles assigns roles checked in the form and removes the others.
+func (h Handler) HandleRoles() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse path params
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).Render("error/404", nil)
+		}
+		back := "/users/" + id.String()
+
+		// admin would revoke their own admin role
+		if isSelf(c, id) {
+			return failed(c, back, errSelf)
+		}
+
+		// parse request body, unchecked boxes are not sent at all
+		var checked []string
+		for _, v := range c.Request().PostArgs().PeekMulti("roles") {
+			checked = append(checked, string(v))
+		}
+
+		// call core service
+		u, err := h.service.GetById(c.Context(), id)
+		if err != nil {
+			return failed(c, back, apiErr.ErrGetById)
+		}
+
+		var add, remove []string
+		for _, r := range checked {
+			if !slices.Contains(u.Roles, r) && !slices.Contains(add, r) {
+				add = append(add, r)
+			}
+		}
+		for _, r := range u.Roles {
+			if !slices.Contains(checked, r) {
+				remove = append(remove, r)
+			}
+		}
+
+		if len(add) > 0 {
+			if err := h.service.AddRoles(c.Context(), add, id); err != nil {
+				return failed(c, back, err)
+			}
+		}
+		if len(remove) > 0 {
+			if err := h.service.RemoveRoles(c.Context(), remove, id); err != nil {
+				return failed(c, back, apiErr.ErrEntityDelete)
+			}
+		}
+
+		// response
+		return succeeded(c, back, "Roles are updated")
+	}
+}
+
+// HandleDelete deletes user and returns to the user list.
+func (h Handler) HandleDelete() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse path params
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).Render("error/404", nil)
+		}
+
+		if isSelf(c, id) {
+			return failed(c, "/users/"+id.String(), errSelf)
+		}
+
+		// call core service
+		if err := h.service.DeleteById(c.Context(), id); err != nil {
+			return failed(c, "/users/"+id.String(), apiErr.ErrDeleteById)
+		}
+
+		// response
+		return succeeded(c, "/users", "User is deleted")
+	}
+}
+
+// succeeded redirects to the page and flashes the message.
+func succeeded(c *fiber.Ctx, to string, msg string) error {
+	return flash.WithSuccess(c, fiber.Map{"systemMessage": msg}).Redirect(to, fiber.StatusSeeOther)
+}
+
+// failed redirects to the page and flashes the error.
+func failed(c *fiber.Ctx, to string, err error) error {
+	return flash.WithError(c, fiber.Map{"systemMessage": err.Error()}).Redirect(to, fiber.StatusSeeOther)
+}
+
+// isSelf returns true if the signed in user is the user with the id.
+func isSelf(c *fiber.Ctx, id uuid.UUID) bool {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return false
+	}
+	sub, err := token.Claims.GetSubject()
+	return err == nil && sub == id.String()
+}
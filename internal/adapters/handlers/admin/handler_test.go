@@ -0,0 +1,196 @@
+package admin
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/fmiskovic/go-starter/internal/adapters/repos"
+	"github.com/fmiskovic/go-starter/internal/core/configs"
+	"github.com/fmiskovic/go-starter/internal/core/services"
+	"github.com/fmiskovic/go-starter/internal/utils/testx"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/template/django/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/matryer/is"
+)
+
+const (
+	adminId = "220cea28-b2b0-4051-9eb6-9a99e451af01"
+	userId  = "220cea28-b2b0-4051-9eb6-9a99e451af02"
+)
+
+func TestHandler(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	engine := django.New("../../../../views", ".html")
+	engine.AddFunc("css", func(name string) template.HTML { return "" })
+
+	ts, err := testx.SetUpServer(fiber.Config{Views: engine, PassLocalsToViews: true})
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	service := services.NewUserService(repos.NewUserRepo(ts.TestDb.BunDb), configs.NewAuthConfig())
+	roleService := services.NewRoleService(repos.NewRoleRepo(ts.TestDb.BunDb))
+	handler := NewHandler(service, roleService)
+
+	// signed in admin as SessionAuthenticated middleware would store it
+	ts.App.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &jwt.Token{Claims: jwt.MapClaims{"sub": adminId}, Valid: true})
+		return c.Next()
+	})
+	ts.App.Get("/users", handler.HandleList())
+	ts.App.Get("/users/:id", handler.HandleDetails())
+	ts.App.Post("/users/:id", handler.HandleUpdate())
+	ts.App.Post("/users/:id/enabledisable", handler.HandleEnableDisable())
+	ts.App.Post("/users/:id/roles", handler.HandleRoles())
+	ts.App.Post("/users/:id/delete", handler.HandleDelete())
+
+	get := func(uri string) (*http.Response, string) {
+		res, err := ts.App.Test(httptest.NewRequest("GET", uri, nil), 20000)
+		assert.NoErr(err)
+		body, err := io.ReadAll(res.Body)
+		assert.NoErr(err)
+		return res, string(body)
+	}
+
+	post := func(uri string, form url.Values) *http.Response {
+		req := httptest.NewRequest("POST", uri, strings.NewReader(form.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		res, err := ts.App.Test(req, 20000)
+		assert.NoErr(err)
+		return res
+	}
+
+	t.Run("given users should render sorted page", func(t *testing.T) {
+		res, body := get("/users?size=10&sort=email&dir=asc")
+		assert.Equal(res.StatusCode, 200)
+		assert.True(strings.Index(body, "em@parker.com") < strings.Index(body, "john@doe.com"))
+		assert.True(strings.Index(body, "john@doe.com") < strings.Index(body, "john@smith.com"))
+		assert.True(strings.Contains(body, "1-3 of 3"))
+	})
+
+	t.Run("given user id should render details with assigned roles", func(t *testing.T) {
+		res, body := get("/users/" + adminId)
+		assert.Equal(res.StatusCode, 200)
+		assert.True(strings.Contains(body, `value="ROLE_ADMIN" checked`))
+		assert.True(strings.Contains(body, `value="ROLE_MODERATOR">`))
+		// admin can not change roles, disable or delete own account
+		assert.True(strings.Contains(body, `value="ROLE_USER" checked disabled`))
+		assert.True(!strings.Contains(body, "Save roles"))
+		assert.True(!strings.Contains(body, "/delete"))
+	})
+
+	t.Run("given unknown user id should return 404", func(t *testing.T) {
+		res, _ := get("/users/" + uuid.NewString())
+		assert.Equal(res.StatusCode, 404)
+	})
+
+	t.Run("given profile form should update user", func(t *testing.T) {
+		res := post("/users/"+userId, url.Values{
+			"email":       {"john@doe.org"},
+			"fullname":    {"John Doe"},
+			"dateOfBirth": {"1999-04-11"},
+			"location":    {"Boston"},
+			"gender":      {"Male"},
+			"version":     {"1"},
+		})
+		assert.Equal(res.StatusCode, 303)
+		assert.Equal(res.Header.Get(fiber.HeaderLocation), "/users/"+userId)
+
+		u, err := service.GetById(context.Background(), uuid.MustParse(userId))
+		assert.NoErr(err)
+		assert.Equal(u.Email, "john@doe.org")
+		assert.Equal(u.Location, "Boston")
+		assert.Equal(u.Version, int64(2))
+	})
+
+	t.Run("given stale profile form should not overwrite newer changes", func(t *testing.T) {
+		res := post("/users/"+userId, url.Values{
+			"email":    {"john@doe.net"},
+			"location": {"Denver"},
+			"version":  {"1"},
+		})
+		assert.Equal(res.StatusCode, 303)
+
+		u, err := service.GetById(context.Background(), uuid.MustParse(userId))
+		assert.NoErr(err)
+		assert.Equal(u.Location, "Boston")
+	})
+
+	t.Run("given profile form with empty fields should clear them", func(t *testing.T) {
+		res := post("/users/"+userId, url.Values{
+			"email":   {"john@doe.org"},
+			"gender":  {"Male"},
+			"version": {"2"},
+		})
+		assert.Equal(res.StatusCode, 303)
+
+		u, err := service.GetById(context.Background(), uuid.MustParse(userId))
+		assert.NoErr(err)
+		assert.Equal(u.FullName, "")
+		assert.Equal(u.Location, "")
+		assert.True(u.DateOfBirth.IsZero())
+		assert.Equal(u.Version, int64(3))
+	})
+
+	t.Run("given checked roles should add and remove roles", func(t *testing.T) {
+		res := post("/users/"+userId+"/roles", url.Values{"roles": {"ROLE_USER", "ROLE_MODERATOR"}})
+		assert.Equal(res.StatusCode, 303)
+
+		res = post("/users/"+userId+"/roles", url.Values{"roles": {"ROLE_MODERATOR"}})
+		assert.Equal(res.StatusCode, 303)
+
+		u, err := service.GetById(context.Background(), uuid.MustParse(userId))
+		assert.NoErr(err)
+		assert.Equal(u.Roles, []string{"ROLE_MODERATOR"})
+	})
+
+	t.Run("given own roles should not be changed", func(t *testing.T) {
+		res := post("/users/"+adminId+"/roles", url.Values{"roles": {"ROLE_MODERATOR"}})
+		assert.Equal(res.StatusCode, 303)
+
+		u, err := service.GetById(context.Background(), uuid.MustParse(adminId))
+		assert.NoErr(err)
+		assert.Equal(len(u.Roles), 2)
+		assert.True(strings.Contains(strings.Join(u.Roles, ","), "ROLE_ADMIN"))
+	})
+
+	t.Run("given user should be disabled", func(t *testing.T) {
+		res := post("/users/"+userId+"/enabledisable", url.Values{})
+		assert.Equal(res.StatusCode, 303)
+
+		u, err := service.GetById(context.Background(), uuid.MustParse(userId))
+		assert.NoErr(err)
+		assert.True(!u.Enabled)
+	})
+
+	t.Run("given own account should not be deleted", func(t *testing.T) {
+		res := post("/users/"+adminId+"/delete", url.Values{})
+		assert.Equal(res.StatusCode, 303)
+		assert.Equal(res.Header.Get(fiber.HeaderLocation), "/users/"+adminId)
+
+		_, err := service.GetById(context.Background(), uuid.MustParse(adminId))
+		assert.NoErr(err)
+	})
+
+	t.Run("given user should be deleted", func(t *testing.T) {
+		res := post("/users/"+userId+"/delete", url.Values{})
+		assert.Equal(res.StatusCode, 303)
+		assert.Equal(res.Header.Get(fiber.HeaderLocation), "/users")
+
+		_, err := service.GetById(context.Background(), uuid.MustParse(userId))
+		assert.True(err != nil)
+	})
+}
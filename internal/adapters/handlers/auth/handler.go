@@ -1,13 +1,21 @@
 package auth
 
 import (
+	"errors"
+	"log/slog"
+	"math"
+	"strconv"
 	"strings"
+	"time"
 
 	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
 
+	"github.com/fmiskovic/go-starter/internal/adapters/handlers"
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
 	"github.com/fmiskovic/go-starter/internal/core/domain/user"
 	"github.com/fmiskovic/go-starter/internal/core/ports"
 	"github.com/fmiskovic/go-starter/internal/core/validators"
+	"github.com/fmiskovic/go-starter/internal/utils/jwks"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
@@ -39,13 +47,52 @@ func (h Handler) HandleSignIn() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, strings.Join(errs, " and "))
 		}
 
-		// call core service
+		// call core service, failed attempts are tracked per client IP too
+		req.ClientIP = c.IP()
 		res, err := h.service.SingIn(c.Context(), req)
+		var lockout apiErr.LockoutError
+		if errors.As(err, &lockout) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(time.Until(lockout.Until).Seconds()))))
+			return fiber.NewError(fiber.StatusTooManyRequests, lockout.Error())
+		}
+		if busy := handlers.ServerBusy(c, err); busy != nil {
+			return busy
+		}
 		if err != nil {
 			return fiber.NewError(fiber.StatusBadRequest,
 				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidAuthReq)).Error())
 		}
 
+		// response, access token is issued by HandleVerifyMfa if the second factor is required
+		if !res.MfaRequired {
+			c.Set(fiber.HeaderAuthorization, "Bearer "+res.Token)
+		}
+		return c.JSON(res)
+	}
+}
+
+// HandleRefresh is used to exchange refresh token for a new access and refresh token pair.
+func (h Handler) HandleRefresh() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse request body
+		var req = new(user.RefreshRequest)
+		if err := c.BodyParser(req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrParseReqBody)).Error())
+		}
+
+		// validate request
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, strings.Join(errs, " and "))
+		}
+
+		// call core service
+		res, err := h.service.Refresh(c.Context(), req)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnauthorized,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrRefreshToken)).Error())
+		}
+
 		// response
 		c.Set(fiber.HeaderAuthorization, "Bearer "+res.Token)
 		return c.JSON(res)
@@ -69,7 +116,14 @@ func (h Handler) HandleSignUp() fiber.Handler {
 
 		// call core service
 		res, err := h.service.SingUp(c.Context(), req)
+		if busy := handlers.ServerBusy(c, err); busy != nil {
+			return busy
+		}
 		if err != nil {
+			var policy apiErr.PasswordPolicyError
+			if errors.As(err, &policy) {
+				return fiber.NewError(fiber.StatusBadRequest, policy.Error())
+			}
 			return fiber.NewError(fiber.StatusUnprocessableEntity,
 				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrSignUp)).Error())
 		}
@@ -80,15 +134,70 @@ func (h Handler) HandleSignUp() fiber.Handler {
 	}
 }
 
-// HandleChangePassword change user password.
+// HandleChangePassword changes password of the signed in user.
+// Other sessions of the user are revoked, the caller gets new token pair if refresh tokens are configured.
+// Requests authenticated with API key are rejected.
+// It must be used after Middleware.Authenticated.
 func (h Handler) HandleChangePassword() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		id, err := handlers.SessionSubjectId(c)
+		if err != nil {
+			return err
+		}
+
 		// parse request body
 		var req = new(user.ChangePasswordRequest)
 		if err := c.BodyParser(req); err != nil {
 			return fiber.NewError(fiber.StatusBadRequest,
 				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrParseReqBody)).Error())
 		}
+		req.ID = id.String()
+
+		// validate request
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, strings.Join(errs, " and "))
+		}
+
+		// call core service
+		res, err := h.service.ChangePassword(c.Context(), req)
+		var lockout apiErr.LockoutError
+		if errors.As(err, &lockout) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(time.Until(lockout.Until).Seconds()))))
+			return fiber.NewError(fiber.StatusTooManyRequests, lockout.Error())
+		}
+		var policy apiErr.PasswordPolicyError
+		if errors.As(err, &policy) {
+			return fiber.NewError(fiber.StatusBadRequest, policy.Error())
+		}
+		if busy := handlers.ServerBusy(c, err); busy != nil {
+			return busy
+		}
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrChangePassword)).Error())
+		}
+
+		// response, token used for the request is revoked together with other sessions
+		if res == nil {
+			c.Locals("user", nil)
+			c.Set(fiber.HeaderAuthorization, "Bearer ")
+			c.Status(fiber.StatusNoContent)
+			return nil
+		}
+		c.Set(fiber.HeaderAuthorization, "Bearer "+res.Token)
+		return c.JSON(res)
+	}
+}
+
+// HandleConfirmEmail confirms user email address with the code user received by mail.
+func (h Handler) HandleConfirmEmail() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse request body
+		var req = new(user.ConfirmEmailRequest)
+		if err := c.BodyParser(req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrParseReqBody)).Error())
+		}
 
 		// validate request
 		if errs := h.validator.Validate(req); len(errs) > 0 {
@@ -96,8 +205,13 @@ func (h Handler) HandleChangePassword() fiber.Handler {
 		}
 
 		// call core service
-		if err := h.service.ChangePassword(c.Context(), req); err != nil {
-			return fiber.NewError(fiber.StatusUnprocessableEntity, apiErr.New(apiErr.WithSvcErr(err)).Error())
+		if err := h.service.ConfirmEmail(c.Context(), *req); err != nil {
+			if errors.Is(err, apiErr.ErrEmailTaken) {
+				return fiber.NewError(fiber.StatusConflict,
+					apiErr.New(apiErr.WithAppErr(apiErr.ErrEmailTaken)).Error())
+			}
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrConfirmEmail)).Error())
 		}
 
 		// response
@@ -106,29 +220,59 @@ func (h Handler) HandleChangePassword() fiber.Handler {
 	}
 }
 
-// HandleConfirmEmail confirms user email address.
-func (h Handler) HandleConfirmEmail() fiber.Handler {
+// HandleForgotPassword sends password reset token to the user email address.
+// It always responds with 202 for valid requests, so registered emails can not be discovered.
+func (h Handler) HandleForgotPassword() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// parse query params
-		sId := c.Params("id", "0")
-		if sId == "0" {
+		// parse request body
+		var req = new(user.ForgotPasswordRequest)
+		if err := c.BodyParser(req); err != nil {
 			return fiber.NewError(fiber.StatusBadRequest,
-				apiErr.New(apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrParseReqBody)).Error())
 		}
-		code := c.Params("code", "0")
-		if sId == "0" {
+
+		// validate request
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, strings.Join(errs, " and "))
+		}
+
+		// call core service
+		if err := h.service.ForgotPassword(c.Context(), req); err != nil {
+			slog.Error("failed to send password reset", "error", err.Error())
+		}
+
+		// response
+		c.Status(fiber.StatusAccepted)
+		return nil
+	}
+}
+
+// HandleResetPassword sets new user password with the token user received by mail.
+func (h Handler) HandleResetPassword() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse request body
+		var req = new(user.ResetPasswordRequest)
+		if err := c.BodyParser(req); err != nil {
 			return fiber.NewError(fiber.StatusBadRequest,
-				apiErr.New(apiErr.WithAppErr(apiErr.ErrInvalidCode)).Error())
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrParseReqBody)).Error())
 		}
 
-		req := new(user.ConfirmEmailRequest)
-		req.ID = sId
-		req.Code = code
+		// validate request
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, strings.Join(errs, " and "))
+		}
 
 		// call core service
-		err := h.service.ConfirmEmail(c.Context(), *req)
-		if err != nil {
-			return fiber.NewError(fiber.StatusUnprocessableEntity, apiErr.New(apiErr.WithSvcErr(err)).Error())
+		if err := h.service.ResetPassword(c.Context(), req); err != nil {
+			if busy := handlers.ServerBusy(c, err); busy != nil {
+				return busy
+			}
+			var policy apiErr.PasswordPolicyError
+			if errors.As(err, &policy) {
+				return fiber.NewError(fiber.StatusBadRequest, policy.Error())
+			}
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrResetPassword)).Error())
 		}
 
 		// response
@@ -137,11 +281,272 @@ func (h Handler) HandleConfirmEmail() fiber.Handler {
 	}
 }
 
-// HandleSignOut logout user.
+// HandleSignOut logout user by revoking the access token used for the request.
+// It must be used after Middleware.Authenticated.
 func (h Handler) HandleSignOut() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		claims, err := handlers.TokenClaims(c)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnauthorized, apiErr.New(apiErr.WithAppErr(err)).Error())
+		}
+		// API key has no jti to revoke, it is revoked by HandleRevokeApiKey
+		if claims["api_key"] != nil {
+			return fiber.NewError(fiber.StatusForbidden, apiErr.New(apiErr.WithAppErr(apiErr.ErrApiKeyNotAllowed)).Error())
+		}
+
+		sub, _ := claims.GetSubject()
+		exp, err := claims.GetExpirationTime()
+		if err != nil || exp == nil {
+			return fiber.NewError(fiber.StatusUnauthorized,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidToken)).Error())
+		}
+		jti, _ := claims["jti"].(string)
+		sid, _ := claims["sid"].(string)
+
+		req := &user.SignOutRequest{
+			TokenID:   jti,
+			UserID:    sub,
+			SessionID: sid,
+			ExpiresAt: exp.Time,
+		}
+
+		// call core service
+		if err := h.service.SignOut(c.Context(), req); err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrSignOut)).Error())
+		}
+
+		// response
+		c.Locals("user", nil)
+		c.Set(fiber.HeaderAuthorization, "Bearer ")
+		c.Status(fiber.StatusNoContent)
+		return nil
+	}
+}
+
+// HandleSignOutAll logout user everywhere by revoking all of its access and refresh tokens.
+// It must be used after Middleware.Authenticated.
+func (h Handler) HandleSignOutAll() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := handlers.SessionSubjectId(c)
+		if err != nil {
+			return err
+		}
+
+		// call core service
+		if err := h.service.SignOutAll(c.Context(), id); err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrSignOut)).Error())
+		}
+
+		// response
 		c.Locals("user", nil)
 		c.Set(fiber.HeaderAuthorization, "Bearer ")
+		c.Status(fiber.StatusNoContent)
+		return nil
+	}
+}
+
+// HandleVerifyMfa completes sign in of the user with enabled two-factor authentication.
+func (h Handler) HandleVerifyMfa() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse request body
+		var req = new(user.VerifyMfaRequest)
+		if err := c.BodyParser(req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrParseReqBody)).Error())
+		}
+
+		// validate request
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, strings.Join(errs, " and "))
+		}
+
+		// call core service
+		res, err := h.service.VerifyMfa(c.Context(), req)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnauthorized,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrMfaVerify)).Error())
+		}
+
+		// response
+		c.Set(fiber.HeaderAuthorization, "Bearer "+res.Token)
+		return c.JSON(res)
+	}
+}
+
+// HandleEnrollMfa generates new TOTP secret for the signed in user.
+// It must be used after Middleware.Authenticated.
+func (h Handler) HandleEnrollMfa() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := handlers.SessionSubjectId(c)
+		if err != nil {
+			return err
+		}
+
+		// call core service
+		res, err := h.service.EnrollMfa(c.Context(), id)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrMfaUpdate)).Error())
+		}
+
+		// response
+		return c.JSON(res)
+	}
+}
+
+// HandleEnableMfa enables two-factor authentication of the signed in user and returns recovery codes.
+// It must be used after Middleware.Authenticated.
+func (h Handler) HandleEnableMfa() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req, err := h.mfaCodeRequest(c)
+		if err != nil {
+			return err
+		}
+
+		// call core service
+		res, err := h.service.EnableMfa(c.Context(), req)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrMfaUpdate)).Error())
+		}
+
+		// response
+		return c.JSON(res)
+	}
+}
+
+// HandleDisableMfa disables two-factor authentication of the signed in user.
+// It must be used after Middleware.Authenticated.
+func (h Handler) HandleDisableMfa() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req, err := h.mfaCodeRequest(c)
+		if err != nil {
+			return err
+		}
+
+		// call core service
+		if err := h.service.DisableMfa(c.Context(), req); err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrMfaUpdate)).Error())
+		}
+
+		// response
+		c.Status(fiber.StatusNoContent)
 		return nil
 	}
 }
+
+// HandleCreateApiKey creates new API key of the signed in user, plain key is returned only once.
+// It must be used after Middleware.Authenticated.
+func (h Handler) HandleCreateApiKey() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := handlers.SessionSubjectId(c)
+		if err != nil {
+			return err
+		}
+
+		// parse request body
+		var req = new(security.ApiKeyRequest)
+		if err := c.BodyParser(req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrParseReqBody)).Error())
+		}
+		req.UserID = id.String()
+
+		// validate request
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, strings.Join(errs, " and "))
+		}
+
+		// call core service
+		res, err := h.service.CreateApiKey(c.Context(), req)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrEntityCreate)).Error())
+		}
+
+		// response
+		c.Status(fiber.StatusCreated)
+		return c.JSON(res)
+	}
+}
+
+// HandleGetApiKeys returns API keys of the signed in user.
+// It must be used after Middleware.Authenticated.
+func (h Handler) HandleGetApiKeys() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := handlers.SessionSubjectId(c)
+		if err != nil {
+			return err
+		}
+
+		// call core service
+		res, err := h.service.GetApiKeys(c.Context(), id)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrGetAll)).Error())
+		}
+
+		// response
+		return c.JSON(res)
+	}
+}
+
+// HandleRevokeApiKey deletes API key of the signed in user.
+// It must be used after Middleware.Authenticated.
+func (h Handler) HandleRevokeApiKey() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userId, err := handlers.SessionSubjectId(c)
+		if err != nil {
+			return err
+		}
+
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+		}
+
+		// call core service
+		if err := h.service.RevokeApiKey(c.Context(), id, userId); err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrEntityDelete)).Error())
+		}
+
+		// response
+		c.Status(fiber.StatusNoContent)
+		return nil
+	}
+}
+
+// mfaCodeRequest parses and validates second factor code of the signed in user.
+func (h Handler) mfaCodeRequest(c *fiber.Ctx) (*user.MfaCodeRequest, error) {
+	id, err := handlers.SessionSubjectId(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// parse request body
+	var req = new(user.MfaCodeRequest)
+	if err := c.BodyParser(req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest,
+			apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrParseReqBody)).Error())
+	}
+	req.ID = id.String()
+
+	// validate request
+	if errs := h.validator.Validate(req); len(errs) > 0 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, strings.Join(errs, " and "))
+	}
+	return req, nil
+}
+
+// HandleJWKS is used to publish public keys, so other services can verify issued tokens.
+func HandleJWKS(keys jwks.KeySet) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+		return c.JSON(keys.PublicKeys())
+	}
+}
@@ -3,8 +3,11 @@ package auth
 import (
 	"bytes"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/fmiskovic/go-starter/internal/adapters/mailer"
 	"github.com/fmiskovic/go-starter/internal/adapters/repos"
 	"github.com/fmiskovic/go-starter/internal/core/configs"
 	"github.com/fmiskovic/go-starter/internal/core/services"
@@ -25,7 +28,8 @@ func BenchmarkHandleSignIn(b *testing.B) {
 	defer ts.TestDb.Shutdown()
 
 	repo := repos.NewUserRepo(ts.TestDb.BunDb)
-	service := services.NewUserService(repo, configs.NewAuthConfig())
+	refreshRepo := repos.NewRefreshTokenRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, configs.NewAuthConfig(), services.WithRefreshTokenRepo(refreshRepo))
 	handler := NewHandler(service)
 	ts.App.Post("/auth/login", handler.HandleSignIn())
 
@@ -53,7 +57,7 @@ func BenchmarkHandleSignUp(b *testing.B) {
 	defer ts.TestDb.Shutdown()
 
 	repo := repos.NewUserRepo(ts.TestDb.BunDb)
-	service := services.NewUserService(repo, configs.NewAuthConfig())
+	service := services.NewUserService(repo, configs.NewAuthConfig(), services.WithMailer(mailer.NewLogMailer()))
 	handler := NewHandler(service)
 	ts.App.Post("/auth/register", handler.HandleSignUp())
 
@@ -67,3 +71,83 @@ func BenchmarkHandleSignUp(b *testing.B) {
 		assert.Equal(res.StatusCode, 201)
 	}
 }
+
+func BenchmarkHandleSignInParallel(b *testing.B) {
+	if testing.Short() {
+		return
+	}
+	// requests run in parallel goroutines, failure must not stop the benchmark goroutine
+	assert := is.NewRelaxed(b)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		b.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	// concurrency is limited to number of CPUs by default, other requests wait in the queue
+	authConfig := configs.NewAuthConfig()
+	authConfig.PasswordHashing.QueueTimeout = time.Minute
+
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	refreshRepo := repos.NewRefreshTokenRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, authConfig, services.WithRefreshTokenRepo(refreshRepo))
+	handler := NewHandler(service)
+	ts.App.Post("/auth/login", handler.HandleSignIn())
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			body := []byte("{\"username\":\"username1\",\"password\":\"password1\"}")
+			req := httptest.NewRequest("POST", "/auth/login", bytes.NewReader(body))
+			req.Header.Add("Content-Type", "application/json")
+
+			res, err := ts.App.Test(req, -1)
+			assert.NoErr(err)
+			assert.Equal(res.StatusCode, 200)
+		}
+	})
+}
+
+func BenchmarkHandleSignInSaturated(b *testing.B) {
+	if testing.Short() {
+		return
+	}
+	// requests run in parallel goroutines, failure must not stop the benchmark goroutine
+	assert := is.NewRelaxed(b)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		b.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	// single hashing slot and short queue, so parallel requests over the limit are rejected
+	authConfig := configs.NewAuthConfig()
+	authConfig.PasswordHashing.Concurrency = 1
+	authConfig.PasswordHashing.QueueTimeout = 10 * time.Millisecond
+
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	refreshRepo := repos.NewRefreshTokenRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, authConfig, services.WithRefreshTokenRepo(refreshRepo))
+	handler := NewHandler(service)
+	ts.App.Post("/auth/login", handler.HandleSignIn())
+
+	var rejected atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			body := []byte("{\"username\":\"username1\",\"password\":\"password1\"}")
+			req := httptest.NewRequest("POST", "/auth/login", bytes.NewReader(body))
+			req.Header.Add("Content-Type", "application/json")
+
+			res, err := ts.App.Test(req, -1)
+			assert.NoErr(err)
+			if res.StatusCode == 503 {
+				assert.Equal(res.Header.Get("Retry-After"), "1")
+				rejected.Add(1)
+				continue
+			}
+			assert.Equal(res.StatusCode, 200)
+		}
+	})
+	b.ReportMetric(float64(rejected.Load())/float64(b.N), "rejected/op")
+}
@@ -2,18 +2,35 @@ package auth
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/fmiskovic/go-starter/internal/adapters/ldap"
+	"github.com/fmiskovic/go-starter/internal/adapters/mailer"
+	"github.com/fmiskovic/go-starter/internal/adapters/memory"
+	"github.com/fmiskovic/go-starter/internal/adapters/oidc"
 	"github.com/fmiskovic/go-starter/internal/adapters/repos"
 	"github.com/fmiskovic/go-starter/internal/core/configs"
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
 	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	"github.com/fmiskovic/go-starter/internal/core/ports"
 	"github.com/fmiskovic/go-starter/internal/core/services"
 	"github.com/fmiskovic/go-starter/internal/utils/testx"
+	"github.com/fmiskovic/go-starter/internal/utils/totp"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/matryer/is"
 )
 
@@ -30,7 +47,8 @@ func TestHandleSignIn(t *testing.T) {
 	defer ts.TestDb.Shutdown()
 
 	repo := repos.NewUserRepo(ts.TestDb.BunDb)
-	service := services.NewUserService(repo, configs.NewAuthConfig())
+	refreshRepo := repos.NewRefreshTokenRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, configs.NewAuthConfig(), services.WithRefreshTokenRepo(refreshRepo))
 	handler := NewHandler(service)
 	ts.App.Post("/auth/login", handler.HandleSignIn())
 
@@ -56,8 +74,21 @@ func TestHandleSignIn(t *testing.T) {
 				err := json.NewDecoder(resBody).Decode(signInRes)
 				assert.NoErr(err)
 				assert.True(signInRes.Token != "")
+				assert.True(signInRes.RefreshToken != "")
+				assert.Equal(signInRes.ExpiresIn, int64(900))
+
+				// bcrypt hash of the fixture is replaced by argon2id one
+				u, err := repo.GetByUsername(context.Background(), "username1")
+				assert.NoErr(err)
+				assert.True(strings.HasPrefix(u.Credentials.Password, "$argon2id$"))
 			},
 		},
+		{
+			name:     "given valid credentials should sign in with the rehashed password",
+			reqBody:  []byte("{\"username\":\"username1\",\"password\":\"password1\"}"),
+			wantCode: 200,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
 		{
 			name:     "given invalid password should return 400",
 			reqBody:  []byte("{\"username\":\"username1\",\"password\":\"invalid\"}"),
@@ -70,6 +101,12 @@ func TestHandleSignIn(t *testing.T) {
 			wantCode: 400,
 			verify:   func(t *testing.T, res *http.Response) {},
 		},
+		{
+			name:     "given disabled user should return 400",
+			reqBody:  []byte("{\"username\":\"username3\",\"password\":\"password1\"}"),
+			wantCode: 400,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -84,6 +121,264 @@ func TestHandleSignIn(t *testing.T) {
 	}
 }
 
+func TestHandleSignInLockout(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, configs.NewAuthConfig(configs.LockoutThreshold(2), configs.LockoutIPThreshold(4)),
+		services.WithRefreshTokenRepo(repos.NewRefreshTokenRepo(ts.TestDb.BunDb)),
+		services.WithLoginAttemptStore(memory.NewLoginAttemptStore()),
+	)
+	handler := NewHandler(service)
+	ts.App.Post("/auth/login", handler.HandleSignIn())
+
+	// steps depend on failed attempts made by the previous ones
+	tests := []struct {
+		name     string
+		reqBody  []byte
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "given non-existing username should return 400",
+			reqBody:  []byte("{\"username\":\"non-existing\",\"password\":\"password1\"}"),
+			wantCode: 400,
+			wantBody: "invalid credentials\ninvalid username or password",
+		},
+		{
+			name:     "given invalid password should return the same error",
+			reqBody:  []byte("{\"username\":\"username1\",\"password\":\"invalid1\"}"),
+			wantCode: 400,
+			wantBody: "invalid credentials\ninvalid username or password",
+		},
+		{
+			name:     "given second invalid password should return 400",
+			reqBody:  []byte("{\"username\":\"USERNAME1\",\"password\":\"invalid1\"}"),
+			wantCode: 400,
+			wantBody: "invalid credentials\ninvalid username or password",
+		},
+		{
+			name:     "given valid credentials of locked username should return 429",
+			reqBody:  []byte("{\"username\":\"username1\",\"password\":\"password1\"}"),
+			wantCode: 429,
+			wantBody: "too many failed sign in attempts, try again later",
+		},
+		{
+			name:     "given valid credentials of another username should not be locked",
+			reqBody:  []byte("{\"username\":\"username3\",\"password\":\"password1\"}"),
+			wantCode: 400,
+			wantBody: "user is disabled\ninvalid username or password",
+		},
+		{
+			name:     "given fourth invalid password from the same ip should return 400",
+			reqBody:  []byte("{\"username\":\"username3\",\"password\":\"invalid1\"}"),
+			wantCode: 400,
+			wantBody: "invalid credentials\ninvalid username or password",
+		},
+		{
+			name:     "given valid credentials from locked ip should return 429",
+			reqBody:  []byte("{\"username\":\"username3\",\"password\":\"password1\"}"),
+			wantCode: 429,
+			wantBody: "too many failed sign in attempts, try again later",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/auth/login", bytes.NewReader(tt.reqBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			res, err := ts.App.Test(req, 20000)
+			assert.NoErr(err)
+			assert.Equal(res.StatusCode, tt.wantCode)
+
+			if tt.wantCode == 429 {
+				assert.True(res.Header.Get(fiber.HeaderRetryAfter) != "")
+			}
+			if tt.wantBody != "" {
+				body, err := io.ReadAll(res.Body)
+				assert.NoErr(err)
+				assert.Equal(string(body), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestHandleRefresh(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	refreshRepo := repos.NewRefreshTokenRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, configs.NewAuthConfig(), services.WithRefreshTokenRepo(refreshRepo))
+	handler := NewHandler(service)
+	ts.App.Post("/auth/login", handler.HandleSignIn())
+	ts.App.Post("/auth/refresh", handler.HandleRefresh())
+
+	send := func(route string, body []byte) (*http.Response, *user.SignInResponse) {
+		req := httptest.NewRequest("POST", route, bytes.NewReader(body))
+		req.Header.Add("Content-Type", "application/json")
+
+		res, err := ts.App.Test(req, 20000)
+		assert.NoErr(err)
+		defer func(body io.ReadCloser) {
+			if err := body.Close(); err != nil {
+				fmt.Println("error occurred on body close:", err.Error())
+			}
+		}(res.Body)
+
+		tokens := &user.SignInResponse{}
+		if res.StatusCode == 200 {
+			assert.NoErr(json.NewDecoder(res.Body).Decode(tokens))
+		}
+		return res, tokens
+	}
+	refreshBody := func(refreshToken string) []byte {
+		return []byte(fmt.Sprintf("{\"refreshToken\":\"%s\"}", refreshToken))
+	}
+
+	res, signIn := send("/auth/login", []byte("{\"username\":\"username1\",\"password\":\"password1\"}"))
+	assert.Equal(res.StatusCode, 200)
+
+	t.Run("given valid refresh token should return 200 and rotate tokens", func(t *testing.T) {
+		res, rotated := send("/auth/refresh", refreshBody(signIn.RefreshToken))
+		assert.Equal(res.StatusCode, 200)
+		assert.True(rotated.Token != "")
+		assert.True(rotated.RefreshToken != "")
+		assert.True(rotated.RefreshToken != signIn.RefreshToken)
+
+		t.Run("given already rotated refresh token should return 401 and revoke the family", func(t *testing.T) {
+			res, _ := send("/auth/refresh", refreshBody(signIn.RefreshToken))
+			assert.Equal(res.StatusCode, 401)
+
+			res, _ = send("/auth/refresh", refreshBody(rotated.RefreshToken))
+			assert.Equal(res.StatusCode, 401)
+		})
+	})
+
+	t.Run("given unknown refresh token should return 401", func(t *testing.T) {
+		res, _ := send("/auth/refresh", refreshBody("unknown"))
+		assert.Equal(res.StatusCode, 401)
+	})
+
+	t.Run("given empty request should return 400", func(t *testing.T) {
+		res, _ := send("/auth/refresh", []byte("{}"))
+		assert.Equal(res.StatusCode, 400)
+	})
+}
+
+func TestHandleSignOut(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	authConfig := configs.NewAuthConfig()
+	revocations := memory.NewRevocationStore()
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	refreshRepo := repos.NewRefreshTokenRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, authConfig,
+		services.WithRefreshTokenRepo(refreshRepo),
+		services.WithRevocationStore(revocations),
+	)
+	handler := NewHandler(service)
+	middleware := NewMiddleware(authConfig, revocations)
+
+	ts.App.Post("/auth/login", handler.HandleSignIn())
+	ts.App.Post("/auth/refresh", handler.HandleRefresh())
+	ts.App.Get("/auth/logout", middleware.Authenticated(), handler.HandleSignOut())
+	ts.App.Post("/auth/logout/all", middleware.Authenticated(), handler.HandleSignOutAll())
+	ts.App.Get("/protected", middleware.Authenticated(), func(c *fiber.Ctx) error { return c.SendStatus(200) })
+	ts.App.Get("/admin", middleware.Authenticated(), middleware.RequireRoles(security.ROLE_ADMIN), func(c *fiber.Ctx) error { return c.SendStatus(200) })
+
+	signIn := func() *user.SignInResponse {
+		req := httptest.NewRequest("POST", "/auth/login", bytes.NewReader([]byte("{\"username\":\"username1\",\"password\":\"password1\"}")))
+		req.Header.Add("Content-Type", "application/json")
+
+		res, err := ts.App.Test(req, 20000)
+		assert.NoErr(err)
+		assert.Equal(res.StatusCode, 200)
+
+		tokens := &user.SignInResponse{}
+		assert.NoErr(json.NewDecoder(res.Body).Decode(tokens))
+		assert.NoErr(res.Body.Close())
+		return tokens
+	}
+	send := func(method, route, token string) int {
+		req := httptest.NewRequest(method, route, nil)
+		req.Header.Add(fiber.HeaderAuthorization, "Bearer "+token)
+
+		res, err := ts.App.Test(req, 20000)
+		assert.NoErr(err)
+		return res.StatusCode
+	}
+	refresh := func(refreshToken string) int {
+		body := []byte(fmt.Sprintf("{\"refreshToken\":\"%s\"}", refreshToken))
+		req := httptest.NewRequest("POST", "/auth/refresh", bytes.NewReader(body))
+		req.Header.Add("Content-Type", "application/json")
+
+		res, err := ts.App.Test(req, 20000)
+		assert.NoErr(err)
+		return res.StatusCode
+	}
+
+	t.Run("given signed out token should return 401", func(t *testing.T) {
+		tokens := signIn()
+		other := signIn()
+
+		assert.Equal(send("GET", "/protected", tokens.Token), 200)
+		assert.Equal(send("GET", "/admin", tokens.Token), 200)
+
+		assert.Equal(send("GET", "/auth/logout", tokens.Token), 204)
+
+		assert.Equal(send("GET", "/protected", tokens.Token), 401)
+		assert.Equal(send("GET", "/admin", tokens.Token), 401)
+		assert.Equal(refresh(tokens.RefreshToken), 401)
+
+		// other sessions are still valid
+		assert.Equal(send("GET", "/protected", other.Token), 200)
+	})
+
+	t.Run("given signed out everywhere user should return 401 for all its tokens", func(t *testing.T) {
+		tokens := signIn()
+		other := signIn()
+		testx.WaitNextSecond()
+
+		assert.Equal(send("POST", "/auth/logout/all", tokens.Token), 204)
+
+		assert.Equal(send("GET", "/protected", tokens.Token), 401)
+		assert.Equal(send("GET", "/protected", other.Token), 401)
+		assert.Equal(send("GET", "/admin", other.Token), 401)
+		assert.Equal(refresh(other.RefreshToken), 401)
+	})
+
+	t.Run("given missing token should return 401", func(t *testing.T) {
+		assert.Equal(send("GET", "/auth/logout", ""), 401)
+	})
+}
+
 func TestHandleSignUp(t *testing.T) {
 	if testing.Short() {
 		return
@@ -97,7 +392,7 @@ func TestHandleSignUp(t *testing.T) {
 	defer ts.TestDb.Shutdown()
 
 	repo := repos.NewUserRepo(ts.TestDb.BunDb)
-	service := services.NewUserService(repo, configs.NewAuthConfig())
+	service := services.NewUserService(repo, configs.NewAuthConfig(), services.WithMailer(mailer.NewLogMailer()))
 	handler := NewHandler(service)
 	ts.App.Post("/auth/register", handler.HandleSignUp())
 
@@ -181,50 +476,951 @@ func TestHandleChangePassword(t *testing.T) {
 	}
 	defer ts.TestDb.Shutdown()
 
+	authConfig := configs.NewAuthConfig()
+	revocations := memory.NewRevocationStore()
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, authConfig,
+		services.WithRefreshTokenRepo(repos.NewRefreshTokenRepo(ts.TestDb.BunDb)),
+		services.WithRevocationStore(revocations),
+	)
+	handler := NewHandler(service)
+	middleware := NewMiddleware(authConfig, revocations)
+
+	ts.App.Post("/auth/login", handler.HandleSignIn())
+	ts.App.Post("/auth/password", middleware.Authenticated(), handler.HandleChangePassword())
+	ts.App.Get("/protected", middleware.Authenticated(), func(c *fiber.Ctx) error { return c.SendStatus(200) })
+
+	signIn := func(password string) (int, *user.SignInResponse) {
+		body := []byte(fmt.Sprintf("{\"username\":\"username1\",\"password\":\"%s\"}", password))
+		req := httptest.NewRequest("POST", "/auth/login", bytes.NewReader(body))
+		req.Header.Add("Content-Type", "application/json")
+
+		res, err := ts.App.Test(req, 20000)
+		assert.NoErr(err)
+
+		tokens := &user.SignInResponse{}
+		if res.StatusCode == 200 {
+			assert.NoErr(json.NewDecoder(res.Body).Decode(tokens))
+		}
+		return res.StatusCode, tokens
+	}
+	changePassword := func(token string, body string) (int, *user.SignInResponse) {
+		req := httptest.NewRequest("POST", "/auth/password", strings.NewReader(body))
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add(fiber.HeaderAuthorization, "Bearer "+token)
+
+		res, err := ts.App.Test(req, 30000)
+		assert.NoErr(err)
+
+		tokens := &user.SignInResponse{}
+		if res.StatusCode == 200 {
+			assert.NoErr(json.NewDecoder(res.Body).Decode(tokens))
+		}
+		return res.StatusCode, tokens
+	}
+	send := func(token string) int {
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.Header.Add(fiber.HeaderAuthorization, "Bearer "+token)
+
+		res, err := ts.App.Test(req, 20000)
+		assert.NoErr(err)
+		return res.StatusCode
+	}
+
+	_, tokens := signIn("password1")
+
+	t.Run("given invalid oldPassword should return 422", func(t *testing.T) {
+		code, _ := changePassword(tokens.Token, "{\"oldPassword\":\"password231\",\"newPassword\":\"password111\"}")
+		assert.Equal(code, 422)
+	})
+
+	t.Run("given invalid newPassword should return 400", func(t *testing.T) {
+		code, _ := changePassword(tokens.Token, "{\"oldPassword\":\"password1\",\"newPassword\":\"pass\"}")
+		assert.Equal(code, 400)
+	})
+
+	t.Run("given newPassword same as oldPassword should return 400", func(t *testing.T) {
+		code, _ := changePassword(tokens.Token, "{\"oldPassword\":\"password1\",\"newPassword\":\"password1\"}")
+		assert.Equal(code, 400)
+	})
+
+	t.Run("given empty request should return 400", func(t *testing.T) {
+		code, _ := changePassword(tokens.Token, "")
+		assert.Equal(code, 400)
+	})
+
+	t.Run("given missing token should return 401", func(t *testing.T) {
+		code, _ := changePassword("", "{\"oldPassword\":\"password1\",\"newPassword\":\"password111\"}")
+		assert.Equal(code, 401)
+	})
+
+	t.Run("given valid request should change password and revoke other sessions", func(t *testing.T) {
+		_, other := signIn("password1")
+		testx.WaitNextSecond()
+
+		code, renewed := changePassword(tokens.Token, "{\"oldPassword\":\"password1\",\"newPassword\":\"password111\"}")
+		assert.Equal(code, 200)
+		assert.True(renewed.Token != "")
+
+		assert.Equal(send(tokens.Token), 401)
+		assert.Equal(send(other.Token), 401)
+		assert.Equal(send(renewed.Token), 200)
+
+		code, _ = signIn("password1")
+		assert.Equal(code, 400)
+		code, _ = signIn("password111")
+		assert.Equal(code, 200)
+	})
+}
+
+func TestHandleConfirmEmail(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
 	repo := repos.NewUserRepo(ts.TestDb.BunDb)
 	service := services.NewUserService(repo, configs.NewAuthConfig())
 	handler := NewHandler(service)
-	ts.App.Post("/auth/password", handler.HandleChangePassword())
+	ts.App.Post("/auth/email", handler.HandleConfirmEmail())
 
 	tests := []struct {
 		name     string
 		reqBody  []byte
 		wantCode int
+		verify   func(t *testing.T)
 	}{
-		// {
-		// 	name:     "given valid request should change user password",
-		// 	reqBody:  []byte("{\"username\":\"username1\",\"oldPassword\":\"password1\",\"newPassword\":\"password111\"}"),
-		// 	wantCode: 204,
-		// },
-		{
-			name:     "given invalid oldPassword should return 422",
-			reqBody:  []byte("{\"username\":\"username1\",\"oldPassword\":\"password231\",\"newPassword\":\"password111\"}"),
+		{
+			name:     "given invalid code should return 422",
+			reqBody:  []byte("{\"id\":\"220cea28-b2b0-4051-9eb6-9a99e451af02\",\"code\":\"invalid-code\"}"),
 			wantCode: 422,
+			verify:   func(t *testing.T) {},
 		},
 		{
-			name:     "given invalid newPassword should return 400",
-			reqBody:  []byte("{\"username\":\"username1\",\"oldPassword\":\"password1\",\"newPassword\":\"pass\"}"),
-			wantCode: 400,
+			name:     "given valid code should return 204 and enable user",
+			reqBody:  []byte("{\"id\":\"220cea28-b2b0-4051-9eb6-9a99e451af02\",\"code\":\"valid-code\"}"),
+			wantCode: 204,
+			verify: func(t *testing.T) {
+				u, err := repo.GetById(context.Background(), uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af02"))
+				assert.NoErr(err)
+				assert.True(u.Enabled)
+			},
 		},
 		{
-			name:     "given invalid username should return 422",
-			reqBody:  []byte("{\"username\":\"username1111\",\"oldPassword\":\"password1\",\"newPassword\":\"password111\"}"),
+			name:     "given already used code should return 422",
+			reqBody:  []byte("{\"id\":\"220cea28-b2b0-4051-9eb6-9a99e451af02\",\"code\":\"valid-code\"}"),
 			wantCode: 422,
+			verify:   func(t *testing.T) {},
 		},
 		{
-			name:     "given empty reques should return 400",
+			name:     "given expired code should return 422 and keep user disabled",
+			reqBody:  []byte("{\"id\":\"220cea28-b2b0-4051-9eb6-9a99e451af03\",\"code\":\"expired-code\"}"),
+			wantCode: 422,
+			verify: func(t *testing.T) {
+				u, err := repo.GetById(context.Background(), uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af03"))
+				assert.NoErr(err)
+				assert.True(!u.Enabled)
+			},
+		},
+		{
+			name:     "given code of email registered meanwhile by another user should return 409",
+			reqBody:  []byte("{\"id\":\"220cea28-b2b0-4051-9eb6-9a99e451af01\",\"code\":\"taken-code\"}"),
+			wantCode: 409,
+			verify: func(t *testing.T) {
+				u, err := repo.GetById(context.Background(), uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01"))
+				assert.NoErr(err)
+				assert.Equal(u.Email, "john@smith.com")
+			},
+		},
+		{
+			name:     "given invalid id should return 400",
+			reqBody:  []byte("{\"id\":\"invalid\",\"code\":\"valid-code\"}"),
+			wantCode: 400,
+			verify:   func(t *testing.T) {},
+		},
+		{
+			name:     "given empty request should return 400",
 			reqBody:  []byte(""),
 			wantCode: 400,
+			verify:   func(t *testing.T) {},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest("POST", "/auth/password", bytes.NewReader(tt.reqBody))
+			req := httptest.NewRequest("POST", "/auth/email", bytes.NewReader(tt.reqBody))
 			req.Header.Add("Content-Type", "application/json")
 
-			res, err := ts.App.Test(req, 30000)
+			res, err := ts.App.Test(req, 20000)
 			assert.NoErr(err)
 			assert.Equal(res.StatusCode, tt.wantCode)
+			tt.verify(t)
 		})
 	}
 }
+
+func TestHandleForgotPassword(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	outbox := t.TempDir()
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	authConfig := configs.NewAuthConfig(configs.PasswordResetURL("https://example.com/reset"))
+	service := services.NewUserService(repo, authConfig, services.WithMailer(mailer.NewFileMailer(outbox)))
+	handler := NewHandler(service)
+	ts.App.Post("/auth/password/forgot", handler.HandleForgotPassword())
+	ts.App.Post("/auth/password/reset", handler.HandleResetPassword())
+
+	send := func(route string, body []byte) int {
+		req := httptest.NewRequest("POST", route, bytes.NewReader(body))
+		req.Header.Add("Content-Type", "application/json")
+
+		res, err := ts.App.Test(req, 20000)
+		assert.NoErr(err)
+		return res.StatusCode
+	}
+
+	t.Run("given unknown email should return 202 and send nothing", func(t *testing.T) {
+		assert.Equal(send("/auth/password/forgot", []byte("{\"email\":\"nobody@fake.com\"}")), 202)
+
+		files, err := os.ReadDir(outbox)
+		assert.NoErr(err)
+		assert.Equal(len(files), 0)
+	})
+
+	t.Run("given known email should return 202 and send reset link", func(t *testing.T) {
+		assert.Equal(send("/auth/password/forgot", []byte("{\"email\":\"john@smith.com\"}")), 202)
+
+		files, err := os.ReadDir(outbox)
+		assert.NoErr(err)
+		assert.Equal(len(files), 1)
+
+		content, err := os.ReadFile(filepath.Join(outbox, files[0].Name()))
+		assert.NoErr(err)
+
+		_, link, found := strings.Cut(string(content), "https://example.com/reset?token=")
+		assert.True(found)
+		resetToken, _, _ := strings.Cut(link, "\n")
+
+		// previously issued token is discarded
+		body := []byte("{\"token\":\"reset-token\",\"newPassword\":\"Password1234!\"}")
+		assert.Equal(send("/auth/password/reset", body), 422)
+
+		body = []byte(fmt.Sprintf("{\"token\":\"%s\",\"newPassword\":\"Password1234!\"}", resetToken))
+		assert.Equal(send("/auth/password/reset", body), 204)
+	})
+
+	t.Run("given invalid email should return 400", func(t *testing.T) {
+		assert.Equal(send("/auth/password/forgot", []byte("{\"email\":\"invalid\"}")), 400)
+	})
+}
+
+func TestHandleResetPassword(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	authConfig := configs.NewAuthConfig()
+	revocations := memory.NewRevocationStore()
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	refreshRepo := repos.NewRefreshTokenRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, authConfig,
+		services.WithRefreshTokenRepo(refreshRepo),
+		services.WithRevocationStore(revocations),
+	)
+	handler := NewHandler(service)
+	middleware := NewMiddleware(authConfig, revocations)
+
+	ts.App.Post("/auth/login", handler.HandleSignIn())
+	ts.App.Post("/auth/refresh", handler.HandleRefresh())
+	ts.App.Post("/auth/password/reset", handler.HandleResetPassword())
+	ts.App.Get("/protected", middleware.Authenticated(), func(c *fiber.Ctx) error { return c.SendStatus(200) })
+
+	post := func(route string, body []byte) *http.Response {
+		req := httptest.NewRequest("POST", route, bytes.NewReader(body))
+		req.Header.Add("Content-Type", "application/json")
+
+		res, err := ts.App.Test(req, 20000)
+		assert.NoErr(err)
+		return res
+	}
+	signIn := func(password string) *http.Response {
+		return post("/auth/login", []byte(fmt.Sprintf("{\"username\":\"username1\",\"password\":\"%s\"}", password)))
+	}
+
+	res := signIn("password1")
+	assert.Equal(res.StatusCode, 200)
+	tokens := &user.SignInResponse{}
+	assert.NoErr(json.NewDecoder(res.Body).Decode(tokens))
+	assert.NoErr(res.Body.Close())
+	testx.WaitNextSecond()
+
+	tests := []struct {
+		name     string
+		reqBody  []byte
+		wantCode int
+		verify   func(t *testing.T)
+	}{
+		{
+			name:     "given expired token should return 422",
+			reqBody:  []byte("{\"token\":\"expired-reset-token\",\"newPassword\":\"Password1234!\"}"),
+			wantCode: 422,
+			verify:   func(t *testing.T) {},
+		},
+		{
+			name:     "given password containing username should return 400",
+			reqBody:  []byte("{\"token\":\"reset-token\",\"newPassword\":\"Username1234!\"}"),
+			wantCode: 400,
+			verify:   func(t *testing.T) {},
+		},
+		{
+			name:     "given valid token should return 204 and sign out user everywhere",
+			reqBody:  []byte("{\"token\":\"reset-token\",\"newPassword\":\"Password1234!\"}"),
+			wantCode: 204,
+			verify: func(t *testing.T) {
+				assert.Equal(signIn("password1").StatusCode, 400)
+				assert.Equal(signIn("Password1234!").StatusCode, 200)
+
+				req := httptest.NewRequest("GET", "/protected", nil)
+				req.Header.Add(fiber.HeaderAuthorization, "Bearer "+tokens.Token)
+				res, err := ts.App.Test(req, 20000)
+				assert.NoErr(err)
+				assert.Equal(res.StatusCode, 401)
+
+				res = post("/auth/refresh", []byte(fmt.Sprintf("{\"refreshToken\":\"%s\"}", tokens.RefreshToken)))
+				assert.Equal(res.StatusCode, 401)
+			},
+		},
+		{
+			name:     "given already used token should return 422",
+			reqBody:  []byte("{\"token\":\"reset-token\",\"newPassword\":\"Password5678!\"}"),
+			wantCode: 422,
+			verify:   func(t *testing.T) {},
+		},
+		{
+			name:     "given too short password should return 400",
+			reqBody:  []byte("{\"token\":\"reset-token\",\"newPassword\":\"short\"}"),
+			wantCode: 400,
+			verify:   func(t *testing.T) {},
+		},
+		{
+			name:     "given empty request should return 400",
+			reqBody:  []byte(""),
+			wantCode: 400,
+			verify:   func(t *testing.T) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := post("/auth/password/reset", tt.reqBody)
+			assert.Equal(res.StatusCode, tt.wantCode)
+			tt.verify(t)
+		})
+	}
+}
+
+func TestHandleMfa(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	authConfig := configs.NewAuthConfig()
+	revocations := memory.NewRevocationStore()
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, authConfig,
+		services.WithRefreshTokenRepo(repos.NewRefreshTokenRepo(ts.TestDb.BunDb)),
+		services.WithRevocationStore(revocations),
+		services.WithMfaRepo(repos.NewMfaRepo(ts.TestDb.BunDb)),
+	)
+	handler := NewHandler(service)
+	middleware := NewMiddleware(authConfig, revocations)
+
+	ts.App.Post("/auth/login", handler.HandleSignIn())
+	ts.App.Post("/auth/mfa/verify", handler.HandleVerifyMfa())
+	ts.App.Post("/auth/mfa/enroll", middleware.Authenticated(), handler.HandleEnrollMfa())
+	ts.App.Post("/auth/mfa/enable", middleware.Authenticated(), handler.HandleEnableMfa())
+	ts.App.Post("/auth/mfa/disable", middleware.Authenticated(), handler.HandleDisableMfa())
+
+	send := func(route, accessToken string, body []byte, v any) int {
+		req := httptest.NewRequest("POST", route, bytes.NewReader(body))
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add(fiber.HeaderAuthorization, "Bearer "+accessToken)
+
+		res, err := ts.App.Test(req, 20000)
+		assert.NoErr(err)
+		defer func(body io.ReadCloser) {
+			if err := body.Close(); err != nil {
+				fmt.Println("error occurred on body close:", err.Error())
+			}
+		}(res.Body)
+
+		if v != nil && res.StatusCode == 200 {
+			assert.NoErr(json.NewDecoder(res.Body).Decode(v))
+		}
+		return res.StatusCode
+	}
+	signIn := func() *user.SignInResponse {
+		res := &user.SignInResponse{}
+		assert.Equal(send("/auth/login", "", []byte("{\"username\":\"username1\",\"password\":\"password1\"}"), res), 200)
+		return res
+	}
+	codeBody := func(code string) []byte {
+		return []byte(fmt.Sprintf("{\"code\":\"%s\"}", code))
+	}
+	verifyBody := func(mfaToken, code string) []byte {
+		return []byte(fmt.Sprintf("{\"mfaToken\":\"%s\",\"code\":\"%s\"}", mfaToken, code))
+	}
+
+	tokens := signIn()
+	assert.True(!tokens.MfaRequired)
+
+	enroll := &user.MfaEnrollResponse{}
+	assert.Equal(send("/auth/mfa/enroll", tokens.Token, nil, enroll), 200)
+	assert.True(strings.HasPrefix(enroll.URI, "otpauth://totp/"))
+
+	code, err := totp.Code(enroll.Secret, totp.Step(time.Now()))
+	assert.NoErr(err)
+
+	t.Run("given wrong code should not enable mfa", func(t *testing.T) {
+		assert.Equal(send("/auth/mfa/enable", tokens.Token, codeBody("000000"), nil), 422)
+		assert.True(!signIn().MfaRequired)
+	})
+
+	recovery := &user.RecoveryCodesResponse{}
+	assert.Equal(send("/auth/mfa/enable", tokens.Token, codeBody(code), recovery), 200)
+	assert.Equal(len(recovery.RecoveryCodes), 10)
+
+	t.Run("given enabled mfa sign in should return challenge instead of tokens", func(t *testing.T) {
+		res := signIn()
+		assert.True(res.MfaRequired)
+		assert.True(res.MfaToken != "")
+		assert.Equal(res.Token, "")
+		assert.Equal(res.RefreshToken, "")
+	})
+
+	t.Run("given already used totp code should return 401", func(t *testing.T) {
+		assert.Equal(send("/auth/mfa/verify", "", verifyBody(signIn().MfaToken, code), nil), 401)
+	})
+
+	t.Run("given recovery code should return tokens only once", func(t *testing.T) {
+		challenge := signIn()
+
+		res := &user.SignInResponse{}
+		assert.Equal(send("/auth/mfa/verify", "", verifyBody(challenge.MfaToken, recovery.RecoveryCodes[0]), res), 200)
+		assert.True(res.Token != "")
+		assert.True(res.RefreshToken != "")
+
+		// challenge is single-use
+		assert.Equal(send("/auth/mfa/verify", "", verifyBody(challenge.MfaToken, recovery.RecoveryCodes[1]), nil), 401)
+		// recovery code is single-use
+		assert.Equal(send("/auth/mfa/verify", "", verifyBody(signIn().MfaToken, recovery.RecoveryCodes[0]), nil), 401)
+	})
+
+	t.Run("given too many wrong codes should invalidate challenge", func(t *testing.T) {
+		challenge := signIn()
+		for i := 0; i < 5; i++ {
+			assert.Equal(send("/auth/mfa/verify", "", verifyBody(challenge.MfaToken, "000000"), nil), 401)
+		}
+		assert.Equal(send("/auth/mfa/verify", "", verifyBody(challenge.MfaToken, recovery.RecoveryCodes[1]), nil), 401)
+	})
+
+	t.Run("given enabled mfa should not allow enrolling again", func(t *testing.T) {
+		assert.Equal(send("/auth/mfa/enroll", tokens.Token, nil, nil), 422)
+	})
+
+	t.Run("given recovery code should disable mfa", func(t *testing.T) {
+		assert.Equal(send("/auth/mfa/disable", tokens.Token, codeBody("000000"), nil), 422)
+		assert.Equal(send("/auth/mfa/disable", tokens.Token, codeBody(recovery.RecoveryCodes[2]), nil), 204)
+		assert.True(!signIn().MfaRequired)
+	})
+
+	t.Run("given admin reset should disable mfa", func(t *testing.T) {
+		assert.Equal(send("/auth/mfa/enroll", tokens.Token, nil, enroll), 200)
+		code, err := totp.Code(enroll.Secret, totp.Step(time.Now()))
+		assert.NoErr(err)
+		assert.Equal(send("/auth/mfa/enable", tokens.Token, codeBody(code), nil), 200)
+		assert.True(signIn().MfaRequired)
+
+		assert.NoErr(service.ResetMfa(context.Background(), uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01")))
+		assert.True(!signIn().MfaRequired)
+	})
+
+	t.Run("given missing token should return 401", func(t *testing.T) {
+		assert.Equal(send("/auth/mfa/enroll", "", nil, nil), 401)
+	})
+}
+
+func TestHandleApiKeys(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	authConfig := configs.NewAuthConfig()
+	revocations := memory.NewRevocationStore()
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, authConfig,
+		services.WithRefreshTokenRepo(repos.NewRefreshTokenRepo(ts.TestDb.BunDb)),
+		services.WithApiKeyRepo(repos.NewApiKeyRepo(ts.TestDb.BunDb)),
+		services.WithRevocationStore(revocations),
+	)
+	handler := NewHandler(service)
+	middleware := NewMiddleware(authConfig, revocations, WithApiKeys(service))
+
+	ts.App.Post("/auth/login", handler.HandleSignIn())
+	ts.App.Post("/auth/apikeys", middleware.Authenticated(), handler.HandleCreateApiKey())
+	ts.App.Get("/auth/apikeys", middleware.Authenticated(), handler.HandleGetApiKeys())
+	ts.App.Delete("/auth/apikeys/:id", middleware.Authenticated(), handler.HandleRevokeApiKey())
+	ts.App.Post("/auth/logout", middleware.Authenticated(), handler.HandleSignOut())
+	ts.App.Post("/auth/logout/all", middleware.Authenticated(), handler.HandleSignOutAll())
+	ts.App.Post("/auth/mfa/enroll", middleware.Authenticated(), handler.HandleEnrollMfa())
+	ts.App.Post("/auth/mfa/enable", middleware.Authenticated(), handler.HandleEnableMfa())
+	ts.App.Post("/auth/mfa/disable", middleware.Authenticated(), handler.HandleDisableMfa())
+	ts.App.Get("/read", middleware.Authenticated(), middleware.RequireScopes(security.PERM_USER_READ), func(c *fiber.Ctx) error {
+		return c.SendStatus(200)
+	})
+	ts.App.Get("/write", middleware.Authenticated(), middleware.RequireScopes(security.PERM_USER_WRITE), func(c *fiber.Ctx) error {
+		return c.SendStatus(200)
+	})
+
+	send := func(method, route, header, credential string, body []byte, v any) int {
+		req := httptest.NewRequest(method, route, bytes.NewReader(body))
+		req.Header.Add("Content-Type", "application/json")
+		if header != "" {
+			req.Header.Add(header, credential)
+		}
+
+		res, err := ts.App.Test(req, 20000)
+		assert.NoErr(err)
+		defer func(body io.ReadCloser) {
+			if err := body.Close(); err != nil {
+				fmt.Println("error occurred on body close:", err.Error())
+			}
+		}(res.Body)
+
+		if v != nil && res.StatusCode < 300 {
+			assert.NoErr(json.NewDecoder(res.Body).Decode(v))
+		}
+		return res.StatusCode
+	}
+
+	tokens := &user.SignInResponse{}
+	assert.Equal(send("POST", "/auth/login", "", "", []byte("{\"username\":\"username1\",\"password\":\"password1\"}"), tokens), 200)
+	bearer := "Bearer " + tokens.Token
+
+	created := &security.CreateApiKeyResponse{}
+	assert.Equal(send("POST", "/auth/apikeys", fiber.HeaderAuthorization, bearer,
+		[]byte("{\"name\":\"deploy\",\"scopes\":[\"user:read\"],\"expiresInDays\":7}"), created), 201)
+	assert.True(strings.HasPrefix(created.Key, security.ApiKeyPrefix))
+	assert.True(strings.HasPrefix(created.Key, created.Prefix))
+	assert.Equal(created.Scopes, []string{security.PERM_USER_READ})
+
+	t.Run("given api key should access route within its scopes", func(t *testing.T) {
+		assert.Equal(send("GET", "/read", HeaderApiKey, created.Key, nil, nil), 200)
+		assert.Equal(send("GET", "/read", fiber.HeaderAuthorization, "Bearer "+created.Key, nil, nil), 200)
+	})
+
+	t.Run("given api key should be denied route out of its scopes", func(t *testing.T) {
+		assert.Equal(send("GET", "/write", HeaderApiKey, created.Key, nil, nil), 403)
+	})
+
+	t.Run("given expired api key should return 401", func(t *testing.T) {
+		assert.Equal(send("GET", "/read", HeaderApiKey, "gs_expired-api-key", nil, nil), 401)
+	})
+
+	t.Run("given api key should not create new api key", func(t *testing.T) {
+		assert.Equal(send("POST", "/auth/apikeys", HeaderApiKey, created.Key, []byte("{\"name\":\"escalate\"}"), nil), 403)
+	})
+
+	t.Run("given api key should not manage sessions or two-factor authentication", func(t *testing.T) {
+		for _, route := range []string{"/auth/logout", "/auth/logout/all", "/auth/mfa/enroll"} {
+			assert.Equal(send("POST", route, HeaderApiKey, created.Key, nil, nil), 403)
+		}
+		for _, route := range []string{"/auth/mfa/enable", "/auth/mfa/disable"} {
+			assert.Equal(send("POST", route, HeaderApiKey, created.Key, []byte("{\"code\":\"123456\"}"), nil), 403)
+		}
+		assert.Equal(send("GET", "/read", HeaderApiKey, created.Key, nil, nil), 200)
+	})
+
+	t.Run("given scope not granted to the user should return 422", func(t *testing.T) {
+		assert.Equal(send("POST", "/auth/apikeys", fiber.HeaderAuthorization, bearer,
+			[]byte("{\"name\":\"mfa\",\"scopes\":[\"user:mfa\"]}"), nil), 422)
+	})
+
+	t.Run("given get api keys request should return keys with last used time", func(t *testing.T) {
+		var keys []security.ApiKeyDto
+		assert.Equal(send("GET", "/auth/apikeys", fiber.HeaderAuthorization, bearer, nil, &keys), 200)
+		assert.Equal(len(keys), 3)
+		assert.Equal(keys[0].ID, created.ID)
+		assert.True(keys[0].LastUsedAt != nil)
+	})
+
+	t.Run("given revoked api key should return 401", func(t *testing.T) {
+		assert.Equal(send("DELETE", "/auth/apikeys/"+created.ID, fiber.HeaderAuthorization, bearer, nil, nil), 204)
+		assert.Equal(send("GET", "/read", HeaderApiKey, created.Key, nil, nil), 401)
+	})
+
+	t.Run("given signed out everywhere user should revoke all its api keys", func(t *testing.T) {
+		other := &security.CreateApiKeyResponse{}
+		assert.Equal(send("POST", "/auth/apikeys", fiber.HeaderAuthorization, bearer,
+			[]byte("{\"name\":\"other\",\"scopes\":[\"user:read\"]}"), other), 201)
+		assert.Equal(send("GET", "/read", HeaderApiKey, other.Key, nil, nil), 200)
+
+		assert.Equal(send("POST", "/auth/logout/all", fiber.HeaderAuthorization, bearer, nil, nil), 204)
+		assert.Equal(send("GET", "/read", HeaderApiKey, other.Key, nil, nil), 401)
+	})
+}
+
+func TestHandleOidc(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	stub, err := testx.SetUpOidcProvider()
+	assert.NoErr(err)
+	defer stub.Close()
+
+	cfg := stub.Config("stub", "http://localhost:8080/auth/oidc/stub/callback")
+	authConfig := configs.NewAuthConfig(configs.OidcProviders(cfg))
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, authConfig,
+		services.WithRefreshTokenRepo(repos.NewRefreshTokenRepo(ts.TestDb.BunDb)),
+		services.WithIdentityProviders(map[string]ports.IdentityProvider{"stub": oidc.NewProvider(cfg)}),
+	)
+	handler := NewHandler(service)
+
+	ts.App.Get("/auth/oidc/:provider/login", handler.HandleOidcLogin())
+	ts.App.Get("/auth/oidc/:provider/callback", handler.HandleOidcCallback())
+
+	// login starts the flow and returns the login cookie and the callback url provider redirected back to
+	login := func() (*http.Cookie, *url.URL) {
+		res, err := ts.App.Test(httptest.NewRequest("GET", "/auth/oidc/stub/login", nil), 20000)
+		assert.NoErr(err)
+		assert.Equal(res.StatusCode, 302)
+
+		var cookie *http.Cookie
+		for _, c := range res.Cookies() {
+			if c.Name == "oidc_stub" {
+				cookie = c
+			}
+		}
+		assert.True(cookie != nil)
+		assert.True(cookie.HttpOnly)
+		assert.Equal(cookie.Path, "/auth/oidc/stub")
+
+		back, err := stub.SignIn(res.Header.Get(fiber.HeaderLocation))
+		assert.NoErr(err)
+		return cookie, back
+	}
+
+	callback := func(cookie *http.Cookie, uri string, v any) int {
+		req := httptest.NewRequest("GET", uri, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		res, err := ts.App.Test(req, 20000)
+		assert.NoErr(err)
+		defer func(body io.ReadCloser) {
+			if err := body.Close(); err != nil {
+				fmt.Println("error occurred on body close:", err.Error())
+			}
+		}(res.Body)
+
+		if v != nil && res.StatusCode < 300 {
+			assert.NoErr(json.NewDecoder(res.Body).Decode(v))
+		}
+		return res.StatusCode
+	}
+
+	t.Run("given new identity should provision user with default roles", func(t *testing.T) {
+		stub.Claims = jwt.MapClaims{"sub": "stub-new", "email": "new@fake.com", "email_verified": true, "name": "New User"}
+		cookie, back := login()
+
+		tokens := &user.SignInResponse{}
+		assert.Equal(callback(cookie, back.RequestURI(), tokens), 200)
+		assert.True(tokens.Token != "")
+		assert.True(tokens.RefreshToken != "")
+
+		u, err := repo.GetByIdentity(context.Background(), "stub", "stub-new")
+		assert.NoErr(err)
+		assert.Equal(u.Email, "new@fake.com")
+		assert.Equal(u.FullName, "New User")
+		assert.Equal(len(u.Roles), 1)
+		assert.Equal(u.Roles[0].Name, security.ROLE_USER)
+
+		// next sign in finds the linked user
+		cookie, back = login()
+		assert.Equal(callback(cookie, back.RequestURI(), nil), 200)
+	})
+
+	t.Run("given verified email of existing user should link identity", func(t *testing.T) {
+		stub.Claims = jwt.MapClaims{"sub": "stub-john", "email": "john@smith.com", "email_verified": true}
+		cookie, back := login()
+		assert.Equal(callback(cookie, back.RequestURI(), nil), 200)
+
+		u, err := repo.GetByIdentity(context.Background(), "stub", "stub-john")
+		assert.NoErr(err)
+		assert.Equal(u.ID, uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01"))
+	})
+
+	t.Run("given unverified email of existing user should return 401", func(t *testing.T) {
+		stub.Claims = jwt.MapClaims{"sub": "stub-emily", "email": "em@parker.com", "email_verified": false}
+		cookie, back := login()
+		assert.Equal(callback(cookie, back.RequestURI(), nil), 401)
+	})
+
+	t.Run("given state that does not match the cookie should return 401", func(t *testing.T) {
+		stub.Claims = jwt.MapClaims{"sub": "stub-new", "email": "new@fake.com", "email_verified": true}
+		cookie, back := login()
+		q := back.Query()
+		q.Set("state", "forged-state")
+		assert.Equal(callback(cookie, back.Path+"?"+q.Encode(), nil), 401)
+	})
+
+	t.Run("given callback without login cookie should return 400", func(t *testing.T) {
+		_, back := login()
+		assert.Equal(callback(nil, back.RequestURI(), nil), 400)
+	})
+
+	t.Run("given provider error should return 401", func(t *testing.T) {
+		cookie, _ := login()
+		assert.Equal(callback(cookie, "/auth/oidc/stub/callback?error=access_denied", nil), 401)
+	})
+
+	t.Run("given unknown provider should return 404", func(t *testing.T) {
+		res, err := ts.App.Test(httptest.NewRequest("GET", "/auth/oidc/unknown/login", nil), 20000)
+		assert.NoErr(err)
+		assert.Equal(res.StatusCode, 404)
+	})
+}
+
+func TestHandleSignInLdap(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	memberOf := []string{"cn=admins,ou=groups,dc=example,dc=com"}
+	server, err := testx.SetUpLdapServer(testx.LdapEntry{
+		DN:       "uid=jdoe,ou=people,dc=example,dc=com",
+		Password: "jdoe-secret",
+		Attributes: map[string][]string{
+			"objectClass": {"person"},
+			"uid":         {"jdoe"},
+			"cn":          {"Jane Doe"},
+			"mail":        {"jane@example.com"},
+			"memberOf":    memberOf,
+		},
+	})
+	assert.NoErr(err)
+	defer server.Close()
+
+	cfg := configs.LdapConfig{
+		URL:        server.URL(),
+		BaseDN:     "ou=people,dc=example,dc=com",
+		GroupRoles: map[string][]string{"admins": {security.ROLE_ADMIN}},
+	}
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, configs.NewAuthConfig(configs.Ldap(cfg)),
+		services.WithRefreshTokenRepo(repos.NewRefreshTokenRepo(ts.TestDb.BunDb)),
+		services.WithAuthenticator(services.NewDirectoryAuthenticator(ldap.NewDirectory(cfg), repo, cfg)),
+	)
+	handler := NewHandler(service)
+	ts.App.Post("/auth/login", handler.HandleSignIn())
+
+	signIn := func(password string) int {
+		req := httptest.NewRequest("POST", "/auth/login",
+			strings.NewReader(fmt.Sprintf("{\"username\":\"jdoe\",\"password\":%q}", password)))
+		req.Header.Add("Content-Type", "application/json")
+		res, err := ts.App.Test(req, 20000)
+		assert.NoErr(err)
+		return res.StatusCode
+	}
+
+	roleNames := func(u *user.User) []string {
+		var names []string
+		for _, r := range u.Roles {
+			names = append(names, r.Name)
+		}
+		return names
+	}
+
+	t.Run("given first sign in should provision user with mapped roles", func(t *testing.T) {
+		assert.Equal(signIn("jdoe-secret"), 200)
+
+		u, err := repo.GetByIdentity(context.Background(), services.DirectoryProvider, "jdoe")
+		assert.NoErr(err)
+		assert.Equal(u.Email, "jane@example.com")
+		assert.Equal(u.FullName, "Jane Doe")
+		assert.Equal(len(u.Roles), 2)
+		assert.True(slices.Contains(roleNames(u), security.ROLE_ADMIN))
+		assert.True(slices.Contains(roleNames(u), security.ROLE_USER))
+	})
+
+	t.Run("given user removed from group should remove mapped role", func(t *testing.T) {
+		server.Entries[0].Attributes["memberOf"] = nil
+		defer func() { server.Entries[0].Attributes["memberOf"] = memberOf }()
+
+		assert.Equal(signIn("jdoe-secret"), 200)
+
+		u, err := repo.GetByIdentity(context.Background(), services.DirectoryProvider, "jdoe")
+		assert.NoErr(err)
+		assert.Equal(roleNames(u), []string{security.ROLE_USER})
+	})
+
+	t.Run("given wrong password should return 400", func(t *testing.T) {
+		assert.Equal(signIn("wrong-secret"), 400)
+	})
+}
+
+func TestHandleSession(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	service := services.NewUserService(repos.NewUserRepo(ts.TestDb.BunDb), configs.NewAuthConfig(),
+		services.WithSessionStore(memory.NewSessionStore()),
+	)
+	handler := NewHandler(service)
+	m := NewMiddleware(configs.NewAuthConfig(), memory.NewRevocationStore(), WithSessions(service))
+
+	ts.App.Post("/login", handler.HandleSessionLogin())
+	ts.App.Post("/logout", handler.HandleSessionLogout())
+	ts.App.Get("/users", m.SessionAuthenticated(), func(c *fiber.Ctx) error {
+		claims, _ := localClaims(c)
+		return c.SendString(claims["sub"].(string))
+	})
+
+	login := func(uri string, form url.Values) *http.Response {
+		req := httptest.NewRequest("POST", uri, strings.NewReader(form.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		res, err := ts.App.Test(req, 20000)
+		assert.NoErr(err)
+		return res
+	}
+
+	sessionCookie := func(res *http.Response) *http.Cookie {
+		for _, c := range res.Cookies() {
+			if c.Name == SessionCookie {
+				return c
+			}
+		}
+		return nil
+	}
+
+	get := func(uri string, cookie *http.Cookie) *http.Response {
+		req := httptest.NewRequest("GET", uri, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		res, err := ts.App.Test(req, 20000)
+		assert.NoErr(err)
+		return res
+	}
+
+	t.Run("given page without session should redirect to login", func(t *testing.T) {
+		res := get("/users", nil)
+		assert.Equal(res.StatusCode, 303)
+		assert.Equal(res.Header.Get(fiber.HeaderLocation), "/login?next=%2Fusers")
+	})
+
+	t.Run("given valid credentials should start session and redirect to next page", func(t *testing.T) {
+		res := login("/login?next=%2Fusers", url.Values{"username": {"username1"}, "password": {"password1"}})
+		assert.Equal(res.StatusCode, 303)
+		assert.Equal(res.Header.Get(fiber.HeaderLocation), "/users")
+
+		cookie := sessionCookie(res)
+		assert.True(cookie != nil)
+		assert.True(cookie.HttpOnly)
+		assert.True(!cookie.Secure) // secure only in production, like the CSRF cookie
+		assert.Equal(cookie.SameSite, http.SameSiteLaxMode)
+
+		res = get("/users", cookie)
+		assert.Equal(res.StatusCode, 200)
+		body, err := io.ReadAll(res.Body)
+		assert.NoErr(err)
+		assert.Equal(string(body), "220cea28-b2b0-4051-9eb6-9a99e451af01")
+
+		// signed out session is not accepted anymore
+		req := httptest.NewRequest("POST", "/logout", nil)
+		req.AddCookie(cookie)
+		res, err = ts.App.Test(req, 20000)
+		assert.NoErr(err)
+		assert.Equal(res.StatusCode, 303)
+		assert.Equal(res.Header.Get(fiber.HeaderLocation), "/login")
+		assert.Equal(sessionCookie(res).Value, "")
+
+		assert.Equal(get("/users", cookie).StatusCode, 303)
+	})
+
+	t.Run("given invalid password should redirect back to login", func(t *testing.T) {
+		res := login("/login?next=%2Fusers", url.Values{"username": {"username1"}, "password": {"invalid-password"}})
+		assert.Equal(res.StatusCode, 303)
+		assert.Equal(res.Header.Get(fiber.HeaderLocation), "/login?next=%2Fusers")
+		assert.True(sessionCookie(res) == nil)
+	})
+
+	t.Run("given off-site next page should redirect home", func(t *testing.T) {
+		res := login("/login?next=%2F%2Fevil.com", url.Values{"username": {"username1"}, "password": {"password1"}})
+		assert.Equal(res.StatusCode, 303)
+		assert.Equal(res.Header.Get(fiber.HeaderLocation), "/")
+	})
+
+	t.Run("given forged session cookie should redirect to login", func(t *testing.T) {
+		res := get("/users", &http.Cookie{Name: SessionCookie, Value: "forged"})
+		assert.Equal(res.StatusCode, 303)
+	})
+}
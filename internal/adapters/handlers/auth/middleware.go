@@ -1,83 +1,331 @@
 package auth
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
 
 	"github.com/fmiskovic/go-starter/internal/core/configs"
 	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/fmiskovic/go-starter/internal/core/ports"
 	"github.com/fmiskovic/go-starter/internal/utils"
+	"github.com/fmiskovic/go-starter/internal/utils/jwks"
 	jwtware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// HeaderApiKey is alternative to sending API key as bearer token.
+const HeaderApiKey = "X-API-Key"
+
+var errMissingClaims = errors.New("token is missing jti, sub or iat claim")
+
+// ApiKeyAuthenticator resolves API key into claims of its owner.
+type ApiKeyAuthenticator interface {
+	AuthenticateApiKey(ctx context.Context, key string) (jwt.MapClaims, error)
+}
+
+// SessionAuthenticator resolves UI session token into claims of the signed in user.
+type SessionAuthenticator interface {
+	AuthenticateSession(ctx context.Context, sessionToken string) (jwt.MapClaims, error)
+}
+
 type Middleware struct {
-	cfg configs.AuthConfig
+	cfg         configs.AuthConfig
+	keys        jwks.KeySet
+	revocations ports.RevocationStore[uuid.UUID]
+	apiKeys     ApiKeyAuthenticator
+	sessions    SessionAuthenticator
+}
+
+func NewMiddleware(cfg configs.AuthConfig, revocations ports.RevocationStore[uuid.UUID], opts ...MiddlewareOption) Middleware {
+	m := &Middleware{cfg: cfg, keys: jwks.New(cfg), revocations: revocations}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return *m
 }
 
-func NewMiddleware(cfg configs.AuthConfig) Middleware {
-	return Middleware{cfg: cfg}
+// MiddlewareOption func used to configure optional Middleware dependencies.
+type MiddlewareOption func(m *Middleware)
+
+// WithApiKeys makes Authenticated accept API keys alongside JWTs.
+func WithApiKeys(a ApiKeyAuthenticator) MiddlewareOption {
+	return func(m *Middleware) {
+		m.apiKeys = a
+	}
+}
+
+// WithSessions enables SessionAuthenticated.
+func WithSessions(a SessionAuthenticator) MiddlewareOption {
+	return func(m *Middleware) {
+		m.sessions = a
+	}
 }
 
+// Authenticated allows access with valid access token or, if configured, with API key.
 func (m Middleware) Authenticated() fiber.Handler {
-	return jwtware.New(jwtware.Config{
-		SigningKey: jwtware.SigningKey{Key: []byte(m.cfg.Secret)},
+	jwtHandler := jwtware.New(jwtware.Config{
+		KeyFunc: m.keys.Keyfunc,
+		SuccessHandler: func(c *fiber.Ctx) error {
+			token := c.Locals("user").(*jwt.Token)
+			claims := token.Claims.(jwt.MapClaims)
+
+			if m.isRevoked(c.Context(), claims) {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"message": "Token is revoked",
+				})
+			}
+			if !containsAll(tokenScopes(claims), m.cfg.Scopes) {
+				slog.Error("jwt is missing required scopes", "scopes", m.cfg.Scopes)
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"message": "Permission denied",
+				})
+			}
+			return c.Next()
+		},
 	})
-}
 
-func (m Middleware) AdminAuthenticated() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		authHeader := c.Get(fiber.HeaderAuthorization)
-		if utils.IsBlank(authHeader) {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"message": "Unauthorized",
-			})
+		if key, ok := m.apiKey(c); ok {
+			return m.authenticateApiKey(c, key)
 		}
-		tokenString := c.Get(fiber.HeaderAuthorization)[7:]
-		claims := jwt.MapClaims{}
+		return jwtHandler(c)
+	}
+}
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(m.cfg.Secret), nil
+// apiKey returns API key sent in X-API-Key header or as bearer token.
+func (m Middleware) apiKey(c *fiber.Ctx) (string, bool) {
+	if m.apiKeys == nil {
+		return "", false
+	}
+	if key := c.Get(HeaderApiKey); !utils.IsBlank(key) {
+		return key, true
+	}
+
+	key, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	return key, found && strings.HasPrefix(key, security.ApiKeyPrefix)
+}
+
+// authenticateApiKey stores claims of the API key owner the same way jwtware stores parsed token,
+// so the key passes RequireRoles and RequireScopes like access token does.
+func (m Middleware) authenticateApiKey(c *fiber.Ctx, key string) error {
+	claims, err := m.apiKeys.AuthenticateApiKey(c.Context(), key)
+	if err == nil {
+		claims, err = parsedClaims(claims)
+	}
+	if err != nil {
+		slog.Error("authenticating api key", "error", err)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Invalid API key",
 		})
+	}
+
+	if !containsAll(tokenScopes(claims), m.cfg.Scopes) {
+		slog.Error("api key is missing required scopes", "scopes", m.cfg.Scopes)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": "Permission denied",
+		})
+	}
+
+	c.Locals("user", &jwt.Token{Claims: claims, Valid: true})
+	return c.Next()
+}
+
+// parsedClaims converts claims to the form they have after parsing jwt, e.g. uuids become strings.
+func parsedClaims(claims jwt.MapClaims) (jwt.MapClaims, error) {
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
 
+	parsed := jwt.MapClaims{}
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// SessionAuthenticated protects UI pages, it allows access with valid session cookie.
+// Visitor that is not signed in is redirected to the login page, which brings them back once they sign in.
+// Claims of the user are stored the same way jwtware stores parsed token, so RequireRoles and RequireScopes can be chained.
+func (m Middleware) SessionAuthenticated() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sessionToken := c.Cookies(SessionCookie)
+		if m.sessions == nil || utils.IsBlank(sessionToken) {
+			return redirectToLogin(c)
+		}
+
+		claims, err := m.sessions.AuthenticateSession(c.Context(), sessionToken)
+		if err == nil {
+			claims, err = parsedClaims(claims)
+		}
 		if err != nil {
-			slog.Error("parsing jwt", "error", err)
-			if err == jwt.ErrSignatureInvalid {
-				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-					"error": "Invalid signature",
-				})
-			}
+			slog.Error("authenticating session", "error", err)
+			clearSessionCookie(c)
+			return redirectToLogin(c)
+		}
+
+		c.Locals("user", &jwt.Token{Claims: claims, Valid: true})
+		return c.Next()
+	}
+}
+
+// redirectToLogin redirects to the login page, requested page is passed as "next" query param.
+func redirectToLogin(c *fiber.Ctx) error {
+	return c.Redirect("/login?next="+url.QueryEscape(c.OriginalURL()), fiber.StatusSeeOther)
+}
+
+// RequireRoles allows access only if the token holds at least one of the roles.
+// It must be chained after Authenticated.
+func (m Middleware) RequireRoles(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := localClaims(c)
+		if !ok {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"message": "Unauthorized",
 			})
 		}
 
-		if !token.Valid {
-			slog.Error("jwt is invalid")
+		if !containsAny(claimStrings(claims, "roles"), roles) {
+			slog.Error("jwt is missing required roles", "roles", roles)
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "Permission denied",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// RequireScopes allows access only if the token holds all the scopes.
+// It must be chained after Authenticated.
+func (m Middleware) RequireScopes(scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := localClaims(c)
+		if !ok {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"message": "Invalid token",
+				"message": "Unauthorized",
 			})
 		}
 
-		// Check if the user has the "admin" role
-		roles := claims["roles"].([]interface{})
-		if !containsAdminRole(roles) {
-			slog.Error("ROLE_ADMIN is not present in the jwt claims")
+		if !containsAll(tokenScopes(claims), scopes) {
+			slog.Error("jwt is missing required scopes", "scopes", scopes)
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"message": "Permission denied",
 			})
 		}
+		return c.Next()
+	}
+}
 
-		c.Locals("user", token)
+// SessionRequireScopes allows access to the UI page only if the session holds all the scopes.
+// Denied user gets error page instead of JSON response. It must be chained after SessionAuthenticated.
+func (m Middleware) SessionRequireScopes(scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := localClaims(c)
+		if !ok {
+			return redirectToLogin(c)
+		}
+
+		if !containsAll(tokenScopes(claims), scopes) {
+			slog.Error("session is missing required scopes", "scopes", scopes)
+			return c.Status(fiber.StatusForbidden).Render("error/403", nil)
+		}
 		return c.Next()
 	}
 }
 
-func containsAdminRole(roles []interface{}) bool {
-	for _, role := range roles {
-		if role == security.ROLE_ADMIN {
+// isRevoked checks whether the token or all tokens of its subject are revoked.
+// Tokens that can not be checked are treated as revoked.
+func (m Middleware) isRevoked(ctx context.Context, claims jwt.MapClaims) bool {
+	tokenID, userID, issuedAt, err := revocationClaims(claims)
+	if err != nil {
+		slog.Error("checking jwt revocation", "error", err)
+		return true
+	}
+
+	revoked, err := m.revocations.IsRevoked(ctx, tokenID, userID, issuedAt.Time)
+	if err != nil {
+		slog.Error("checking jwt revocation", "error", err)
+		return true
+	}
+	return revoked
+}
+
+func revocationClaims(claims jwt.MapClaims) (uuid.UUID, uuid.UUID, *jwt.NumericDate, error) {
+	jti, _ := claims["jti"].(string)
+	tokenID, err := uuid.Parse(jti)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, nil, errMissingClaims
+	}
+
+	sub, err := claims.GetSubject()
+	if err != nil {
+		return uuid.Nil, uuid.Nil, nil, err
+	}
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, nil, errMissingClaims
+	}
+
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil {
+		return uuid.Nil, uuid.Nil, nil, err
+	}
+	if issuedAt == nil {
+		return uuid.Nil, uuid.Nil, nil, errMissingClaims
+	}
+
+	return tokenID, userID, issuedAt, nil
+}
+
+func localClaims(c *fiber.Ctx) (jwt.MapClaims, bool) {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	return claims, ok
+}
+
+// claimStrings reads claim holding list of strings, e.g. roles.
+func claimStrings(claims jwt.MapClaims, key string) []string {
+	values, _ := claims[key].([]interface{})
+
+	var res []string
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// tokenScopes reads space delimited "scope" claim.
+func tokenScopes(claims jwt.MapClaims) []string {
+	scope, _ := claims["scope"].(string)
+	return strings.Fields(scope)
+}
+
+func containsAny(values []string, wanted []string) bool {
+	for _, w := range wanted {
+		if slices.Contains(values, w) {
 			return true
 		}
 	}
 	return false
 }
+
+func containsAll(values []string, wanted []string) bool {
+	for _, w := range wanted {
+		if !slices.Contains(values, w) {
+			return false
+		}
+	}
+	return true
+}
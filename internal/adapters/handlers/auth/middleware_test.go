@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/adapters/memory"
+	"github.com/fmiskovic/go-starter/internal/core/configs"
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/utils/jwks"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/template/django/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/matryer/is"
+)
+
+func TestMiddleware_RequireRolesAndScopes(t *testing.T) {
+	assert := is.New(t)
+
+	authConfig := configs.NewAuthConfig()
+	m := NewMiddleware(authConfig, memory.NewRevocationStore())
+
+	app := fiber.New()
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(200) }
+	app.Get("/admin", m.Authenticated(), m.RequireRoles(security.ROLE_ADMIN), ok)
+	app.Get("/role", m.Authenticated(), m.RequireRoles(security.ROLE_ADMIN, security.ROLE_MODERATOR), ok)
+	app.Get("/read", m.Authenticated(), m.RequireScopes(security.PERM_USER_READ), ok)
+	app.Get("/enable", m.Authenticated(), m.RequireScopes(security.PERM_USER_READ, security.PERM_USER_ENABLE), ok)
+	app.Get("/delete", m.Authenticated(), m.RequireScopes(security.PERM_USER_DELETE), ok)
+
+	admin := signTestToken(t, authConfig, []string{security.ROLE_ADMIN},
+		security.PERM_USER_READ, security.PERM_USER_ENABLE, security.PERM_USER_DELETE)
+	moderator := signTestToken(t, authConfig, []string{security.ROLE_MODERATOR},
+		security.PERM_USER_READ, security.PERM_USER_ENABLE)
+	regular := signTestToken(t, authConfig, []string{security.ROLE_USER})
+	noRoles := signTestToken(t, authConfig, nil)
+
+	tests := []struct {
+		name     string
+		route    string
+		token    string
+		wantCode int
+	}{
+		{name: "given admin token should access admin route", route: "/admin", token: admin, wantCode: 200},
+		{name: "given moderator token should be denied admin route", route: "/admin", token: moderator, wantCode: 403},
+		{name: "given token without roles should be denied admin route", route: "/admin", token: noRoles, wantCode: 403},
+		{name: "given moderator token should match one of the roles", route: "/role", token: moderator, wantCode: 200},
+		{name: "given user token should be denied role route", route: "/role", token: regular, wantCode: 403},
+		{name: "given moderator token should enable users", route: "/enable", token: moderator, wantCode: 200},
+		{name: "given moderator token should be denied deleting users", route: "/delete", token: moderator, wantCode: 403},
+		{name: "given admin token should delete users", route: "/delete", token: admin, wantCode: 200},
+		{name: "given user token should be denied reading users", route: "/read", token: regular, wantCode: 403},
+		{name: "given missing token should return 401", route: "/read", token: "", wantCode: 401},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.route, nil)
+			req.Header.Add(fiber.HeaderAuthorization, "Bearer "+tt.token)
+
+			res, err := app.Test(req, -1)
+			assert.NoErr(err)
+			assert.Equal(res.StatusCode, tt.wantCode)
+		})
+	}
+}
+
+func TestMiddleware_ConfiguredScopes(t *testing.T) {
+	assert := is.New(t)
+
+	authConfig := configs.NewAuthConfig(configs.Scopes([]string{"api"}))
+	m := NewMiddleware(authConfig, memory.NewRevocationStore())
+
+	app := fiber.New()
+	app.Get("/protected", m.Authenticated(), func(c *fiber.Ctx) error { return c.SendStatus(200) })
+
+	send := func(token string) int {
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.Header.Add(fiber.HeaderAuthorization, "Bearer "+token)
+
+		res, err := app.Test(req, -1)
+		assert.NoErr(err)
+		return res.StatusCode
+	}
+
+	// token issued with configured scopes
+	assert.Equal(send(signTestToken(t, authConfig, []string{security.ROLE_USER})), 200)
+	// token issued without configured scopes
+	assert.Equal(send(signTestToken(t, configs.NewAuthConfig(), []string{security.ROLE_USER})), 403)
+}
+
+// signTestToken signs access token the same way user service does.
+func TestMiddleware_SessionRequireScopes(t *testing.T) {
+	assert := is.New(t)
+
+	engine := django.New("../../../../views", ".html")
+	engine.AddFunc("css", func(name string) template.HTML { return "" })
+
+	m := NewMiddleware(configs.NewAuthConfig(), memory.NewRevocationStore())
+
+	app := fiber.New(fiber.Config{Views: engine})
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(200) }
+	// signed in user as SessionAuthenticated middleware would store it
+	session := func(c *fiber.Ctx) error {
+		c.Locals("user", &jwt.Token{Claims: jwt.MapClaims{"sub": uuid.NewString(), "scope": security.PERM_USER_READ}, Valid: true})
+		return c.Next()
+	}
+	app.Get("/read", session, m.SessionRequireScopes(security.PERM_USER_READ), ok)
+	app.Get("/delete", session, m.SessionRequireScopes(security.PERM_USER_DELETE), ok)
+	app.Get("/anonymous", m.SessionRequireScopes(security.PERM_USER_READ), ok)
+
+	t.Run("given session with scopes should access page", func(t *testing.T) {
+		res, err := app.Test(httptest.NewRequest("GET", "/read", nil))
+		assert.NoErr(err)
+		assert.Equal(res.StatusCode, 200)
+	})
+
+	t.Run("given session without scopes should render error page", func(t *testing.T) {
+		res, err := app.Test(httptest.NewRequest("GET", "/delete", nil))
+		assert.NoErr(err)
+		assert.Equal(res.StatusCode, 403)
+		assert.True(strings.HasPrefix(res.Header.Get(fiber.HeaderContentType), fiber.MIMETextHTML))
+
+		body, err := io.ReadAll(res.Body)
+		assert.NoErr(err)
+		assert.True(strings.Contains(string(body), "You do not have permission to access this page."))
+	})
+
+	t.Run("given missing session should redirect to login", func(t *testing.T) {
+		res, err := app.Test(httptest.NewRequest("GET", "/anonymous", nil))
+		assert.NoErr(err)
+		assert.Equal(res.StatusCode, 303)
+		assert.Equal(res.Header.Get(fiber.HeaderLocation), "/login?next=%2Fanonymous")
+	})
+}
+
+func TestMiddleware_ApiKey(t *testing.T) {
+	assert := is.New(t)
+
+	authConfig := configs.NewAuthConfig()
+	keys := fakeApiKeys{
+		"gs_reader": {"sub": uuid.New(), "roles": []string{security.ROLE_USER}, "scope": security.PERM_USER_READ, "api_key": uuid.New()},
+	}
+	m := NewMiddleware(authConfig, memory.NewRevocationStore(), WithApiKeys(keys))
+
+	app := fiber.New()
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(200) }
+	app.Get("/read", m.Authenticated(), m.RequireScopes(security.PERM_USER_READ), ok)
+	app.Get("/delete", m.Authenticated(), m.RequireScopes(security.PERM_USER_DELETE), ok)
+	app.Get("/role", m.Authenticated(), m.RequireRoles(security.ROLE_USER), ok)
+
+	tests := []struct {
+		name     string
+		route    string
+		header   string
+		value    string
+		wantCode int
+	}{
+		{name: "given api key as bearer token should access route", route: "/read", header: fiber.HeaderAuthorization, value: "Bearer gs_reader", wantCode: 200},
+		{name: "given api key in header should access route", route: "/read", header: HeaderApiKey, value: "gs_reader", wantCode: 200},
+		{name: "given api key should match roles of the owner", route: "/role", header: HeaderApiKey, value: "gs_reader", wantCode: 200},
+		{name: "given api key without scope should be denied", route: "/delete", header: HeaderApiKey, value: "gs_reader", wantCode: 403},
+		{name: "given unknown api key should return 401", route: "/read", header: HeaderApiKey, value: "gs_unknown", wantCode: 401},
+		{name: "given unknown bearer api key should return 401", route: "/read", header: fiber.HeaderAuthorization, value: "Bearer gs_unknown", wantCode: 401},
+		{
+			name:     "given jwt should still be accepted",
+			route:    "/read",
+			header:   fiber.HeaderAuthorization,
+			value:    "Bearer " + signTestToken(t, authConfig, nil, security.PERM_USER_READ),
+			wantCode: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.route, nil)
+			req.Header.Add(tt.header, tt.value)
+
+			res, err := app.Test(req, -1)
+			assert.NoErr(err)
+			assert.Equal(res.StatusCode, tt.wantCode)
+		})
+	}
+}
+
+// fakeApiKeys maps API keys to claims of their owners.
+type fakeApiKeys map[string]jwt.MapClaims
+
+func (f fakeApiKeys) AuthenticateApiKey(ctx context.Context, key string) (jwt.MapClaims, error) {
+	claims, ok := f[key]
+	if !ok {
+		return nil, apiErr.ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func signTestToken(t *testing.T, cfg configs.AuthConfig, roles []string, permissions ...string) string {
+	t.Helper()
+
+	scopes := append([]string{}, cfg.Scopes...)
+	scopes = append(scopes, permissions...)
+
+	now := time.Now()
+	token, err := jwks.New(cfg).Sign(jwt.MapClaims{
+		"sub":   uuid.New(),
+		"roles": roles,
+		"scope": strings.Join(scopes, " "),
+		"exp":   now.Add(time.Minute).Unix(),
+		"iat":   now.Unix(),
+		"jti":   uuid.New(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// oidcCookiePrefix prefixes cookie holding state, nonce and PKCE verifier of the login in progress.
+	oidcCookiePrefix = "oidc_"
+	// oidcLoginTimeout is how long user has to sign in with identity provider.
+	oidcLoginTimeout = 10 * time.Minute
+)
+
+// HandleOidcLogin redirects user to the identity provider.
+// Values needed to complete the login are kept in HttpOnly cookie scoped to the provider's routes.
+func (h Handler) HandleOidcLogin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		provider := c.Params("provider")
+
+		// call core service
+		res, err := h.service.StartOidcLogin(c.Context(), provider)
+		if errors.Is(err, apiErr.ErrUnknownProvider) {
+			return fiber.NewError(fiber.StatusNotFound, apiErr.New(apiErr.WithAppErr(err)).Error())
+		}
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadGateway,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrOidcSignIn)).Error())
+		}
+
+		// response, tokens are url-safe base64 so dot can separate them
+		c.Cookie(&fiber.Cookie{
+			Name:     oidcCookiePrefix + provider,
+			Value:    strings.Join([]string{res.State, res.Nonce, res.Verifier}, "."),
+			Path:     strings.TrimSuffix(c.Path(), "/login"),
+			Expires:  time.Now().Add(oidcLoginTimeout),
+			Secure:   c.Protocol() == "https",
+			HTTPOnly: true,
+			// Lax lets the cookie through on the top-level redirect back from the provider
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+		return c.Redirect(res.URL, fiber.StatusFound)
+	}
+}
+
+// HandleOidcCallback completes the login when identity provider redirects user back.
+// Response is the same as of HandleSignIn.
+func (h Handler) HandleOidcCallback() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		provider := c.Params("provider")
+		cookie := c.Cookies(oidcCookiePrefix + provider)
+
+		// login cookie is single use
+		c.Cookie(&fiber.Cookie{
+			Name:     oidcCookiePrefix + provider,
+			Path:     strings.TrimSuffix(c.Path(), "/callback"),
+			Expires:  time.Unix(0, 0),
+			Secure:   c.Protocol() == "https",
+			HTTPOnly: true,
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+
+		// provider reports denied consent and other failures as query params
+		if e := c.Query("error"); e != "" {
+			return fiber.NewError(fiber.StatusUnauthorized,
+				apiErr.New(apiErr.WithSvcErr(errors.New(e+" "+c.Query("error_description"))), apiErr.WithAppErr(apiErr.ErrOidcSignIn)).Error())
+		}
+
+		// parse request
+		var req = &user.OidcCallbackRequest{Provider: provider, Code: c.Query("code"), State: c.Query("state")}
+		if parts := strings.Split(cookie, "."); len(parts) == 3 {
+			req.ExpectedState, req.Nonce, req.Verifier = parts[0], parts[1], parts[2]
+		}
+
+		// validate request
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, strings.Join(errs, " and "))
+		}
+
+		// call core service
+		res, err := h.service.CompleteOidcLogin(c.Context(), req)
+		if errors.Is(err, apiErr.ErrUnknownProvider) {
+			return fiber.NewError(fiber.StatusNotFound, apiErr.New(apiErr.WithAppErr(err)).Error())
+		}
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnauthorized,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrOidcSignIn)).Error())
+		}
+
+		// response, access token is issued by HandleVerifyMfa if the second factor is required
+		if !res.MfaRequired {
+			c.Set(fiber.HeaderAuthorization, "Bearer "+res.Token)
+		}
+		return c.JSON(res)
+	}
+}
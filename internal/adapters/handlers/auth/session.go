@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sujit-baniya/flash"
+)
+
+// SessionCookie holds token of the UI session.
+const SessionCookie = "session"
+
+// HandleSessionLogin signs user in to the UI with the login form and redirects to the "next" page.
+// Failure redirects back to the login page with the error flashed.
+func (h Handler) HandleSessionLogin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		next := safeRedirect(c.Query("next"))
+
+		// parse request body
+		var req = new(user.SessionRequest)
+		if err := c.BodyParser(req); err != nil {
+			return loginFailed(c, next, apiErr.ErrParseReqBody.Error(), "")
+		}
+
+		// validate request
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			return loginFailed(c, next, apiErr.ErrInvalidAuthReq.Error(), req.Username)
+		}
+
+		// call core service, failed attempts are tracked per client IP too
+		req.ClientIP = c.IP()
+		res, err := h.service.StartSession(c.Context(), req)
+		var lockout apiErr.LockoutError
+		if errors.As(err, &lockout) {
+			return loginFailed(c, next, lockout.Error(), req.Username)
+		}
+		if errors.Is(err, apiErr.ErrServerBusy) {
+			return loginFailed(c, next, err.Error(), req.Username)
+		}
+		if errors.Is(err, apiErr.ErrMfaRequired) || errors.Is(err, apiErr.ErrInvalidCode) {
+			return flash.WithError(c, fiber.Map{
+				"systemMessage": err.Error(),
+				"username":      req.Username,
+				"mfaRequired":   true,
+			}).Redirect(loginURL(next), fiber.StatusSeeOther)
+		}
+		if err != nil {
+			return loginFailed(c, next, apiErr.ErrInvalidAuthReq.Error(), req.Username)
+		}
+
+		// response
+		c.Cookie(&fiber.Cookie{
+			Name:     SessionCookie,
+			Value:    res.Token,
+			Path:     "/",
+			Expires:  res.ExpiresAt,
+			Secure:   utils.IsProd(),
+			HTTPOnly: true,
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+		return c.Redirect(next, fiber.StatusSeeOther)
+	}
+}
+
+// HandleSessionLogout signs user out of the UI and redirects to the login page.
+func (h Handler) HandleSessionLogout() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if sessionToken := c.Cookies(SessionCookie); sessionToken != "" {
+			// call core service, the cookie is cleared even if the session can not be ended
+			if err := h.service.EndSession(c.Context(), sessionToken); err != nil {
+				slog.Error("ending session", "error", err)
+			}
+		}
+
+		// response
+		clearSessionCookie(c)
+		return c.Redirect("/login", fiber.StatusSeeOther)
+	}
+}
+
+// loginFailed redirects back to the login page and flashes the error, username is kept so it does not have to be typed again.
+func loginFailed(c *fiber.Ctx, next string, msg string, username string) error {
+	return flash.WithError(c, fiber.Map{
+		"systemMessage": msg,
+		"username":      username,
+	}).Redirect(loginURL(next), fiber.StatusSeeOther)
+}
+
+func loginURL(next string) string {
+	if next == "/" {
+		return "/login"
+	}
+	return "/login?next=" + url.QueryEscape(next)
+}
+
+func clearSessionCookie(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     SessionCookie,
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		Secure:   utils.IsProd(),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// safeRedirect returns the page to redirect to after signing in, only local paths are allowed so login can not redirect off-site.
+func safeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
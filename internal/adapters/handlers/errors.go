@@ -1,7 +1,24 @@
 package handlers
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/gofiber/fiber/v2"
+)
 
 func ErrorHandler(c *fiber.Ctx, err error) error {
 	return c.Render("error/500", nil)
 }
+
+// ServerBusy returns 503 error with Retry-After header if the service rejected the request as busy, otherwise nil.
+func ServerBusy(c *fiber.Ctx, err error) error {
+	var busy apiErr.BusyError
+	if !errors.As(err, &busy) {
+		return nil
+	}
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(max(int(math.Ceil(busy.RetryAfter.Seconds())), 1)))
+	return fiber.NewError(fiber.StatusServiceUnavailable, busy.Error())
+}
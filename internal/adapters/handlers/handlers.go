@@ -13,12 +13,8 @@ func HandleAbout(c *fiber.Ctx) error {
 	return c.Render("home/about", fiber.Map{})
 }
 
-func HandleUsers(c *fiber.Ctx) error {
-	return c.Render("home/users", fiber.Map{})
-}
-
 func HandleLogin(c *fiber.Ctx) error {
-	return c.Render("home/login", fiber.Map{})
+	return c.Render("home/login", fiber.Map{"next": c.Query("next")})
 }
 
 func HandleFlash(c *fiber.Ctx) error {
@@ -3,10 +3,15 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/fmiskovic/go-starter/internal/utils"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/csrf"
 	"github.com/sujit-baniya/flash"
 )
 
+// CsrfField is the form field of the CSRF token, the token is available to views as "csrf".
+const CsrfField = "_csrf"
+
 func NotFoundMiddleware(c *fiber.Ctx) error {
 	return c.Status(http.StatusNotFound).Render("error/404", nil)
 }
@@ -15,3 +20,16 @@ func FlashMiddleware(c *fiber.Ctx) error {
 	c.Locals("flash", flash.Get(c))
 	return c.Next()
 }
+
+// CsrfMiddleware protects form posts of the UI with double submit cookie.
+// Forms have to send the token rendered by the view in CsrfField.
+func CsrfMiddleware() fiber.Handler {
+	return csrf.New(csrf.Config{
+		KeyLookup:      "form:" + CsrfField,
+		CookieName:     "csrf_",
+		CookieSecure:   utils.IsProd(),
+		CookieHTTPOnly: true,
+		CookieSameSite: fiber.CookieSameSiteLaxMode,
+		ContextKey:     "csrf",
+	})
+}
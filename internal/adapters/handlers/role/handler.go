@@ -0,0 +1,259 @@
+package role
+
+import (
+	"strings"
+
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/fmiskovic/go-starter/internal/core/ports"
+	"github.com/fmiskovic/go-starter/internal/core/validators"
+	"github.com/google/uuid"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	service   ports.RoleService[uuid.UUID]
+	validator validators.Validator
+}
+
+func NewHandler(service ports.RoleService[uuid.UUID]) Handler {
+	return Handler{
+		service:   service,
+		validator: validators.New(),
+	}
+}
+
+// HandleGetAll creates handler func that is responsible for getting all catalog roles.
+// Response is json array of RoleDtos.
+func (h Handler) HandleGetAll() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// call core service
+		res, err := h.service.GetAll(c.Context())
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrGetAll)).Error())
+		}
+
+		// response
+		return toJson(c, res)
+	}
+}
+
+// HandleGetById creates handler func that is responsible for getting role by its ID.
+// Response is RoleDto json.
+func (h Handler) HandleGetById() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse query params
+		id, err := parseId(c)
+		if err != nil {
+			return err
+		}
+
+		// call core service
+		res, err := h.service.GetById(c.Context(), id)
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrGetById)).Error())
+		}
+
+		// response
+		return toJson(c, res)
+	}
+}
+
+// HandleCreate creates handler func that is responsible for adding new role into the catalog.
+// Response is RoleDto json.
+func (h Handler) HandleCreate() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse request body
+		req := new(security.RoleRequest)
+		if err := c.BodyParser(req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrParseReqBody)).Error())
+		}
+
+		// validate request
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, strings.Join(errs, " and "))
+		}
+
+		// call core service
+		res, err := h.service.Create(c.Context(), req)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrEntityCreate)).Error())
+		}
+
+		// response
+		c.Status(fiber.StatusCreated)
+		return toJson(c, res)
+	}
+}
+
+// HandleUpdate creates handler func that is responsible for updating role description.
+// Response is RoleDto json.
+func (h Handler) HandleUpdate() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse request body
+		req := new(security.UpdateRoleRequest)
+		if err := c.BodyParser(req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrParseReqBody)).Error())
+		}
+
+		// validate request
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, strings.Join(errs, " and "))
+		}
+
+		// call core service
+		res, err := h.service.Update(c.Context(), req)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrEntityUpdate)).Error())
+		}
+
+		// response
+		return toJson(c, res)
+	}
+}
+
+// HandleDeleteById creates handler func that is responsible for removing role from the catalog.
+func (h Handler) HandleDeleteById() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse query params
+		id, err := parseId(c)
+		if err != nil {
+			return err
+		}
+
+		// call core service
+		if err := h.service.DeleteById(c.Context(), id); err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrDeleteById)).Error())
+		}
+
+		// response
+		c.Status(fiber.StatusNoContent)
+		return nil
+	}
+}
+
+// HandleSetPermissions creates handler func that is responsible for replacing role permissions.
+func (h Handler) HandleSetPermissions() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse request body
+		req := new(security.RolePermissionsRequest)
+		if err := c.BodyParser(req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrParseReqBody)).Error())
+		}
+		req.ID = c.Params("id")
+
+		// validate request
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, strings.Join(errs, " and "))
+		}
+
+		// call core service
+		if err := h.service.SetPermissions(c.Context(), req); err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrEntityUpdate)).Error())
+		}
+
+		// response
+		c.Status(fiber.StatusNoContent)
+		return nil
+	}
+}
+
+// HandleGetPermissions creates handler func that is responsible for getting all catalog permissions.
+// Response is json array of PermissionDtos.
+func (h Handler) HandleGetPermissions() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// call core service
+		res, err := h.service.GetPermissions(c.Context())
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrGetAll)).Error())
+		}
+
+		// response
+		return toJson(c, res)
+	}
+}
+
+// HandleCreatePermission creates handler func that is responsible for adding new permission into the catalog.
+// Response is PermissionDto json.
+func (h Handler) HandleCreatePermission() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse request body
+		req := new(security.PermissionRequest)
+		if err := c.BodyParser(req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrParseReqBody)).Error())
+		}
+
+		// validate request
+		if errs := h.validator.Validate(req); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, strings.Join(errs, " and "))
+		}
+
+		// call core service
+		res, err := h.service.CreatePermission(c.Context(), req)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrEntityCreate)).Error())
+		}
+
+		// response
+		c.Status(fiber.StatusCreated)
+		return toJson(c, res)
+	}
+}
+
+// HandleDeletePermissionById creates handler func that is responsible for removing permission from the catalog.
+func (h Handler) HandleDeletePermissionById() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse query params
+		id, err := parseId(c)
+		if err != nil {
+			return err
+		}
+
+		// call core service
+		if err := h.service.DeletePermissionById(c.Context(), id); err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrDeleteById)).Error())
+		}
+
+		// response
+		c.Status(fiber.StatusNoContent)
+		return nil
+	}
+}
+
+// parseId parses id path parameter.
+func parseId(c *fiber.Ctx) (uuid.UUID, error) {
+	sId := c.Params("id", "0")
+	if sId == "0" {
+		return uuid.Nil, fiber.NewError(fiber.StatusBadRequest,
+			apiErr.New(apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+	}
+
+	id, err := uuid.Parse(sId)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusBadRequest,
+			apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+	}
+	return id, nil
+}
+
+func toJson(c *fiber.Ctx, t interface{}) error {
+	if err := c.JSON(t); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
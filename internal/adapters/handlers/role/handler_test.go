@@ -0,0 +1,241 @@
+package role
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fmiskovic/go-starter/internal/adapters/repos"
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/fmiskovic/go-starter/internal/core/services"
+	"github.com/fmiskovic/go-starter/internal/utils/testx"
+
+	"github.com/matryer/is"
+)
+
+func TestHandleGetAll(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	service := services.NewRoleService(repos.NewRoleRepo(ts.TestDb.BunDb))
+	handler := NewHandler(service)
+	ts.App.Get("/role", handler.HandleGetAll())
+	ts.App.Get("/permission", handler.HandleGetPermissions())
+
+	t.Run("given get all roles request should return 200", func(t *testing.T) {
+		res, err := ts.App.Test(httptest.NewRequest("GET", "/role", nil), 20000)
+		assert.NoErr(err)
+		assert.Equal(res.StatusCode, 200)
+
+		var roles []security.RoleDto
+		decode(t, res, &roles)
+		assert.Equal(len(roles), 3)
+		assert.Equal(roles[0].Name, security.ROLE_ADMIN)
+		assert.Equal(len(roles[0].Permissions), 8)
+	})
+
+	t.Run("given get all permissions request should return 200", func(t *testing.T) {
+		res, err := ts.App.Test(httptest.NewRequest("GET", "/permission", nil), 20000)
+		assert.NoErr(err)
+		assert.Equal(res.StatusCode, 200)
+
+		var perms []security.PermissionDto
+		decode(t, res, &perms)
+		assert.Equal(len(perms), 8)
+	})
+}
+
+func TestHandleCreate(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	service := services.NewRoleService(repos.NewRoleRepo(ts.TestDb.BunDb))
+	handler := NewHandler(service)
+	ts.App.Post("/role", handler.HandleCreate())
+
+	tests := []struct {
+		name     string
+		reqBody  []byte
+		wantCode int
+		verify   func(t *testing.T, res *http.Response)
+	}{
+		{
+			name:     "given valid create request should return 201",
+			reqBody:  []byte("{\"name\":\"ROLE_AUDITOR\",\"description\":\"Read only\",\"permissions\":[\"user:read\",\"role:read\"]}"),
+			wantCode: 201,
+			verify: func(t *testing.T, res *http.Response) {
+				r := &security.RoleDto{}
+				decode(t, res, r)
+				assert.Equal(r.Name, "ROLE_AUDITOR")
+				assert.Equal(r.Permissions, []string{"role:read", "user:read"})
+			},
+		},
+		{
+			name:     "given duplicate role name should return 422",
+			reqBody:  []byte("{\"name\":\"ROLE_ADMIN\"}"),
+			wantCode: 422,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given unknown permission should return 422",
+			reqBody:  []byte("{\"name\":\"ROLE_SUPPORT\",\"permissions\":[\"user:raed\"]}"),
+			wantCode: 422,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given missing name should return 400",
+			reqBody:  []byte("{\"description\":\"no name\"}"),
+			wantCode: 400,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/role", bytes.NewReader(tt.reqBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			res, err := ts.App.Test(req, 20000)
+			assert.NoErr(err)
+			assert.Equal(res.StatusCode, tt.wantCode)
+			tt.verify(t, res)
+		})
+	}
+}
+
+func TestHandleSetPermissions(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	service := services.NewRoleService(repos.NewRoleRepo(ts.TestDb.BunDb))
+	handler := NewHandler(service)
+	ts.App.Put("/role/:id/permissions", handler.HandleSetPermissions())
+
+	tests := []struct {
+		name     string
+		route    string
+		reqBody  []byte
+		wantCode int
+	}{
+		{
+			name:     "given known permissions should return 204",
+			route:    "/role/200cea28-b2b0-4051-9eb6-9a99e451af03/permissions",
+			reqBody:  []byte("{\"permissions\":[\"user:read\"]}"),
+			wantCode: 204,
+		},
+		{
+			name:     "given unknown permission should return 422",
+			route:    "/role/200cea28-b2b0-4051-9eb6-9a99e451af03/permissions",
+			reqBody:  []byte("{\"permissions\":[\"user:raed\"]}"),
+			wantCode: 422,
+		},
+		{
+			name:     "given invalid id should return 400",
+			route:    "/role/invalid/permissions",
+			reqBody:  []byte("{\"permissions\":[\"user:read\"]}"),
+			wantCode: 400,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", tt.route, bytes.NewReader(tt.reqBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			res, err := ts.App.Test(req, 20000)
+			assert.NoErr(err)
+			assert.Equal(res.StatusCode, tt.wantCode)
+		})
+	}
+}
+
+func TestHandleDeleteById(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	service := services.NewRoleService(repos.NewRoleRepo(ts.TestDb.BunDb))
+	handler := NewHandler(service)
+	ts.App.Delete("/role/:id", handler.HandleDeleteById())
+	ts.App.Delete("/permission/:id", handler.HandleDeletePermissionById())
+
+	tests := []struct {
+		name     string
+		route    string
+		wantCode int
+	}{
+		{
+			name:     "given custom role should return 204",
+			route:    "/role/200cea28-b2b0-4051-9eb6-9a99e451af03",
+			wantCode: 204,
+		},
+		{
+			name:     "given built-in role should return 422",
+			route:    "/role/200cea28-b2b0-4051-9eb6-9a99e451af01",
+			wantCode: 422,
+		},
+		{
+			name:     "given built-in permission should return 422",
+			route:    "/permission/300cea28-b2b0-4051-9eb6-9a99e451af01",
+			wantCode: 422,
+		},
+		{
+			name:     "given invalid id should return 400",
+			route:    "/role/invalid",
+			wantCode: 400,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ts.App.Test(httptest.NewRequest("DELETE", tt.route, nil), 20000)
+			assert.NoErr(err)
+			assert.Equal(res.StatusCode, tt.wantCode)
+		})
+	}
+}
+
+func decode(t *testing.T, res *http.Response, v any) {
+	t.Helper()
+	defer func(body io.ReadCloser) {
+		if err := body.Close(); err != nil {
+			fmt.Println("error occurred on body close:", err.Error())
+		}
+	}(res.Body)
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// SubjectId returns ID of the user the token stored in context by auth middleware is issued to.
+func SubjectId(c *fiber.Ctx) (uuid.UUID, error) {
+	claims, err := TokenClaims(c)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, apiErr.New(apiErr.WithAppErr(err)).Error())
+	}
+
+	sub, _ := claims.GetSubject()
+	id, err := uuid.Parse(sub)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized,
+			apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidToken)).Error())
+	}
+	return id, nil
+}
+
+// SessionSubjectId returns ID of the user signed in with access token.
+// Requests authenticated with API key are rejected, so leaked key can not be used to manage keys, sessions,
+// two-factor authentication or profile of the user.
+func SessionSubjectId(c *fiber.Ctx) (uuid.UUID, error) {
+	if claims, err := TokenClaims(c); err == nil && claims["api_key"] != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusForbidden, apiErr.New(apiErr.WithAppErr(apiErr.ErrApiKeyNotAllowed)).Error())
+	}
+	return SubjectId(c)
+}
+
+// TokenClaims returns claims of the token stored in context by auth middleware.
+func TokenClaims(c *fiber.Ctx) (jwt.MapClaims, error) {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok || token == nil {
+		return nil, apiErr.ErrInvalidToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, apiErr.ErrInvalidToken
+	}
+	return claims, nil
+}
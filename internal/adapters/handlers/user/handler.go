@@ -1,20 +1,34 @@
 package user
 
 import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
 
+	"github.com/fmiskovic/go-starter/internal/adapters/handlers"
 	"github.com/fmiskovic/go-starter/internal/core/domain"
 	"github.com/fmiskovic/go-starter/internal/core/domain/user"
 	"github.com/fmiskovic/go-starter/internal/core/ports"
 	"github.com/fmiskovic/go-starter/internal/core/validators"
+	"github.com/fmiskovic/go-starter/internal/utils/mergepatch"
 	"github.com/google/uuid"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// mergePatchMIME is media type of JSON merge patch (RFC 7396).
+const mergePatchMIME = "application/merge-patch+json"
+
+// maxPageSize is the largest page of users that can be requested.
+const maxPageSize = 100
+
 type Handler struct {
 	service   ports.UserService[uuid.UUID]
 	validator validators.Validator
@@ -46,6 +60,13 @@ func (uh Handler) HandleCreate() fiber.Handler {
 		// call core service
 		res, err := uh.service.Create(c.Context(), req)
 		if err != nil {
+			if busy := handlers.ServerBusy(c, err); busy != nil {
+				return busy
+			}
+			var policy apiErr.PasswordPolicyError
+			if errors.As(err, &policy) {
+				return fiber.NewError(fiber.StatusBadRequest, policy.Error())
+			}
 			return fiber.NewError(fiber.StatusInternalServerError,
 				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrEntityCreate)).Error())
 		}
@@ -57,7 +78,9 @@ func (uh Handler) HandleCreate() fiber.Handler {
 }
 
 // HandleUpdate creates handler func that is responsible for updating existing user entity.
-// Response is UserDto json.
+// Version the change is based on is read from If-Match header or the request body,
+// 412 is returned if the user was changed in the meantime.
+// Response is UserDto json with ETag of the new version.
 func (uh Handler) HandleUpdate() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// parse request body
@@ -67,6 +90,16 @@ func (uh Handler) HandleUpdate() fiber.Handler {
 				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrParseReqBody)).Error())
 		}
 
+		// parse If-Match header
+		if ifMatch := c.Get(fiber.HeaderIfMatch); ifMatch != "" {
+			version, err := parseETag(ifMatch)
+			if err != nil {
+				return fiber.NewError(fiber.StatusPreconditionFailed,
+					apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrVersionMismatch)).Error())
+			}
+			req.Version = version
+		}
+
 		// validate request
 		if errs := uh.validator.Validate(req); len(errs) > 0 {
 			return fiber.NewError(fiber.StatusBadRequest, strings.Join(errs, " and "))
@@ -75,17 +108,75 @@ func (uh Handler) HandleUpdate() fiber.Handler {
 		// call core service
 		res, err := uh.service.Update(c.Context(), req)
 		if err != nil {
+			if errors.Is(err, apiErr.ErrVersionRequired) {
+				return fiber.NewError(fiber.StatusPreconditionRequired,
+					apiErr.New(apiErr.WithAppErr(apiErr.ErrVersionRequired)).Error())
+			}
+			if errors.Is(err, apiErr.ErrVersionMismatch) {
+				return fiber.NewError(fiber.StatusPreconditionFailed,
+					apiErr.New(apiErr.WithAppErr(apiErr.ErrVersionMismatch)).Error())
+			}
 			return fiber.NewError(fiber.StatusUnprocessableEntity,
 				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrEntityUpdate)).Error())
 		}
 
 		// response
+		c.Set(fiber.HeaderETag, etag(res.Version))
+		return toJson(c, res)
+	}
+}
+
+// HandlePatch creates handler func that is responsible for partial update of existing user entity.
+// Request body is JSON merge patch of the user profile, members set to null are cleared.
+// Patched profile is validated as a whole, If-Match header optionally pins the version the patch applies to.
+// Response is UserDto json with ETag of the new version.
+func (uh Handler) HandlePatch() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// parse path params
+		sId := c.Params("id", "0")
+		if sId == "0" {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+		}
+
+		id, err := uuid.Parse(sId)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+		}
+
+		// get current profile
+		current, err := uh.service.GetById(c.Context(), id)
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrGetById)).Error())
+		}
+
+		// apply patch
+		req, err := uh.applyPatch(c, current)
+		if err != nil {
+			return err
+		}
+
+		// call core service
+		res, err := uh.service.Patch(c.Context(), req)
+		if err != nil {
+			if errors.Is(err, apiErr.ErrVersionMismatch) {
+				return fiber.NewError(fiber.StatusPreconditionFailed,
+					apiErr.New(apiErr.WithAppErr(apiErr.ErrVersionMismatch)).Error())
+			}
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrEntityUpdate)).Error())
+		}
+
+		// response
+		c.Set(fiber.HeaderETag, etag(res.Version))
 		return toJson(c, res)
 	}
 }
 
 // HandleGetById creates handler func that is responsible for getting existing user entity by its ID.
-// Response is UserDto json.
+// Response is UserDto json with ETag of its version, the ETag is sent back in If-Match header on update.
 func (uh Handler) HandleGetById() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// parse query params
@@ -109,6 +200,7 @@ func (uh Handler) HandleGetById() fiber.Handler {
 		}
 
 		// response
+		c.Set(fiber.HeaderETag, etag(res.Version))
 		return toJson(c, res)
 	}
 }
@@ -142,34 +234,79 @@ func (uh Handler) HandleDeleteById() fiber.Handler {
 	}
 }
 
-// HandleGetPage returns page of users
 // HandleGetPage creates handler func that is responsible for getting page of user entities.
+// Users are filtered by query params: q, email, fullname, location, gender, enabled, role,
+// createdFrom, createdTo, updatedFrom and updatedTo, includeDeleted=true lists soft deleted users too.
+// Page is read at offset, or by cursor if paging=keyset or cursor is given, count=false skips counting total elements.
 // Response is json representing Page of UserDtos.
 func (uh Handler) HandleGetPage() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// parse query params
 		size, err := strconv.Atoi(c.Query("size", "10"))
+		if err == nil && (size < 1 || size > maxPageSize) {
+			err = fmt.Errorf("size must be from 1 to %d", maxPageSize)
+		}
 		if err != nil {
 			return fiber.NewError(fiber.StatusBadRequest,
 				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidPageSize)).Error())
 		}
 
 		offset, err := strconv.Atoi(c.Query("offset", "0"))
+		if err == nil && offset < 0 {
+			err = errors.New("offset can not be negative")
+		}
 		if err != nil {
 			return fiber.NewError(fiber.StatusBadRequest,
 				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidPageOffset)).Error())
 		}
 
-		sort := resolveSort(c)
+		count, err := strconv.ParseBool(c.Query("count", "true"))
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidPaging)).Error())
+		}
+
+		paging := c.Query("paging", "offset")
+		if paging != "offset" && paging != "keyset" {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(errors.New("paging must be offset or keyset")), apiErr.WithAppErr(apiErr.ErrInvalidPaging)).Error())
+		}
 
 		pageReq := domain.Pageable{
-			Size:   size,
-			Offset: offset,
-			Sort:   sort,
+			Size:      size,
+			Offset:    offset,
+			Keyset:    paging == "keyset" || c.Query("cursor") != "",
+			SkipCount: !count,
+		}
+
+		if pageReq.Keyset {
+			if pageReq.Sort, err = resolveKeysetSort(c.Query("sort", "createdAt DESC")); err != nil {
+				return fiber.NewError(fiber.StatusBadRequest,
+					apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidSort)).Error())
+			}
+			if cursor := c.Query("cursor"); cursor != "" {
+				if pageReq.Cursor, err = domain.DecodeCursor(cursor); err != nil {
+					return fiber.NewError(fiber.StatusBadRequest,
+						apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidCursor)).Error())
+				}
+			}
+		} else if pageReq.Sort, err = user.SortFields.ParseSort(c.Query("sort")); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidSort)).Error())
+		}
+
+		filter, err := resolveFilter(c)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidFilter)).Error())
 		}
 
 		// call core service
-		page, err := uh.service.GetPage(c.Context(), pageReq)
+		page, err := uh.service.GetPage(c.Context(), pageReq, filter)
+		if errors.Is(err, apiErr.ErrInvalidCursor) {
+			// cursor was made for another sort
+			return fiber.NewError(fiber.StatusBadRequest, apiErr.ErrInvalidCursor.Error())
+		}
 		if err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError,
 				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrGetPage)).Error())
@@ -249,6 +386,162 @@ func (uh Handler) HandleEnableDisable() fiber.Handler {
 	}
 }
 
+// HandleUnlock lifts sign in lockout of the user caused by too many failed attempts.
+func (uh Handler) HandleUnlock() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sId := c.Params("id", "0")
+		if sId == "0" {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+		}
+
+		id, err := uuid.Parse(sId)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+		}
+
+		if err := uh.service.Unlock(c.Context(), id); err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrEntityUpdate)).Error())
+		}
+
+		c.Status(fiber.StatusNoContent)
+		return nil
+	}
+}
+
+// HandleRestore brings back soft deleted user that is not purged yet.
+func (uh Handler) HandleRestore() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sId := c.Params("id", "0")
+		if sId == "0" {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+		}
+
+		id, err := uuid.Parse(sId)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+		}
+
+		if err := uh.service.Restore(c.Context(), id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fiber.NewError(fiber.StatusNotFound,
+					apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrEntityRestore)).Error())
+			}
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrEntityRestore)).Error())
+		}
+
+		c.Status(fiber.StatusNoContent)
+		return nil
+	}
+}
+
+// HandleSignOutAll revokes all access and refresh tokens of the user, logging it out everywhere.
+func (uh Handler) HandleSignOutAll() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sId := c.Params("id", "0")
+		if sId == "0" {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+		}
+
+		id, err := uuid.Parse(sId)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+		}
+
+		if err := uh.service.SignOutAll(c.Context(), id); err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrSignOut)).Error())
+		}
+
+		c.Status(fiber.StatusNoContent)
+		return nil
+	}
+}
+
+// HandleResetMfa turns off two-factor authentication of the user, e.g. when user lost the authenticator.
+func (uh Handler) HandleResetMfa() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sId := c.Params("id", "0")
+		if sId == "0" {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+		}
+
+		id, err := uuid.Parse(sId)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+		}
+
+		if err := uh.service.ResetMfa(c.Context(), id); err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrMfaUpdate)).Error())
+		}
+
+		c.Status(fiber.StatusNoContent)
+		return nil
+	}
+}
+
+// HandleSetPassword forces new password of the user, e.g. when the account is compromised.
+// All sessions of the user are revoked, the old password is not required.
+func (uh Handler) HandleSetPassword() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sId := c.Params("id", "0")
+		if sId == "0" {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+		}
+
+		id, err := uuid.Parse(sId)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidId)).Error())
+		}
+
+		// parse request body
+		var req = new(user.SetPasswordRequest)
+		if err := c.BodyParser(req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrParseReqBody)).Error())
+		}
+		req.ID = id.String()
+
+		// validate request
+		if errs := uh.validator.Validate(req); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, strings.Join(errs, " and "))
+		}
+
+		// call core service
+		if err := uh.service.SetPassword(c.Context(), req); err != nil {
+			if busy := handlers.ServerBusy(c, err); busy != nil {
+				return busy
+			}
+			var policy apiErr.PasswordPolicyError
+			if errors.As(err, &policy) {
+				return fiber.NewError(fiber.StatusBadRequest, policy.Error())
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				return fiber.NewError(fiber.StatusNotFound,
+					apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrChangePassword)).Error())
+			}
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrChangePassword)).Error())
+		}
+
+		// response
+		c.Status(fiber.StatusNoContent)
+		return nil
+	}
+}
+
 func toJson(c *fiber.Ctx, t interface{}) error {
 	if err := c.JSON(t); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
@@ -256,26 +549,147 @@ func toJson(c *fiber.Ctx, t interface{}) error {
 	return nil
 }
 
-// resolveSort parses the sort parameter into a sort object.
-func resolveSort(c *fiber.Ctx) domain.Sort {
-	// extract sort parameters from query parameters
-	sortParam := c.Query("sort", "")
-	if sortParam == "" {
-		return domain.NewSort()
+// resolveKeysetSort parses sort of keyset page, only not null fields and ASC or DESC directions are supported.
+func resolveKeysetSort(s string) (domain.Sort, error) {
+	sort, err := user.KeysetSortFields.ParseSort(s)
+	if err != nil {
+		return sort, err
+	}
+	for _, o := range sort.Orders {
+		if o.Direction != domain.ASC && o.Direction != domain.DESC {
+			return sort, fmt.Errorf("keyset paging does not support %s direction", o.Direction)
+		}
+	}
+	return sort, nil
+}
+
+// resolveFilter extracts user filter from query params.
+// Dates are either RFC 3339 timestamps or yyyy-mm-dd dates, date given as the end of range is included whole.
+func resolveFilter(c *fiber.Ctx) (user.Filter, error) {
+	filter := user.Filter{
+		Query:    strings.TrimSpace(c.Query("q")),
+		Email:    strings.TrimSpace(c.Query("email")),
+		FullName: strings.TrimSpace(c.Query("fullname")),
+		Location: strings.TrimSpace(c.Query("location")),
+		Role:     strings.TrimSpace(c.Query("role")),
+	}
+
+	if g := c.Query("gender"); g != "" {
+		if g != "Male" && g != "Female" && g != "Other" {
+			return filter, errors.New("gender must be Male, Female or Other")
+		}
+		gender := user.GenderDto(g).Numberfy()
+		filter.Gender = &gender
 	}
-	// split the sort parameter into individual sort orderParams
-	orderParams := strings.Split(sortParam, ",")
 
-	var orders []*domain.Order
-	// remove any leading or trailing spaces from each sort order
-	for i := range orderParams {
-		o := strings.Split(strings.TrimSpace(orderParams[i]), " ")
-		order := domain.NewOrder(domain.WithProperty(o[0]), domain.WithDirection(domain.ASC))
-		if len(o) == 2 {
-			order.Direction = domain.Direction(o[1])
+	if e := c.Query("enabled"); e != "" {
+		enabled, err := strconv.ParseBool(e)
+		if err != nil {
+			return filter, errors.New("enabled must be true or false")
 		}
-		orders = append(orders, order)
+		filter.Enabled = &enabled
 	}
 
-	return domain.NewSort(orders...)
+	if d := c.Query("includeDeleted"); d != "" {
+		includeDeleted, err := strconv.ParseBool(d)
+		if err != nil {
+			return filter, errors.New("includeDeleted must be true or false")
+		}
+		filter.IncludeDeleted = includeDeleted
+	}
+
+	var err error
+	if filter.CreatedFrom, err = parseFilterTime(c.Query("createdFrom"), false); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = parseFilterTime(c.Query("createdTo"), true); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedFrom, err = parseFilterTime(c.Query("updatedFrom"), false); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedTo, err = parseFilterTime(c.Query("updatedTo"), true); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// applyPatch applies merge patch from the request body to the current profile and validates the result.
+// If-Match header, if sent, has to match the current version.
+func (uh Handler) applyPatch(c *fiber.Ctx, current *user.Dto) (*user.PatchRequest, error) {
+	ct, _, _ := strings.Cut(strings.ToLower(c.Get(fiber.HeaderContentType)), ";")
+	if ct = strings.TrimSpace(ct); ct != mergePatchMIME && ct != fiber.MIMEApplicationJSON {
+		return nil, fiber.NewError(fiber.StatusUnsupportedMediaType,
+			apiErr.New(apiErr.WithAppErr(apiErr.ErrUnsupportedPatch)).Error())
+	}
+
+	if ifMatch := c.Get(fiber.HeaderIfMatch); ifMatch != "" {
+		version, err := parseETag(ifMatch)
+		if err != nil || version != current.Version {
+			return nil, fiber.NewError(fiber.StatusPreconditionFailed,
+				apiErr.New(apiErr.WithAppErr(apiErr.ErrVersionMismatch)).Error())
+		}
+	}
+
+	doc, err := json.Marshal(user.ConvertToPatchRequest(current))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError,
+			apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrEntityUpdate)).Error())
+	}
+	patched, err := mergepatch.Apply(doc, c.Body())
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest,
+			apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidPatch)).Error())
+	}
+
+	// fields that are not part of the profile are rejected
+	req := &user.PatchRequest{ID: current.ID, Version: current.Version}
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(req); err != nil {
+		return nil, fiber.NewError(fiber.StatusUnprocessableEntity,
+			apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrInvalidPatch)).Error())
+	}
+	if errs := uh.validator.Validate(req); len(errs) > 0 {
+		return nil, fiber.NewError(fiber.StatusUnprocessableEntity, strings.Join(errs, " and "))
+	}
+	return req, nil
+}
+
+// etag formats entity version as strong entity tag.
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// parseETag parses entity version from the entity tag, weak tags are accepted too.
+func parseETag(tag string) (int64, error) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	v, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, fmt.Errorf("malformed entity tag %s", tag)
+	}
+	version, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("entity tag %s is not a version", tag)
+	}
+	return version, nil
+}
+
+// parseFilterTime parses RFC 3339 timestamp or date, end of range date is moved to the next day to include it whole.
+func parseFilterTime(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s is neither date nor RFC 3339 timestamp", s)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
 }
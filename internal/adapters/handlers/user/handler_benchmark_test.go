@@ -3,6 +3,7 @@ package user
 import (
 	"bytes"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/fmiskovic/go-starter/internal/adapters/repos"
@@ -33,6 +34,8 @@ func BenchmarkHandleUpdate(b *testing.B) {
 		body := []byte("{\"id\":\"220cea28-b2b0-4051-9eb6-9a99e451af01\",\"email\":\"test1@fake.com\", \"location\":\"Vienna\"}")
 		req := httptest.NewRequest("PUT", "/user", bytes.NewReader(body))
 		req.Header.Add("Content-Type", "application/json")
+		// every update increments version of the user
+		req.Header.Add("If-Match", strconv.Quote(strconv.Itoa(n+1)))
 
 		res, err := ts.App.Test(req, 20000)
 
@@ -8,14 +8,24 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/fmiskovic/go-starter/internal/adapters/mailer"
+	"github.com/fmiskovic/go-starter/internal/adapters/memory"
 	"github.com/fmiskovic/go-starter/internal/adapters/repos"
 	"github.com/fmiskovic/go-starter/internal/core/configs"
 	"github.com/fmiskovic/go-starter/internal/core/domain"
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
 	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/core/ports"
 	"github.com/fmiskovic/go-starter/internal/core/services"
+	"github.com/fmiskovic/go-starter/internal/utils/password"
 	"github.com/fmiskovic/go-starter/internal/utils/testx"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 
 	"github.com/matryer/is"
@@ -112,6 +122,7 @@ func TestHandleUpdate(t *testing.T) {
 	tests := []struct {
 		name     string
 		route    string
+		ifMatch  string
 		reqBody  []byte
 		verify   func(t *testing.T, res *http.Response)
 		wantCode int
@@ -119,6 +130,7 @@ func TestHandleUpdate(t *testing.T) {
 		{
 			name:     "given valid update request should return 200",
 			route:    "/user",
+			ifMatch:  `"1"`,
 			reqBody:  []byte("{\"id\":\"220cea28-b2b0-4051-9eb6-9a99e451af01\",\"email\":\"test1@fake.com\", \"location\":\"Vienna\"}"),
 			wantCode: 200,
 			verify: func(t *testing.T, res *http.Response) {
@@ -133,8 +145,40 @@ func TestHandleUpdate(t *testing.T) {
 				err := json.NewDecoder(resBody).Decode(updateRes)
 				assert.NoErr(err)
 				assert.Equal(updateRes.Location, "Vienna")
+				assert.Equal(updateRes.Version, int64(2))
+				assert.Equal(res.Header.Get("ETag"), `"2"`)
 			},
 		},
+		{
+			name:     "given stale version should return 412",
+			route:    "/user",
+			ifMatch:  `"1"`,
+			reqBody:  []byte("{\"id\":\"220cea28-b2b0-4051-9eb6-9a99e451af01\",\"email\":\"test1@fake.com\", \"location\":\"Paris\"}"),
+			wantCode: 412,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given version in request body should return 200",
+			route:    "/user",
+			reqBody:  []byte("{\"id\":\"220cea28-b2b0-4051-9eb6-9a99e451af01\",\"version\":2,\"email\":\"test1@fake.com\", \"location\":\"Paris\"}"),
+			wantCode: 200,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given no version should return 428",
+			route:    "/user",
+			reqBody:  []byte("{\"id\":\"220cea28-b2b0-4051-9eb6-9a99e451af02\",\"email\":\"test2@fake.com\"}"),
+			wantCode: 428,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given malformed If-Match should return 412",
+			route:    "/user",
+			ifMatch:  "latest",
+			reqBody:  []byte("{\"id\":\"220cea28-b2b0-4051-9eb6-9a99e451af02\",\"email\":\"test2@fake.com\"}"),
+			wantCode: 412,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
 		{
 			name:     "given empty update request should return 400",
 			route:    "/user",
@@ -150,10 +194,11 @@ func TestHandleUpdate(t *testing.T) {
 			verify:   func(t *testing.T, res *http.Response) {},
 		},
 		{
-			name:     "given invalid id should return 200",
+			name:     "given non-existing id should return 422",
 			route:    "/user",
+			ifMatch:  `"1"`,
 			reqBody:  []byte("{\"id\":\"333cea28-b2b0-4051-9eb6-9a99e451af01\",\"email\":\"test1@fake.com\"}"),
-			wantCode: 200,
+			wantCode: 422,
 			verify:   func(t *testing.T, res *http.Response) {},
 		},
 	}
@@ -162,6 +207,127 @@ func TestHandleUpdate(t *testing.T) {
 			// given
 			req := httptest.NewRequest("PUT", tt.route, bytes.NewReader(tt.reqBody))
 			req.Header.Add("Content-Type", "application/json")
+			if tt.ifMatch != "" {
+				req.Header.Add("If-Match", tt.ifMatch)
+			}
+
+			// when
+			res, err := ts.App.Test(req, 5000)
+			// then
+			assert.NoErr(err)
+			assert.Equal(res.StatusCode, tt.wantCode)
+			tt.verify(t, res)
+		})
+	}
+}
+
+func TestHandlePatch(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, configs.NewAuthConfig())
+	handler := NewHandler(service)
+	ts.App.Patch("/user/:id", handler.HandlePatch())
+
+	tests := []struct {
+		name        string
+		id          string
+		contentType string
+		ifMatch     string
+		reqBody     string
+		verify      func(t *testing.T, res *http.Response)
+		wantCode    int
+	}{
+		{
+			name:        "given null members should clear them",
+			id:          "220cea28-b2b0-4051-9eb6-9a99e451af02",
+			contentType: "application/merge-patch+json",
+			ifMatch:     `"1"`,
+			reqBody:     `{"location":null,"dateOfBirth":null,"fullname":"John Doe"}`,
+			wantCode:    200,
+			verify: func(t *testing.T, res *http.Response) {
+				updateRes := &user.UpdateResponse{}
+				err := json.NewDecoder(res.Body).Decode(updateRes)
+				assert.NoErr(err)
+				assert.Equal(updateRes.FullName, "John Doe")
+				assert.Equal(updateRes.Email, "john@doe.com")
+				assert.Equal(updateRes.Location, "")
+				assert.True(updateRes.DateOfBirth == nil)
+				assert.Equal(res.Header.Get("ETag"), `"2"`)
+
+				u, err := repo.GetById(context.Background(), uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af02"))
+				assert.NoErr(err)
+				assert.Equal(u.Location, "")
+				assert.True(u.DateOfBirth.IsZero())
+			},
+		},
+		{
+			name:        "given stale If-Match should return 412",
+			id:          "220cea28-b2b0-4051-9eb6-9a99e451af02",
+			contentType: "application/merge-patch+json",
+			ifMatch:     `"1"`,
+			reqBody:     `{"location":"Boston"}`,
+			wantCode:    412,
+			verify:      func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:        "given patch clearing required email should return 422",
+			id:          "220cea28-b2b0-4051-9eb6-9a99e451af02",
+			contentType: "application/merge-patch+json",
+			reqBody:     `{"email":null}`,
+			wantCode:    422,
+			verify:      func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:        "given patch of field that is not part of profile should return 422",
+			id:          "220cea28-b2b0-4051-9eb6-9a99e451af02",
+			contentType: "application/merge-patch+json",
+			reqBody:     `{"enabled":false}`,
+			wantCode:    422,
+			verify:      func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:        "given malformed patch should return 400",
+			id:          "220cea28-b2b0-4051-9eb6-9a99e451af02",
+			contentType: "application/merge-patch+json",
+			reqBody:     `{"location":`,
+			wantCode:    400,
+			verify:      func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:        "given json patch should return 415",
+			id:          "220cea28-b2b0-4051-9eb6-9a99e451af02",
+			contentType: "application/json-patch+json",
+			reqBody:     `[{"op":"remove","path":"/location"}]`,
+			wantCode:    415,
+			verify:      func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:        "given non-existing id should return 404",
+			id:          "333cea28-b2b0-4051-9eb6-9a99e451af01",
+			contentType: "application/merge-patch+json",
+			reqBody:     `{"location":"Boston"}`,
+			wantCode:    404,
+			verify:      func(t *testing.T, res *http.Response) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			req := httptest.NewRequest("PATCH", "/user/"+tt.id, strings.NewReader(tt.reqBody))
+			req.Header.Add("Content-Type", tt.contentType)
+			if tt.ifMatch != "" {
+				req.Header.Add("If-Match", tt.ifMatch)
+			}
 
 			// when
 			res, err := ts.App.Test(req, 5000)
@@ -281,6 +447,7 @@ func TestHandleGetById(t *testing.T) {
 				assert.NoErr(err)
 				assert.Equal(userDto.Email, "john@smith.com")
 				assert.Equal(userDto.Gender.Numberfy(), user.MALE)
+				assert.Equal(res.Header.Get("ETag"), `"1"`)
 			},
 		},
 		{
@@ -374,6 +541,94 @@ func TestHandleGetPage(t *testing.T) {
 				assert.Equal(pageDto.Elements[1].Email, "john@doe.com")
 			},
 		},
+		{
+			name:     "given filter should return 200 and matching users",
+			route:    "/user?q=new%20york&createdTo=" + time.Now().Format(time.DateOnly),
+			wantCode: 200,
+			verify: func(t *testing.T, res *http.Response) {
+				resBody := res.Body
+				defer func(body io.ReadCloser) {
+					if err := body.Close(); err != nil {
+						fmt.Println("error occurred on body close:", err.Error())
+					}
+				}(resBody)
+
+				var pageDto domain.Page[user.Dto]
+				err := json.NewDecoder(resBody).Decode(&pageDto)
+				assert.NoErr(err)
+				assert.Equal(pageDto.TotalElements, 1)
+				assert.Equal(pageDto.Elements[0].Email, "john@doe.com")
+			},
+		},
+		{
+			name:     "given keyset paging should return 200 and next cursor",
+			route:    "/user?paging=keyset&size=2&sort=email%20ASC&count=false",
+			wantCode: 200,
+			verify: func(t *testing.T, res *http.Response) {
+				resBody := res.Body
+				defer func(body io.ReadCloser) {
+					if err := body.Close(); err != nil {
+						fmt.Println("error occurred on body close:", err.Error())
+					}
+				}(resBody)
+
+				var pageDto domain.Page[user.Dto]
+				err := json.NewDecoder(resBody).Decode(&pageDto)
+				assert.NoErr(err)
+				assert.Equal(len(pageDto.Elements), 2)
+				assert.Equal(pageDto.TotalElements, -1)
+				assert.True(pageDto.NextCursor != "")
+				assert.Equal(pageDto.PrevCursor, "")
+			},
+		},
+		{
+			name:     "given keyset paging with nullable sort field should return 400",
+			route:    "/user?paging=keyset&sort=fullName",
+			wantCode: 400,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given malformed cursor should return 400",
+			route:    "/user?cursor=abc",
+			wantCode: 400,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given unknown sort field should return 400",
+			route:    "/user?sort=password_hash%20ASC",
+			wantCode: 400,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given negative size should return 400",
+			route:    "/user?paging=keyset&size=-1",
+			wantCode: 400,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given size above maximum should return 400",
+			route:    "/user?size=101",
+			wantCode: 400,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given negative offset should return 400",
+			route:    "/user?offset=-1",
+			wantCode: 400,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given invalid filter should return 400",
+			route:    "/user?createdFrom=yesterday",
+			wantCode: 400,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given invalid include deleted flag should return 400",
+			route:    "/user?includeDeleted=maybe",
+			wantCode: 400,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
 		{
 			name:     "given pageable with offset 5 should return 200 and no elements",
 			route:    "/user?offset=5&sort=email%20ASC",
@@ -536,3 +791,417 @@ func TestHandleEnableDisable(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleUnlock(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	attempts := memory.NewLoginAttemptStore()
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, configs.NewAuthConfig(), services.WithLoginAttemptStore(attempts))
+	handler := NewHandler(service)
+	ts.App.Post("/user/:id/unlock", handler.HandleUnlock())
+
+	ctx := context.Background()
+	_, err = attempts.Fail(ctx, "username:username1", time.Now().Add(-time.Hour))
+	assert.NoErr(err)
+	assert.NoErr(attempts.Lock(ctx, "username:username1", time.Now().Add(time.Hour)))
+
+	tests := []struct {
+		name     string
+		id       string
+		verify   func(t *testing.T)
+		wantCode int
+	}{
+		{
+			name: "given locked user should unlock it",
+			id:   "220cea28-b2b0-4051-9eb6-9a99e451af01",
+			verify: func(t *testing.T) {
+				a, err := attempts.Get(ctx, "username:username1")
+				assert.NoErr(err)
+				assert.True(!a.IsLocked(time.Now()))
+				assert.Equal(a.Failures, 0)
+			},
+			wantCode: 204,
+		},
+		{
+			name:     "given non-existing user should return 422",
+			id:       "333cea28-b2b0-4051-9eb6-9a99e451af02",
+			verify:   func(t *testing.T) {},
+			wantCode: 422,
+		},
+		{
+			name:     "given invalid id should return 400",
+			id:       "invalid",
+			verify:   func(t *testing.T) {},
+			wantCode: 400,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ts.App.Test(httptest.NewRequest("POST", fmt.Sprintf("/user/%s/unlock", tt.id), nil), 20000)
+			assert.NoErr(err)
+			assert.Equal(res.StatusCode, tt.wantCode)
+			tt.verify(t)
+		})
+	}
+}
+
+func TestHandleRestore(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, configs.NewAuthConfig())
+	handler := NewHandler(service)
+	ts.App.Post("/user/:id/restore", handler.HandleRestore())
+
+	ctx := context.Background()
+	assert.NoErr(repo.DeleteById(ctx, uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af02")))
+
+	tests := []struct {
+		name     string
+		id       string
+		verify   func(t *testing.T)
+		wantCode int
+	}{
+		{
+			name: "given deleted user should restore it",
+			id:   "220cea28-b2b0-4051-9eb6-9a99e451af02",
+			verify: func(t *testing.T) {
+				u, err := repo.GetById(ctx, uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af02"))
+				assert.NoErr(err)
+				assert.True(u.DeletedAt.IsZero())
+			},
+			wantCode: 204,
+		},
+		{
+			name:     "given user that is not deleted should return 404",
+			id:       "220cea28-b2b0-4051-9eb6-9a99e451af01",
+			verify:   func(t *testing.T) {},
+			wantCode: 404,
+		},
+		{
+			name:     "given invalid id should return 400",
+			id:       "invalid",
+			verify:   func(t *testing.T) {},
+			wantCode: 400,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ts.App.Test(httptest.NewRequest("POST", fmt.Sprintf("/user/%s/restore", tt.id), nil), 20000)
+			assert.NoErr(err)
+			assert.Equal(res.StatusCode, tt.wantCode)
+			tt.verify(t)
+		})
+	}
+}
+
+func TestHandleMe(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	service := services.NewUserService(repo, configs.NewAuthConfig(), services.WithMailer(mailer.NewLogMailer()))
+	handler := NewHandler(service)
+
+	// signed in user as Authenticated middleware would store it
+	me := ts.App.Group("/me", func(c *fiber.Ctx) error {
+		claims := jwt.MapClaims{"sub": "220cea28-b2b0-4051-9eb6-9a99e451af02"}
+		if c.Get("X-Api-Key") != "" {
+			claims["api_key"] = c.Get("X-Api-Key")
+		}
+		c.Locals("user", &jwt.Token{Claims: claims, Valid: true})
+		return c.Next()
+	})
+	me.Get("/", handler.HandleGetMe())
+	me.Patch("/", handler.HandlePatchMe())
+	me.Delete("/", handler.HandleDeleteMe())
+
+	ctx := context.Background()
+	id := uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af02")
+
+	tests := []struct {
+		name     string
+		method   string
+		reqBody  string
+		apiKey   string
+		verify   func(t *testing.T, res *http.Response)
+		wantCode int
+	}{
+		{
+			name:     "given signed in user should return own profile",
+			method:   "GET",
+			wantCode: 200,
+			verify: func(t *testing.T, res *http.Response) {
+				dto := &user.Dto{}
+				assert.NoErr(json.NewDecoder(res.Body).Decode(dto))
+				assert.Equal(dto.ID, id.String())
+				assert.Equal(dto.Email, "john@doe.com")
+				assert.Equal(res.Header.Get("ETag"), `"1"`)
+			},
+		},
+		{
+			name:     "given profile patch should update own profile",
+			method:   "PATCH",
+			reqBody:  `{"location":"Boston"}`,
+			wantCode: 200,
+			verify: func(t *testing.T, res *http.Response) {
+				u, err := repo.GetById(ctx, id)
+				assert.NoErr(err)
+				assert.Equal(u.Location, "Boston")
+				assert.Equal(res.Header.Get("ETag"), `"2"`)
+			},
+		},
+		{
+			name:     "given new email should keep current email until it is confirmed",
+			method:   "PATCH",
+			reqBody:  `{"email":"jonh@doe.com","fullname":"John Doe"}`,
+			wantCode: 202,
+			verify: func(t *testing.T, res *http.Response) {
+				u, err := repo.GetById(ctx, id)
+				assert.NoErr(err)
+				assert.Equal(u.Email, "john@doe.com")
+				assert.Equal(u.FullName, "John Doe")
+
+				count, err := ts.TestDb.BunDb.NewSelect().
+					Model((*security.EmailConfirmation)(nil)).
+					Where("user_id = ? AND email = ?", id, "jonh@doe.com").
+					Count(ctx)
+				assert.NoErr(err)
+				assert.Equal(count, 1)
+			},
+		},
+		{
+			name:     "given email of another user should return 409",
+			method:   "PATCH",
+			reqBody:  `{"email":"em@parker.com"}`,
+			wantCode: 409,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given invalid email should return 422",
+			method:   "PATCH",
+			reqBody:  `{"email":"john"}`,
+			wantCode: 422,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given api key should read own profile",
+			method:   "GET",
+			apiKey:   "key",
+			wantCode: 200,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given api key should not update profile",
+			method:   "PATCH",
+			reqBody:  `{"location":"Denver"}`,
+			apiKey:   "key",
+			wantCode: 403,
+			verify: func(t *testing.T, res *http.Response) {
+				u, err := repo.GetById(ctx, id)
+				assert.NoErr(err)
+				assert.Equal(u.Location, "Boston")
+			},
+		},
+		{
+			name:     "given api key should not change email",
+			method:   "PATCH",
+			reqBody:  `{"email":"jonh@doe.com"}`,
+			apiKey:   "key",
+			wantCode: 403,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given api key should not delete account",
+			method:   "DELETE",
+			apiKey:   "key",
+			wantCode: 403,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+		{
+			name:     "given signed in user should delete own account",
+			method:   "DELETE",
+			wantCode: 204,
+			verify: func(t *testing.T, res *http.Response) {
+				_, err := repo.GetById(ctx, id)
+				assert.True(err != nil)
+			},
+		},
+		{
+			name:     "given deleted account should return 404",
+			method:   "GET",
+			wantCode: 404,
+			verify:   func(t *testing.T, res *http.Response) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			req := httptest.NewRequest(tt.method, "/me", strings.NewReader(tt.reqBody))
+			req.Header.Add("Content-Type", "application/merge-patch+json")
+			if tt.apiKey != "" {
+				req.Header.Add("X-Api-Key", tt.apiKey)
+			}
+
+			// when
+			res, err := ts.App.Test(req, 5000)
+			// then
+			assert.NoErr(err)
+			assert.Equal(res.StatusCode, tt.wantCode)
+			tt.verify(t, res)
+		})
+	}
+}
+
+// staleUserService reports version mismatch on every patch, as if profile was updated by another request meanwhile.
+type staleUserService struct {
+	ports.UserService[uuid.UUID]
+	emails []string
+}
+
+func (s *staleUserService) GetById(ctx context.Context, id uuid.UUID) (*user.Dto, error) {
+	return &user.Dto{ID: id.String(), Email: "john@doe.com", Gender: "Male", Version: 1}, nil
+}
+
+func (s *staleUserService) Patch(ctx context.Context, req *user.PatchRequest) (*user.UpdateResponse, error) {
+	return nil, apiErr.ErrVersionMismatch
+}
+
+func (s *staleUserService) ChangeEmail(ctx context.Context, req user.ChangeEmailRequest) error {
+	s.emails = append(s.emails, req.Email)
+	return nil
+}
+
+func TestHandlePatchMe_VersionMismatch(t *testing.T) {
+	assert := is.New(t)
+
+	service := &staleUserService{}
+	handler := NewHandler(service)
+
+	app := fiber.New()
+	app.Patch("/me", func(c *fiber.Ctx) error {
+		c.Locals("user", &jwt.Token{Claims: jwt.MapClaims{"sub": uuid.NewString()}, Valid: true})
+		return c.Next()
+	}, handler.HandlePatchMe())
+
+	req := httptest.NewRequest("PATCH", "/me", strings.NewReader(`{"email":"jonh@doe.com","fullname":"John Doe"}`))
+	req.Header.Add("Content-Type", "application/merge-patch+json")
+	req.Header.Add("If-Match", `"1"`)
+
+	res, err := app.Test(req, 5000)
+	assert.NoErr(err)
+	assert.Equal(res.StatusCode, 412)
+	assert.Equal(len(service.emails), 0) // confirmation code is not sent
+}
+
+func TestHandleSetPassword(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	assert := is.New(t)
+
+	ts, err := testx.SetUpServer()
+	if err != nil {
+		t.Errorf("failed to run test server: %v", err)
+	}
+	defer ts.TestDb.Shutdown()
+
+	repo := repos.NewUserRepo(ts.TestDb.BunDb)
+	revocations := memory.NewRevocationStore()
+	authConfig := configs.NewAuthConfig()
+	service := services.NewUserService(repo, authConfig, services.WithRevocationStore(revocations))
+	handler := NewHandler(service)
+	ts.App.Put("/user/:id/password", handler.HandleSetPassword())
+
+	tests := []struct {
+		name     string
+		id       string
+		reqBody  string
+		verify   func(t *testing.T)
+		wantCode int
+	}{
+		{
+			name:    "given valid request should set password and revoke sessions",
+			id:      "220cea28-b2b0-4051-9eb6-9a99e451af01",
+			reqBody: `{"newPassword":"Password1234!"}`,
+			verify: func(t *testing.T) {
+				u, err := repo.GetById(context.Background(), uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01"))
+				assert.NoErr(err)
+				ok, err := password.NewHasher(authConfig.PasswordHashing).Verify("Password1234!", u.Credentials.Password)
+				assert.NoErr(err)
+				assert.True(ok)
+
+				revoked, err := revocations.IsRevoked(context.Background(), uuid.New(), u.ID, time.Now().Add(-2*time.Second).Truncate(time.Second))
+				assert.NoErr(err)
+				assert.True(revoked)
+			},
+			wantCode: 204,
+		},
+		{
+			name:     "given short password should return 400",
+			id:       "220cea28-b2b0-4051-9eb6-9a99e451af01",
+			reqBody:  `{"newPassword":"pass"}`,
+			verify:   func(t *testing.T) {},
+			wantCode: 400,
+		},
+		{
+			name:     "given password containing the username should return 400",
+			id:       "220cea28-b2b0-4051-9eb6-9a99e451af01",
+			reqBody:  `{"newPassword":"Username1-secret"}`,
+			verify:   func(t *testing.T) {},
+			wantCode: 400,
+		},
+		{
+			name:     "given non-existing id should return 404",
+			id:       "333cea28-b2b0-4051-9eb6-9a99e451af01",
+			reqBody:  `{"newPassword":"Password1234!"}`,
+			verify:   func(t *testing.T) {},
+			wantCode: 404,
+		},
+		{
+			name:     "given invalid id should return 400",
+			id:       "invalid",
+			reqBody:  `{"newPassword":"Password1234!"}`,
+			verify:   func(t *testing.T) {},
+			wantCode: 400,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", fmt.Sprintf("/user/%s/password", tt.id), strings.NewReader(tt.reqBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			res, err := ts.App.Test(req, 30000)
+			assert.NoErr(err)
+			assert.Equal(res.StatusCode, tt.wantCode)
+			tt.verify(t)
+		})
+	}
+}
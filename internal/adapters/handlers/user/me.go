@@ -0,0 +1,125 @@
+package user
+
+import (
+	"errors"
+
+	"github.com/fmiskovic/go-starter/internal/adapters/handlers"
+	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/gofiber/fiber/v2"
+)
+
+// HandleGetMe creates handler func that is responsible for getting profile of the signed in user.
+// It must be used after Middleware.Authenticated.
+// Response is UserDto json with ETag of its version.
+func (uh Handler) HandleGetMe() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := handlers.SubjectId(c)
+		if err != nil {
+			return err
+		}
+
+		// call core service
+		res, err := uh.service.GetById(c.Context(), id)
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrGetById)).Error())
+		}
+
+		// response
+		c.Set(fiber.HeaderETag, etag(res.Version))
+		return toJson(c, res)
+	}
+}
+
+// HandlePatchMe creates handler func that is responsible for partial update of the signed in user profile.
+// Request body is JSON merge patch of the profile, members set to null are cleared.
+// Changed email is not stored right away, confirmation code is sent to the new address and 202 is returned.
+// Requests authenticated with API key are rejected, API key scopes do not cover the owner's profile.
+// It must be used after Middleware.Authenticated.
+// Response is UserDto json with ETag of the new version.
+func (uh Handler) HandlePatchMe() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := handlers.SessionSubjectId(c)
+		if err != nil {
+			return err
+		}
+
+		// get current profile
+		current, err := uh.service.GetById(c.Context(), id)
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrGetById)).Error())
+		}
+
+		// apply patch
+		req, err := uh.applyPatch(c, current)
+		if err != nil {
+			return err
+		}
+
+		// email is changed once the new address is confirmed
+		var emailReq *user.ChangeEmailRequest
+		if req.Email != current.Email {
+			emailReq = &user.ChangeEmailRequest{ID: current.ID, Email: req.Email}
+			if errs := uh.validator.Validate(emailReq); len(errs) > 0 {
+				return fiber.NewError(fiber.StatusUnprocessableEntity,
+					apiErr.New(apiErr.WithSvcErr(errors.New(errs[0])), apiErr.WithAppErr(apiErr.ErrChangeEmail)).Error())
+			}
+			req.Email = current.Email
+		}
+
+		// call core service
+		res, err := uh.service.Patch(c.Context(), req)
+		if err != nil {
+			if errors.Is(err, apiErr.ErrVersionMismatch) {
+				return fiber.NewError(fiber.StatusPreconditionFailed,
+					apiErr.New(apiErr.WithAppErr(apiErr.ErrVersionMismatch)).Error())
+			}
+			return fiber.NewError(fiber.StatusUnprocessableEntity,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrEntityUpdate)).Error())
+		}
+		c.Set(fiber.HeaderETag, etag(res.Version))
+
+		// confirmation code is sent only once the patch is applied, so rejected patch does not send any email
+		status := fiber.StatusOK
+		if emailReq != nil {
+			if err := uh.service.ChangeEmail(c.Context(), *emailReq); err != nil {
+				if errors.Is(err, apiErr.ErrEmailTaken) {
+					return fiber.NewError(fiber.StatusConflict,
+						apiErr.New(apiErr.WithAppErr(apiErr.ErrEmailTaken)).Error())
+				}
+				return fiber.NewError(fiber.StatusUnprocessableEntity,
+					apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrChangeEmail)).Error())
+			}
+			status = fiber.StatusAccepted
+		}
+
+		// response
+		c.Status(status)
+		return toJson(c, res)
+	}
+}
+
+// HandleDeleteMe creates handler func that is responsible for deleting account of the signed in user.
+// Account is signed out everywhere and it can be restored by admin until it is purged.
+// Requests authenticated with API key are rejected.
+// It must be used after Middleware.Authenticated.
+func (uh Handler) HandleDeleteMe() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := handlers.SessionSubjectId(c)
+		if err != nil {
+			return err
+		}
+
+		// call core service
+		if err := uh.service.DeleteById(c.Context(), id); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError,
+				apiErr.New(apiErr.WithSvcErr(err), apiErr.WithAppErr(apiErr.ErrDeleteById)).Error())
+		}
+
+		// response
+		c.Status(fiber.StatusNoContent)
+		return nil
+	}
+}
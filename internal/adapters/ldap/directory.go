@@ -0,0 +1,131 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/configs"
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/go-ldap/ldap/v3"
+)
+
+var ErrDirectory = errors.New("user directory request failed")
+
+// Directory is LDAP implementation of ports.Directory interface.
+// User is searched with the service account and authenticated by binding with its own DN and password.
+type Directory struct {
+	cfg configs.LdapConfig
+}
+
+// NewDirectory instantiate new Directory, attributes that are not configured get OpenLDAP defaults.
+func NewDirectory(cfg configs.LdapConfig) *Directory {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(&(objectClass=person)(uid=%s))"
+	}
+	if cfg.UsernameAttribute == "" {
+		cfg.UsernameAttribute = "uid"
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	if cfg.NameAttribute == "" {
+		cfg.NameAttribute = "cn"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+	return &Directory{cfg: cfg}
+}
+
+// Authenticate searches the user by username and binds as the user to verify the password.
+func (d *Directory) Authenticate(_ context.Context, username string, password string) (*security.DirectoryEntry, error) {
+	// empty password would be accepted by most servers as unauthenticated bind
+	if username == "" || password == "" {
+		return nil, apiErr.ErrInvalidCreds
+	}
+
+	conn, err := d.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if d.cfg.BindDN != "" {
+		if err := conn.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("%w: service account bind: %w", ErrDirectory, err)
+		}
+	}
+
+	res, err := conn.Search(ldap.NewSearchRequest(
+		d.cfg.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2,
+		int(d.cfg.Timeout.Seconds()),
+		false,
+		fmt.Sprintf(d.cfg.UserFilter, ldap.EscapeFilter(username)),
+		[]string{d.cfg.UsernameAttribute, d.cfg.EmailAttribute, d.cfg.NameAttribute, d.cfg.GroupAttribute},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("%w: search: %w", ErrDirectory, err)
+	}
+	// ambiguous filter must not let the user sign in as somebody else
+	if len(res.Entries) != 1 {
+		return nil, apiErr.ErrInvalidCreds
+	}
+	entry := res.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, apiErr.ErrInvalidCreds
+		}
+		return nil, fmt.Errorf("%w: user bind: %w", ErrDirectory, err)
+	}
+
+	uid := entry.GetEqualFoldAttributeValue(d.cfg.UsernameAttribute)
+	if uid == "" {
+		uid = username
+	}
+	return &security.DirectoryEntry{
+		Username: uid,
+		Email:    entry.GetEqualFoldAttributeValue(d.cfg.EmailAttribute),
+		FullName: entry.GetEqualFoldAttributeValue(d.cfg.NameAttribute),
+		Groups:   entry.GetEqualFoldAttributeValues(d.cfg.GroupAttribute),
+	}, nil
+}
+
+// dial connects to the directory, ldap:// connection is upgraded to TLS if StartTLS is set.
+func (d *Directory) dial() (*ldap.Conn, error) {
+	u, err := url.Parse(d.cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDirectory, err)
+	}
+	tlsConfig := &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+
+	conn, err := ldap.DialURL(d.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: d.cfg.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDirectory, err)
+	}
+	conn.SetTimeout(d.cfg.Timeout)
+
+	if d.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: start tls: %w", ErrDirectory, err)
+		}
+	}
+	return conn, nil
+}
@@ -0,0 +1,107 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fmiskovic/go-starter/internal/core/configs"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/utils/testx"
+	"github.com/matryer/is"
+)
+
+func TestDirectory_Authenticate(t *testing.T) {
+	assert := is.New(t)
+
+	server, err := testx.SetUpLdapServer(
+		testx.LdapEntry{
+			DN:       "cn=search,dc=example,dc=com",
+			Password: "search-secret",
+		},
+		testx.LdapEntry{
+			DN:       "uid=jdoe,ou=people,dc=example,dc=com",
+			Password: "jdoe-secret",
+			Attributes: map[string][]string{
+				"objectClass": {"person", "inetOrgPerson"},
+				"uid":         {"jdoe"},
+				"cn":          {"Jane Doe"},
+				"mail":        {"jane@example.com"},
+				"memberOf":    {"cn=admins,ou=groups,dc=example,dc=com", "cn=staff,ou=groups,dc=example,dc=com"},
+			},
+		},
+		testx.LdapEntry{
+			DN: "uid=nopass,ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"nopass"},
+			},
+		},
+	)
+	assert.NoErr(err)
+	defer server.Close()
+
+	cfg := configs.LdapConfig{
+		URL:          server.URL(),
+		BindDN:       "cn=search,dc=example,dc=com",
+		BindPassword: "search-secret",
+		BaseDN:       "ou=people,dc=example,dc=com",
+	}
+
+	tests := []struct {
+		name     string
+		cfg      configs.LdapConfig
+		username string
+		password string
+		wantErr  error
+	}{
+		{
+			name:     "given valid credentials should return entry",
+			cfg:      cfg,
+			username: "jdoe",
+			password: "jdoe-secret",
+		},
+		{
+			name:     "given wrong password should return error",
+			cfg:      cfg,
+			username: "jdoe",
+			password: "wrong-secret",
+			wantErr:  apiErr.ErrInvalidCreds,
+		},
+		{
+			name:     "given unknown username should return error",
+			cfg:      cfg,
+			username: "unknown",
+			password: "jdoe-secret",
+			wantErr:  apiErr.ErrInvalidCreds,
+		},
+		{
+			name:     "given empty password should return error",
+			cfg:      cfg,
+			username: "nopass",
+			password: "",
+			wantErr:  apiErr.ErrInvalidCreds,
+		},
+		{
+			name:     "given wrong service account password should return error",
+			cfg:      configs.LdapConfig{URL: cfg.URL, BindDN: cfg.BindDN, BindPassword: "wrong", BaseDN: cfg.BaseDN},
+			username: "jdoe",
+			password: "jdoe-secret",
+			wantErr:  ErrDirectory,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := NewDirectory(tt.cfg).Authenticate(context.Background(), tt.username, tt.password)
+			if tt.wantErr != nil {
+				assert.True(errors.Is(err, tt.wantErr))
+				return
+			}
+			assert.NoErr(err)
+			assert.Equal(entry.Username, "jdoe")
+			assert.Equal(entry.Email, "jane@example.com")
+			assert.Equal(entry.FullName, "Jane Doe")
+			assert.Equal(len(entry.Groups), 2)
+		})
+	}
+}
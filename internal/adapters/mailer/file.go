@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileMailer is implementation of ports.Mailer interface that writes each message into a file.
+// It is meant to be used for local development and testing instead of a real mail server.
+type FileMailer struct {
+	dir string
+}
+
+// NewFileMailer instantiate new FileMailer that writes messages into specified directory.
+func NewFileMailer(dir string) FileMailer {
+	return FileMailer{dir: dir}
+}
+
+// Send writes message into a new file named by the time it was sent and the recipient.
+func (m FileMailer) Send(ctx context.Context, to, subject, body string) error {
+	if err := os.MkdirAll(m.dir, 0o750); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%d_%s.eml", now.UnixNano(), sanitize(to))
+	msg := fmt.Sprintf("Date: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", now.Format(time.RFC1123Z), to, subject, body)
+
+	return os.WriteFile(filepath.Join(m.dir, name), []byte(msg), 0o640)
+}
+
+// sanitize replaces characters that are not safe to be used in a file name.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '@', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
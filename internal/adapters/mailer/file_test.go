@@ -0,0 +1,49 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestFileMailer_Send(t *testing.T) {
+	assert := is.New(t)
+
+	dir := t.TempDir()
+	m := NewFileMailer(dir)
+
+	err := m.Send(context.Background(), "john@smith.com", "Confirm your email", "code: 1234")
+	assert.NoErr(err)
+
+	files, err := os.ReadDir(dir)
+	assert.NoErr(err)
+	assert.Equal(len(files), 1)
+	assert.True(strings.HasSuffix(files[0].Name(), "_john@smith.com.eml"))
+
+	content, err := os.ReadFile(dir + "/" + files[0].Name())
+	assert.NoErr(err)
+	assert.True(strings.Contains(string(content), "To: john@smith.com"))
+	assert.True(strings.Contains(string(content), "Subject: Confirm your email"))
+	assert.True(strings.Contains(string(content), "code: 1234"))
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "given email should keep it as is", in: "john@smith.com", want: "john@smith.com"},
+		{name: "given path separators should replace them", in: "../../etc/passwd", want: ".._.._etc_passwd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitize(tt.in); got != tt.want {
+				t.Errorf("sanitize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogMailer is implementation of ports.Mailer interface that only logs messages.
+type LogMailer struct{}
+
+// NewLogMailer instantiate new LogMailer.
+func NewLogMailer() LogMailer {
+	return LogMailer{}
+}
+
+// Send logs the message instead of sending it.
+// Body is not logged, it holds confirmation codes and password reset tokens that would let log readers take accounts over.
+func (m LogMailer) Send(ctx context.Context, to, subject, body string) error {
+	slog.Info("mail sent", "to", to, "subject", subject, "bodyLength", len(body))
+	return nil
+}
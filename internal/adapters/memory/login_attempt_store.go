@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+)
+
+// LoginAttemptStore is in-memory implementation of ports.LoginAttemptStore interface.
+// It is meant for single instance deployments and tests, attempts are lost on restart.
+type LoginAttemptStore struct {
+	mutex    sync.Mutex
+	attempts map[string]security.LoginAttempts
+}
+
+// NewLoginAttemptStore instantiate new LoginAttemptStore.
+func NewLoginAttemptStore() *LoginAttemptStore {
+	return &LoginAttemptStore{attempts: make(map[string]security.LoginAttempts)}
+}
+
+// Get returns attempts tracked under the key, zero attempts are returned if there are none.
+func (s *LoginAttemptStore) Get(ctx context.Context, key string) (*security.LoginAttempts, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	a, ok := s.attempts[key]
+	if !ok {
+		return &security.LoginAttempts{Key: key}, nil
+	}
+	return &a, nil
+}
+
+// Fail counts failed attempt, failures that happened before since are forgotten.
+// Forgotten attempts that are not locked are purged on the way.
+func (s *LoginAttemptStore) Fail(ctx context.Context, key string, since time.Time) (*security.LoginAttempts, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	a, ok := s.attempts[key]
+	if ok && !a.LastFailureAt.Before(since) {
+		a.Failures++
+		a.LastFailureAt = now
+	} else {
+		a = *security.NewLoginAttempts(key, now)
+	}
+	s.attempts[key] = a
+
+	for k, v := range s.attempts {
+		if k != key && v.LastFailureAt.Before(since) && !v.IsLocked(now) {
+			delete(s.attempts, k)
+		}
+	}
+
+	return &a, nil
+}
+
+// Lock blocks sign in under the key until specified time.
+func (s *LoginAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if a, ok := s.attempts[key]; ok {
+		a.LockedUntil = until
+		s.attempts[key] = a
+	}
+	return nil
+}
+
+// Reset forgets failed attempts and lifts the lock.
+func (s *LoginAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
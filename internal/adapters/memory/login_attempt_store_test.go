@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestLoginAttemptStore(t *testing.T) {
+	assert := is.New(t)
+	ctx := context.Background()
+
+	store := NewLoginAttemptStore()
+	now := time.Now()
+	since := now.Add(-time.Hour)
+
+	t.Run("given unknown key should return zero attempts", func(t *testing.T) {
+		a, err := store.Get(ctx, "username:john")
+		assert.NoErr(err)
+		assert.Equal(a.Failures, 0)
+		assert.True(!a.IsLocked(now))
+	})
+
+	t.Run("given failed attempts should count them", func(t *testing.T) {
+		for i := 1; i <= 3; i++ {
+			a, err := store.Fail(ctx, "username:john", since)
+			assert.NoErr(err)
+			assert.Equal(a.Failures, i)
+		}
+	})
+
+	t.Run("given failures before since should forget them", func(t *testing.T) {
+		a, err := store.Fail(ctx, "username:john", now.Add(time.Minute))
+		assert.NoErr(err)
+		assert.Equal(a.Failures, 1)
+	})
+
+	t.Run("given locked key should return locked attempts", func(t *testing.T) {
+		assert.NoErr(store.Lock(ctx, "username:john", now.Add(time.Minute)))
+
+		a, err := store.Get(ctx, "username:john")
+		assert.NoErr(err)
+		assert.True(a.IsLocked(now))
+		assert.True(!a.IsLocked(now.Add(2 * time.Minute)))
+	})
+
+	t.Run("given reset key should lift the lock", func(t *testing.T) {
+		assert.NoErr(store.Reset(ctx, "username:john"))
+
+		a, err := store.Get(ctx, "username:john")
+		assert.NoErr(err)
+		assert.Equal(a.Failures, 0)
+		assert.True(!a.IsLocked(now))
+	})
+}
@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RevocationStore is in-memory implementation of ports.RevocationStore interface.
+// It is meant for single instance deployments and tests, revocations are lost on restart.
+type RevocationStore struct {
+	mutex   sync.RWMutex
+	tokens  map[uuid.UUID]time.Time // revoked token ID to its expiration time
+	cutoffs map[uuid.UUID]time.Time // user ID to time before which all user's tokens are revoked
+}
+
+// NewRevocationStore instantiate new RevocationStore.
+func NewRevocationStore() *RevocationStore {
+	return &RevocationStore{
+		tokens:  make(map[uuid.UUID]time.Time),
+		cutoffs: make(map[uuid.UUID]time.Time),
+	}
+}
+
+// Revoke keeps revoked token until it expires.
+// Tokens that already expired are purged on the way.
+func (s *RevocationStore) Revoke(ctx context.Context, tokenID uuid.UUID, userID uuid.UUID, expiresAt time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for id, exp := range s.tokens {
+		if exp.Before(now) {
+			delete(s.tokens, id)
+		}
+	}
+
+	s.tokens[tokenID] = expiresAt
+	return nil
+}
+
+// RevokeAll revokes all tokens issued to the user before specified time.
+// Token issue time has second precision, so the time is truncated to seconds and tokens issued within that second stay valid.
+func (s *RevocationStore) RevokeAll(ctx context.Context, userID uuid.UUID, before time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.cutoffs[userID] = before.Truncate(time.Second)
+	return nil
+}
+
+// IsRevoked returns true if the token itself is revoked or all user's tokens are revoked after issuedAt.
+func (s *RevocationStore) IsRevoked(ctx context.Context, tokenID uuid.UUID, userID uuid.UUID, issuedAt time.Time) (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if _, ok := s.tokens[tokenID]; ok {
+		return true, nil
+	}
+	if cutoff, ok := s.cutoffs[userID]; ok && issuedAt.Before(cutoff) {
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matryer/is"
+)
+
+func TestRevocationStore(t *testing.T) {
+	assert := is.New(t)
+	ctx := context.Background()
+
+	store := NewRevocationStore()
+
+	userID := uuid.New()
+	otherUserID := uuid.New()
+	now := time.Now()
+
+	revokedID := uuid.New()
+	assert.NoErr(store.Revoke(ctx, revokedID, userID, now.Add(time.Hour)))
+
+	// expired tokens are purged when new revocation is added
+	expiredID := uuid.New()
+	assert.NoErr(store.Revoke(ctx, expiredID, userID, now.Add(-time.Hour)))
+	assert.NoErr(store.Revoke(ctx, uuid.New(), userID, now.Add(time.Hour)))
+
+	tests := []struct {
+		name     string
+		tokenID  uuid.UUID
+		userID   uuid.UUID
+		issuedAt time.Time
+		setup    func()
+		want     bool
+	}{
+		{
+			name:     "given revoked token should return true",
+			tokenID:  revokedID,
+			userID:   userID,
+			issuedAt: now,
+			setup:    func() {},
+			want:     true,
+		},
+		{
+			name:     "given expired revoked token should return false",
+			tokenID:  expiredID,
+			userID:   userID,
+			issuedAt: now,
+			setup:    func() {},
+			want:     false,
+		},
+		{
+			name:     "given not revoked token should return false",
+			tokenID:  uuid.New(),
+			userID:   userID,
+			issuedAt: now,
+			setup:    func() {},
+			want:     false,
+		},
+		{
+			name:     "given token issued before user revocation should return true",
+			tokenID:  uuid.New(),
+			userID:   otherUserID,
+			issuedAt: now.Add(-time.Minute),
+			setup:    func() { assert.NoErr(store.RevokeAll(ctx, otherUserID, now)) },
+			want:     true,
+		},
+		{
+			name:     "given token issued the second before user revocation should return true",
+			tokenID:  uuid.New(),
+			userID:   otherUserID,
+			issuedAt: now.Truncate(time.Second).Add(-time.Second),
+			setup:    func() {},
+			want:     true,
+		},
+		{
+			name:     "given token issued the same second as user revocation should return false",
+			tokenID:  uuid.New(),
+			userID:   otherUserID,
+			issuedAt: now.Truncate(time.Second),
+			setup:    func() {},
+			want:     false,
+		},
+		{
+			name:     "given token issued after user revocation should return false",
+			tokenID:  uuid.New(),
+			userID:   otherUserID,
+			issuedAt: now.Add(time.Minute),
+			setup:    func() {},
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			got, err := store.IsRevoked(ctx, tt.tokenID, tt.userID, tt.issuedAt)
+			assert.NoErr(err)
+			assert.Equal(got, tt.want)
+		})
+	}
+}
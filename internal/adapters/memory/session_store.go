@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/google/uuid"
+)
+
+// SessionStore is in-memory implementation of ports.SessionStore interface.
+// It is meant for single instance deployments and tests, users are signed out on restart.
+type SessionStore struct {
+	mutex    sync.RWMutex
+	sessions map[string]security.Session // token hash to session
+}
+
+// NewSessionStore instantiate new SessionStore.
+func NewSessionStore() *SessionStore {
+	return &SessionStore{sessions: make(map[string]security.Session)}
+}
+
+// Create keeps new session until it expires.
+// Sessions that already expired are purged on the way.
+func (s *SessionStore) Create(ctx context.Context, session *security.Session) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for hash, ses := range s.sessions {
+		if ses.ExpiresAt.Before(now) {
+			delete(s.sessions, hash)
+		}
+	}
+
+	s.sessions[session.TokenHash] = *session
+	return nil
+}
+
+// Get returns session by the hash of its token, sql.ErrNoRows is returned the same way as by the postgres store.
+func (s *SessionStore) Get(ctx context.Context, tokenHash string) (*security.Session, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ses, ok := s.sessions[tokenHash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &ses, nil
+}
+
+// Delete removes session by the hash of its token, unknown token is not an error.
+func (s *SessionStore) Delete(ctx context.Context, tokenHash string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.sessions, tokenHash)
+	return nil
+}
+
+// DeleteAll removes all sessions of the user.
+func (s *SessionStore) DeleteAll(ctx context.Context, userID uuid.UUID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for hash, ses := range s.sessions {
+		if ses.UserID == userID {
+			delete(s.sessions, hash)
+		}
+	}
+	return nil
+}
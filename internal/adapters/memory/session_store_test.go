@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/google/uuid"
+	"github.com/matryer/is"
+)
+
+func TestSessionStore(t *testing.T) {
+	assert := is.New(t)
+	ctx := context.Background()
+
+	store := NewSessionStore()
+	userID := uuid.New()
+	now := time.Now()
+
+	assert.NoErr(store.Create(ctx, security.NewSession(userID, "hash-1", now.Add(time.Hour))))
+	assert.NoErr(store.Create(ctx, security.NewSession(userID, "hash-2", now.Add(time.Hour))))
+	assert.NoErr(store.Create(ctx, security.NewSession(uuid.New(), "hash-other", now.Add(time.Hour))))
+
+	t.Run("given known token hash should return session", func(t *testing.T) {
+		s, err := store.Get(ctx, "hash-1")
+		assert.NoErr(err)
+		assert.Equal(s.UserID, userID)
+		assert.True(!s.IsExpired())
+	})
+
+	t.Run("given unknown token hash should return sql.ErrNoRows", func(t *testing.T) {
+		_, err := store.Get(ctx, "hash-unknown")
+		assert.True(errors.Is(err, sql.ErrNoRows))
+	})
+
+	t.Run("given expired sessions should purge them when new session is created", func(t *testing.T) {
+		assert.NoErr(store.Create(ctx, security.NewSession(userID, "hash-expired", now.Add(-time.Hour))))
+		assert.NoErr(store.Create(ctx, security.NewSession(userID, "hash-3", now.Add(time.Hour))))
+
+		_, err := store.Get(ctx, "hash-expired")
+		assert.True(errors.Is(err, sql.ErrNoRows))
+	})
+
+	t.Run("given deleted session should not return it", func(t *testing.T) {
+		assert.NoErr(store.Delete(ctx, "hash-1"))
+
+		_, err := store.Get(ctx, "hash-1")
+		assert.True(errors.Is(err, sql.ErrNoRows))
+		_, err = store.Get(ctx, "hash-2")
+		assert.NoErr(err)
+	})
+
+	t.Run("given deleted user sessions should keep sessions of other users", func(t *testing.T) {
+		assert.NoErr(store.DeleteAll(ctx, userID))
+
+		_, err := store.Get(ctx, "hash-2")
+		assert.True(errors.Is(err, sql.ErrNoRows))
+		_, err = store.Get(ctx, "hash-other")
+		assert.NoErr(err)
+	})
+}
@@ -0,0 +1,246 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MicahParks/keyfunc/v2"
+	"github.com/fmiskovic/go-starter/internal/core/configs"
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrDiscovery    = errors.New("failed to discover identity provider metadata")
+	ErrExchange     = errors.New("failed to exchange authorization code")
+	ErrInvalidToken = errors.New("invalid id token")
+)
+
+// defaultScopes are requested when provider is configured without scopes.
+var defaultScopes = []string{"openid", "email", "profile"}
+
+// signingMethods are ID token algorithms accepted from providers, symmetric ones are never accepted.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// metadata is part of the provider discovery document used by the authorization code flow.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// Provider is OpenID Connect implementation of ports.IdentityProvider interface.
+// Provider metadata and signing keys are fetched on first use and kept afterwards.
+type Provider struct {
+	cfg    configs.OidcProvider
+	client *http.Client
+
+	mutex sync.Mutex
+	meta  *metadata
+	keys  *keyfunc.JWKS
+}
+
+// NewProvider instantiate new Provider.
+func NewProvider(cfg configs.OidcProvider) *Provider {
+	return &Provider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// AuthCodeURL returns provider's authorization url, PKCE code challenge is derived from the verifier.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	meta, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultScopes
+	}
+	if !slices.Contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge(verifier))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange redeems authorization code at the token endpoint and verifies returned ID token.
+// Signature, issuer, audience, expiration and nonce of the token are verified.
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string, nonce string) (*security.IdentityClaims, error) {
+	meta, keys, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := p.redeem(ctx, meta.TokenEndpoint, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(idToken, claims, keys.Keyfunc,
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if n, _ := claims["nonce"].(string); n == "" || n != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	email, _ := claims["email"].(string)
+	name, _ := claims["name"].(string)
+	return &security.IdentityClaims{
+		Provider:      p.cfg.Name,
+		Subject:       sub,
+		Email:         email,
+		EmailVerified: isTrue(claims["email_verified"]),
+		Name:          name,
+	}, nil
+}
+
+// redeem posts authorization code to the token endpoint and returns ID token from the response.
+// Client authenticates with client_secret_basic, public clients send only their ID.
+func (p *Provider) redeem(ctx context.Context, endpoint string, code string, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrExchange, err)
+	}
+	defer res.Body.Close()
+
+	var body struct {
+		IdToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrExchange, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: %s %s", ErrExchange, body.Error, body.ErrorDescription)
+	}
+	if body.IdToken == "" {
+		return "", fmt.Errorf("%w: response has no id token", ErrExchange)
+	}
+	return body.IdToken, nil
+}
+
+// discover fetches provider metadata and signing keys.
+// Failed discovery is not cached, so the provider is retried once it becomes available.
+func (p *Provider) discover(ctx context.Context) (*metadata, *keyfunc.JWKS, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.meta != nil {
+		return p.meta, p.keys, nil
+	}
+
+	issuer := strings.TrimSuffix(p.cfg.IssuerURL, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%w: unexpected status %d", ErrDiscovery, res.StatusCode)
+	}
+	meta := &metadata{}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(meta); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+	// issuer of the document must be the configured one, otherwise tokens of another issuer would be accepted
+	if strings.TrimSuffix(meta.Issuer, "/") != issuer {
+		return nil, nil, fmt.Errorf("%w: issuer %q does not match %q", ErrDiscovery, meta.Issuer, p.cfg.IssuerURL)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JwksURI == "" {
+		return nil, nil, fmt.Errorf("%w: missing endpoints", ErrDiscovery)
+	}
+
+	// keys are refreshed when token is signed with unknown key, i.e. after provider rotated its keys
+	keys, err := keyfunc.Get(meta.JwksURI, keyfunc.Options{
+		Client:            p.client,
+		RefreshUnknownKID: true,
+		RefreshRateLimit:  5 * time.Minute,
+		RefreshTimeout:    10 * time.Second,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+
+	p.meta, p.keys = meta, keys
+	return p.meta, p.keys, nil
+}
+
+// codeChallenge derives S256 PKCE code challenge from the verifier.
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// isTrue reads boolean claim, some providers send booleans as strings.
+func isTrue(v any) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == "true"
+	default:
+		return false
+	}
+}
@@ -0,0 +1,110 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/fmiskovic/go-starter/internal/utils/testx"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/matryer/is"
+)
+
+const redirectURL = "http://localhost:8080/auth/oidc/stub/callback"
+
+func TestProvider(t *testing.T) {
+	assert := is.New(t)
+
+	stub, err := testx.SetUpOidcProvider()
+	assert.NoErr(err)
+	defer stub.Close()
+
+	ctx := context.Background()
+	verifier := "a9Ftk6C0q4sy2GkH3LwHVa-rJ9KPCyv2k4TKJq2Mmn8"
+
+	// signIn walks authorization url and returns code and state sent back to the callback
+	signIn := func(p *Provider, state string, nonce string) (string, string) {
+		authURL, err := p.AuthCodeURL(ctx, state, nonce, verifier)
+		assert.NoErr(err)
+
+		back, err := stub.SignIn(authURL)
+		assert.NoErr(err)
+		assert.Equal(back.Scheme+"://"+back.Host+back.Path, redirectURL)
+		return back.Query().Get("code"), back.Query().Get("state")
+	}
+
+	t.Run("given valid code should return verified claims", func(t *testing.T) {
+		stub.Claims = jwt.MapClaims{"sub": "stub-user-1", "email": "stub@fake.com", "email_verified": true, "name": "Stub User"}
+		p := NewProvider(stub.Config("stub", redirectURL))
+
+		authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+		assert.NoErr(err)
+		u, err := url.Parse(authURL)
+		assert.NoErr(err)
+		assert.Equal(u.Query().Get("scope"), "openid email profile")
+		assert.Equal(u.Query().Get("code_challenge"), codeChallenge(verifier))
+
+		code, state := signIn(p, "state-1", "nonce-1")
+		assert.Equal(state, "state-1")
+
+		claims, err := p.Exchange(ctx, code, verifier, "nonce-1")
+		assert.NoErr(err)
+		assert.Equal(claims.Provider, "stub")
+		assert.Equal(claims.Subject, "stub-user-1")
+		assert.Equal(claims.Email, "stub@fake.com")
+		assert.True(claims.EmailVerified)
+		assert.Equal(claims.Name, "Stub User")
+
+		// codes are single use
+		_, err = p.Exchange(ctx, code, verifier, "nonce-1")
+		assert.True(errors.Is(err, ErrExchange))
+	})
+
+	t.Run("given wrong code verifier should return error", func(t *testing.T) {
+		p := NewProvider(stub.Config("stub", redirectURL))
+		code, _ := signIn(p, "state-2", "nonce-2")
+
+		_, err := p.Exchange(ctx, code, "wrong-verifier", "nonce-2")
+		assert.True(errors.Is(err, ErrExchange))
+	})
+
+	t.Run("given different nonce should return error", func(t *testing.T) {
+		p := NewProvider(stub.Config("stub", redirectURL))
+		code, _ := signIn(p, "state-3", "nonce-3")
+
+		_, err := p.Exchange(ctx, code, verifier, "other-nonce")
+		assert.True(errors.Is(err, ErrInvalidToken))
+	})
+
+	t.Run("given wrong client secret should return error", func(t *testing.T) {
+		cfg := stub.Config("stub", redirectURL)
+		cfg.ClientSecret = "wrong-secret"
+		p := NewProvider(cfg)
+		code, _ := signIn(p, "state-4", "nonce-4")
+
+		_, err := p.Exchange(ctx, code, verifier, "nonce-4")
+		assert.True(errors.Is(err, ErrExchange))
+	})
+
+	t.Run("given token for another client should return error", func(t *testing.T) {
+		stub.Claims = jwt.MapClaims{"sub": "stub-user-1", "aud": "another-client"}
+		defer func() { stub.Claims = jwt.MapClaims{} }()
+		p := NewProvider(stub.Config("stub", redirectURL))
+		code, _ := signIn(p, "state-5", "nonce-5")
+
+		_, err := p.Exchange(ctx, code, verifier, "nonce-5")
+		assert.True(errors.Is(err, ErrInvalidToken))
+	})
+
+	t.Run("given issuer that does not match discovery should return error", func(t *testing.T) {
+		cfg := stub.Config("stub", redirectURL)
+		cfg.IssuerURL = stub.URL + "/"
+		_, err := NewProvider(cfg).AuthCodeURL(ctx, "state-6", "nonce-6", verifier)
+		assert.NoErr(err)
+
+		cfg.IssuerURL = stub.URL + "/other"
+		_, err = NewProvider(cfg).AuthCodeURL(ctx, "state-6", "nonce-6", verifier)
+		assert.True(errors.Is(err, ErrDiscovery))
+	})
+}
@@ -0,0 +1,90 @@
+package repos
+
+import (
+	"context"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// ApiKeyRepo is postgres implementation of ports.ApiKeyRepo interface.
+type ApiKeyRepo struct {
+	db *bun.DB
+}
+
+// NewApiKeyRepo instantiate new ApiKeyRepo.
+func NewApiKeyRepo(db *bun.DB) *ApiKeyRepo {
+	return &ApiKeyRepo{db}
+}
+
+// Create persists new API key.
+func (repo *ApiKeyRepo) Create(ctx context.Context, k *security.ApiKey) error {
+	if k == nil {
+		return ErrNilEntity
+	}
+	_, err := repo.db.NewInsert().Model(k).Exec(ctx)
+	return err
+}
+
+// GetByHash returns API key by the hash of the key.
+func (repo *ApiKeyRepo) GetByHash(ctx context.Context, keyHash string) (*security.ApiKey, error) {
+	k := new(security.ApiKey)
+	err := repo.db.NewSelect().Model(k).Where("key_hash = ?", keyHash).Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// GetByUserId returns all API keys of the user, newest first.
+func (repo *ApiKeyRepo) GetByUserId(ctx context.Context, userID uuid.UUID) ([]*security.ApiKey, error) {
+	var keys []*security.ApiKey
+	err := repo.db.NewSelect().
+		Model(&keys).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Touch records when the API key was used.
+func (repo *ApiKeyRepo) Touch(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	_, err := repo.db.NewUpdate().
+		Model((*security.ApiKey)(nil)).
+		Set("last_used_at = ?", usedAt).
+		Where("? = ?", bun.Ident("id"), id).
+		Exec(ctx)
+	return err
+}
+
+// DeleteById revokes API key of the user.
+// Returns apiErr.ErrInvalidId if the user does not own the key.
+func (repo *ApiKeyRepo) DeleteById(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
+	res, err := repo.db.NewDelete().
+		Model((*security.ApiKey)(nil)).
+		Where("? = ?", bun.Ident("id"), id).
+		Where("user_id = ?", userID).
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return apiErr.ErrInvalidId
+	}
+	return nil
+}
+
+// RevokeAll revokes all API keys of the user.
+func (repo *ApiKeyRepo) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	_, err := repo.db.NewDelete().
+		Model((*security.ApiKey)(nil)).
+		Where("user_id = ?", userID).
+		Exec(ctx)
+	return err
+}
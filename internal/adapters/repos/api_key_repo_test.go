@@ -0,0 +1,87 @@
+package repos
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/utils/testx"
+	"github.com/google/uuid"
+	"github.com/matryer/is"
+)
+
+func TestApiKeyRepo(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewApiKeyRepo(testDb.BunDb)
+	userID := uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01")
+	otherUserID := uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af02")
+
+	t.Run("given existing key hash should return the key", func(t *testing.T) {
+		k, err := repo.GetByHash(testDb.Ctx, "0a7aacb2c547041cb5fa184160271cbbb8d49f0258447b2becfb0fd6d651afa9")
+		assert.NoErr(err)
+		assert.Equal(k.Name, "ci")
+		assert.Equal(k.Scopes, []string{security.PERM_USER_READ})
+		assert.True(k.LastUsedAt.IsZero())
+	})
+
+	t.Run("given unknown key hash should return error", func(t *testing.T) {
+		_, err := repo.GetByHash(testDb.Ctx, "unknown")
+		assert.True(errors.Is(err, sql.ErrNoRows))
+	})
+
+	t.Run("given new key should list it first", func(t *testing.T) {
+		k := security.NewApiKey(userID, "deploy", "gs_deploy12", "hash", []string{security.PERM_USER_READ, security.PERM_USER_WRITE}, time.Now().Add(time.Hour))
+		assert.NoErr(repo.Create(testDb.Ctx, k))
+
+		keys, err := repo.GetByUserId(testDb.Ctx, userID)
+		assert.NoErr(err)
+		assert.Equal(len(keys), 3)
+		assert.Equal(keys[0].ID, k.ID)
+		assert.Equal(len(keys[0].Scopes), 2)
+	})
+
+	t.Run("given used key should record last used time", func(t *testing.T) {
+		id := uuid.MustParse("2a0cea28-b2b0-4051-9eb6-9a99e451af01")
+		assert.NoErr(repo.Touch(testDb.Ctx, id, time.Now()))
+
+		k, err := repo.GetByHash(testDb.Ctx, "0a7aacb2c547041cb5fa184160271cbbb8d49f0258447b2becfb0fd6d651afa9")
+		assert.NoErr(err)
+		assert.True(!k.LastUsedAt.IsZero())
+	})
+
+	t.Run("given key of another user should not delete it", func(t *testing.T) {
+		err := repo.DeleteById(testDb.Ctx, uuid.MustParse("2a0cea28-b2b0-4051-9eb6-9a99e451af01"), otherUserID)
+		assert.True(errors.Is(err, apiErr.ErrInvalidId))
+	})
+
+	t.Run("given own key should delete it", func(t *testing.T) {
+		assert.NoErr(repo.DeleteById(testDb.Ctx, uuid.MustParse("2a0cea28-b2b0-4051-9eb6-9a99e451af01"), userID))
+
+		_, err := repo.GetByHash(testDb.Ctx, "0a7aacb2c547041cb5fa184160271cbbb8d49f0258447b2becfb0fd6d651afa9")
+		assert.True(errors.Is(err, sql.ErrNoRows))
+	})
+
+	t.Run("given user should revoke all its keys", func(t *testing.T) {
+		assert.NoErr(repo.RevokeAll(testDb.Ctx, userID))
+
+		keys, err := repo.GetByUserId(testDb.Ctx, userID)
+		assert.NoErr(err)
+		assert.Equal(len(keys), 0)
+	})
+}
@@ -1,5 +1,15 @@
 package repos
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/uptrace/bun/driver/pgdriver"
+)
 
 var ErrNilEntity = errors.New("entity can not be nil")
+
+// isUniqueViolation returns true if the statement failed on unique constraint.
+func isUniqueViolation(err error) bool {
+	var pgErr pgdriver.Error
+	return errors.As(err, &pgErr) && pgErr.Field('C') == "23505"
+}
@@ -0,0 +1,81 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/uptrace/bun"
+)
+
+// LoginAttemptRepo is postgres implementation of ports.LoginAttemptStore interface.
+type LoginAttemptRepo struct {
+	db *bun.DB
+}
+
+// NewLoginAttemptRepo instantiate new LoginAttemptRepo.
+func NewLoginAttemptRepo(db *bun.DB) *LoginAttemptRepo {
+	return &LoginAttemptRepo{db}
+}
+
+// Get returns attempts tracked under the key, zero attempts are returned if there are none.
+func (repo *LoginAttemptRepo) Get(ctx context.Context, key string) (*security.LoginAttempts, error) {
+	a := new(security.LoginAttempts)
+	err := repo.db.NewSelect().Model(a).Where("? = ?", bun.Ident("key"), key).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &security.LoginAttempts{Key: key}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Fail counts failed attempt, failures that happened before since are forgotten.
+// Forgotten attempts that are not locked are purged on the way.
+func (repo *LoginAttemptRepo) Fail(ctx context.Context, key string, since time.Time) (*security.LoginAttempts, error) {
+	now := time.Now()
+	a := security.NewLoginAttempts(key, now)
+	_, err := repo.db.NewInsert().
+		Model(a).
+		On("CONFLICT (key) DO UPDATE").
+		Set("failures = CASE WHEN la.last_failure_at < ? THEN 1 ELSE la.failures + 1 END", since).
+		Set("last_failure_at = EXCLUDED.last_failure_at").
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = repo.db.NewDelete().
+		Model((*security.LoginAttempts)(nil)).
+		Where("? != ?", bun.Ident("key"), key).
+		Where("last_failure_at < ?", since).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Lock blocks sign in under the key until specified time.
+func (repo *LoginAttemptRepo) Lock(ctx context.Context, key string, until time.Time) error {
+	_, err := repo.db.NewUpdate().
+		Model((*security.LoginAttempts)(nil)).
+		Set("locked_until = ?", until).
+		Where("? = ?", bun.Ident("key"), key).
+		Exec(ctx)
+	return err
+}
+
+// Reset forgets failed attempts and lifts the lock.
+func (repo *LoginAttemptRepo) Reset(ctx context.Context, key string) error {
+	_, err := repo.db.NewDelete().
+		Model((*security.LoginAttempts)(nil)).
+		Where("? = ?", bun.Ident("key"), key).
+		Exec(ctx)
+	return err
+}
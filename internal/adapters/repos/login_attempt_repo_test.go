@@ -0,0 +1,66 @@
+package repos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/utils/testx"
+	"github.com/matryer/is"
+)
+
+func TestLoginAttemptRepo(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewLoginAttemptRepo(testDb.BunDb)
+	now := time.Now()
+	since := now.Add(-time.Hour)
+
+	t.Run("given unknown key should return zero attempts", func(t *testing.T) {
+		a, err := repo.Get(testDb.Ctx, "username:john")
+		assert.NoErr(err)
+		assert.Equal(a.Failures, 0)
+		assert.True(!a.IsLocked(now))
+	})
+
+	t.Run("given failed attempts should count them", func(t *testing.T) {
+		for i := 1; i <= 3; i++ {
+			a, err := repo.Fail(testDb.Ctx, "username:john", since)
+			assert.NoErr(err)
+			assert.Equal(a.Failures, i)
+		}
+	})
+
+	t.Run("given failures before since should forget them", func(t *testing.T) {
+		a, err := repo.Fail(testDb.Ctx, "username:john", now.Add(time.Minute))
+		assert.NoErr(err)
+		assert.Equal(a.Failures, 1)
+	})
+
+	t.Run("given locked key should return locked attempts", func(t *testing.T) {
+		assert.NoErr(repo.Lock(testDb.Ctx, "username:john", now.Add(time.Minute)))
+
+		a, err := repo.Get(testDb.Ctx, "username:john")
+		assert.NoErr(err)
+		assert.True(a.IsLocked(now))
+	})
+
+	t.Run("given reset key should lift the lock", func(t *testing.T) {
+		assert.NoErr(repo.Reset(testDb.Ctx, "username:john"))
+
+		a, err := repo.Get(testDb.Ctx, "username:john")
+		assert.NoErr(err)
+		assert.Equal(a.Failures, 0)
+	})
+}
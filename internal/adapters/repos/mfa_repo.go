@@ -0,0 +1,198 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// MfaRepo is implementation of ports.MfaRepo interface.
+type MfaRepo struct {
+	db *bun.DB
+}
+
+// NewMfaRepo instantiate new MfaRepo.
+func NewMfaRepo(db *bun.DB) *MfaRepo {
+	return &MfaRepo{db}
+}
+
+// GetSecret returns TOTP secret of the user.
+func (repo *MfaRepo) GetSecret(ctx context.Context, userID uuid.UUID) (*security.MfaSecret, error) {
+	var s = new(security.MfaSecret)
+
+	if err := repo.db.NewSelect().Model(s).Where("user_id = ?", userID).Scan(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SaveSecret persists new pending TOTP secret replacing previous pending one.
+// Returns apiErr.ErrMfaEnabled if user already has enabled secret.
+func (repo *MfaRepo) SaveSecret(ctx context.Context, s *security.MfaSecret) error {
+	if s == nil {
+		return ErrNilEntity
+	}
+
+	res, err := repo.db.NewInsert().
+		Model(s).
+		On("CONFLICT (user_id) DO UPDATE").
+		Set("secret = EXCLUDED.secret").
+		Set("last_used_step = 0").
+		Set("updated_at = EXCLUDED.updated_at").
+		Where("ms.enabled_at IS NULL").
+		Returning("NULL").
+		Exec(ctx)
+
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return apiErr.ErrMfaEnabled
+	}
+	return nil
+}
+
+// Enable enables pending secret of the user and replaces its recovery codes.
+// Step is the time step of the code used for enabling, so the code can not be used again.
+func (repo *MfaRepo) Enable(ctx context.Context, userID uuid.UUID, step int64, codes []*security.RecoveryCode) error {
+	return repo.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		now := time.Now()
+		s := &security.MfaSecret{EnabledAt: now, LastUsedStep: step}
+		s.UpdatedAt = now
+
+		res, err := tx.NewUpdate().
+			Model(s).
+			Column("enabled_at", "last_used_step", "updated_at").
+			Where("user_id = ?", userID).
+			Where("enabled_at IS NULL").
+			Exec(ctx)
+
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return apiErr.ErrMfaEnabled
+		}
+
+		if _, err := tx.NewDelete().Model((*security.RecoveryCode)(nil)).Where("user_id = ?", userID).Exec(ctx); err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		_, err = tx.NewInsert().Model(&codes).Exec(ctx)
+		return err
+	})
+}
+
+// UseStep records time step of accepted code.
+// Returns apiErr.ErrInvalidCode if code of the same or later time step was already used.
+func (repo *MfaRepo) UseStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	s := &security.MfaSecret{LastUsedStep: step}
+	s.UpdatedAt = time.Now()
+
+	res, err := repo.db.NewUpdate().
+		Model(s).
+		Column("last_used_step", "updated_at").
+		Where("user_id = ?", userID).
+		Where("last_used_step < ?", step).
+		Exec(ctx)
+
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return apiErr.ErrInvalidCode
+	}
+	return nil
+}
+
+// UseRecoveryCode consumes recovery code of the user.
+// Returns apiErr.ErrInvalidCode if there is no such code.
+func (repo *MfaRepo) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	res, err := repo.db.NewDelete().
+		Model((*security.RecoveryCode)(nil)).
+		Where("user_id = ?", userID).
+		Where("code_hash = ?", codeHash).
+		Exec(ctx)
+
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return apiErr.ErrInvalidCode
+	}
+	return nil
+}
+
+// DeleteByUserId removes secret, recovery codes and pending challenges of the user.
+func (repo *MfaRepo) DeleteByUserId(ctx context.Context, userID uuid.UUID) error {
+	return repo.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		models := []interface{}{
+			(*security.MfaSecret)(nil),
+			(*security.RecoveryCode)(nil),
+			(*security.MfaChallenge)(nil),
+		}
+		for _, m := range models {
+			if _, err := tx.NewDelete().Model(m).Where("user_id = ?", userID).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CreateChallenge persists new sign in challenge.
+func (repo *MfaRepo) CreateChallenge(ctx context.Context, c *security.MfaChallenge) error {
+	if c == nil {
+		return ErrNilEntity
+	}
+
+	_, err := repo.db.NewInsert().Model(c).Exec(ctx)
+	return err
+}
+
+// GetChallenge returns sign in challenge by its token hash.
+// Returns apiErr.ErrInvalidToken if there is no such challenge.
+func (repo *MfaRepo) GetChallenge(ctx context.Context, tokenHash string) (*security.MfaChallenge, error) {
+	var c = new(security.MfaChallenge)
+
+	err := repo.db.NewSelect().Model(c).Where("token_hash = ?", tokenHash).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apiErr.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// FailChallenge counts failed verification attempt.
+func (repo *MfaRepo) FailChallenge(ctx context.Context, id uuid.UUID) error {
+	_, err := repo.db.NewUpdate().
+		Model((*security.MfaChallenge)(nil)).
+		Set("attempts = attempts + 1").
+		Set("updated_at = ?", time.Now()).
+		Where("id = ?", id).
+		Exec(ctx)
+	return err
+}
+
+// DeleteChallenge consumes sign in challenge.
+// Returns apiErr.ErrInvalidToken if challenge was already consumed.
+func (repo *MfaRepo) DeleteChallenge(ctx context.Context, id uuid.UUID) error {
+	res, err := repo.db.NewDelete().Model((*security.MfaChallenge)(nil)).Where("id = ?", id).Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return apiErr.ErrInvalidToken
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package repos
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/utils/testx"
+	"github.com/fmiskovic/go-starter/internal/utils/token"
+	"github.com/google/uuid"
+	"github.com/matryer/is"
+)
+
+var (
+	pendingMfaUserId = uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af02")
+	enabledMfaUserId = uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af03")
+)
+
+func TestMfaRepo_SaveSecret(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewMfaRepo(testDb.BunDb)
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		wantErr error
+	}{
+		{
+			name:   "given user without secret should save pending secret",
+			userID: uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01"),
+		},
+		{
+			name:   "given user with pending secret should replace it",
+			userID: pendingMfaUserId,
+		},
+		{
+			name:    "given user with enabled secret should return error",
+			userID:  enabledMfaUserId,
+			wantErr: apiErr.ErrMfaEnabled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.SaveSecret(testDb.Ctx, security.NewMfaSecret(tt.userID, "NEWSECRET"))
+			if tt.wantErr != nil {
+				assert.True(errors.Is(err, tt.wantErr))
+				return
+			}
+			assert.NoErr(err)
+
+			s, err := repo.GetSecret(testDb.Ctx, tt.userID)
+			assert.NoErr(err)
+			assert.Equal(s.Secret, "NEWSECRET")
+			assert.True(!s.IsEnabled())
+		})
+	}
+}
+
+func TestMfaRepo_Enable(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewMfaRepo(testDb.BunDb)
+	codes := []*security.RecoveryCode{security.NewRecoveryCode(pendingMfaUserId, token.Hash("recovery"))}
+
+	assert.NoErr(repo.Enable(testDb.Ctx, pendingMfaUserId, 10, codes))
+
+	s, err := repo.GetSecret(testDb.Ctx, pendingMfaUserId)
+	assert.NoErr(err)
+	assert.True(s.IsEnabled())
+	assert.Equal(s.LastUsedStep, int64(10))
+
+	// already enabled secret can not be enabled again
+	assert.True(errors.Is(repo.Enable(testDb.Ctx, pendingMfaUserId, 11, nil), apiErr.ErrMfaEnabled))
+
+	// code of the step used for enabling can not be reused
+	assert.True(errors.Is(repo.UseStep(testDb.Ctx, pendingMfaUserId, 10), apiErr.ErrInvalidCode))
+	assert.NoErr(repo.UseStep(testDb.Ctx, pendingMfaUserId, 11))
+
+	// recovery code can be used only once
+	assert.NoErr(repo.UseRecoveryCode(testDb.Ctx, pendingMfaUserId, token.Hash("recovery")))
+	assert.True(errors.Is(repo.UseRecoveryCode(testDb.Ctx, pendingMfaUserId, token.Hash("recovery")), apiErr.ErrInvalidCode))
+}
+
+func TestMfaRepo_Challenge(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewMfaRepo(testDb.BunDb)
+
+	c, err := repo.GetChallenge(testDb.Ctx, token.Hash("mfa-token"))
+	assert.NoErr(err)
+	assert.Equal(c.UserID, enabledMfaUserId)
+	assert.Equal(c.Attempts, 0)
+
+	assert.NoErr(repo.FailChallenge(testDb.Ctx, c.ID))
+	c, err = repo.GetChallenge(testDb.Ctx, token.Hash("mfa-token"))
+	assert.NoErr(err)
+	assert.Equal(c.Attempts, 1)
+
+	// challenge is single-use
+	assert.NoErr(repo.DeleteChallenge(testDb.Ctx, c.ID))
+	assert.True(errors.Is(repo.DeleteChallenge(testDb.Ctx, c.ID), apiErr.ErrInvalidToken))
+
+	_, err = repo.GetChallenge(testDb.Ctx, token.Hash("mfa-token"))
+	assert.True(errors.Is(err, apiErr.ErrInvalidToken))
+}
+
+func TestMfaRepo_DeleteByUserId(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewMfaRepo(testDb.BunDb)
+
+	assert.NoErr(repo.DeleteByUserId(testDb.Ctx, enabledMfaUserId))
+
+	_, err = repo.GetSecret(testDb.Ctx, enabledMfaUserId)
+	assert.True(err != nil)
+	assert.True(errors.Is(repo.UseRecoveryCode(testDb.Ctx, enabledMfaUserId, token.Hash("abcdefghij")), apiErr.ErrInvalidCode))
+
+	_, err = repo.GetChallenge(testDb.Ctx, token.Hash("mfa-token"))
+	assert.True(errors.Is(err, apiErr.ErrInvalidToken))
+}
@@ -0,0 +1,126 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// RefreshTokenRepo is implementation of ports.RefreshTokenRepo interface.
+type RefreshTokenRepo struct {
+	db *bun.DB
+}
+
+// NewRefreshTokenRepo instantiate new RefreshTokenRepo.
+func NewRefreshTokenRepo(db *bun.DB) *RefreshTokenRepo {
+	return &RefreshTokenRepo{db}
+}
+
+// Create persists new refresh token.
+func (repo *RefreshTokenRepo) Create(ctx context.Context, t *security.RefreshToken) error {
+	if t == nil {
+		return ErrNilEntity
+	}
+
+	_, err := repo.db.NewInsert().Model(t).Exec(ctx)
+	return err
+}
+
+// Rotate marks refresh token with specified hash as used and persists the next token in the same family.
+// If already used token is presented again, the whole family is revoked and apiErr.ErrTokenReuse is returned.
+// Returns the rotated token.
+func (repo *RefreshTokenRepo) Rotate(ctx context.Context, tokenHash string, next *security.RefreshToken) (*security.RefreshToken, error) {
+	if next == nil {
+		return nil, ErrNilEntity
+	}
+
+	var (
+		current = new(security.RefreshToken)
+		reused  bool
+	)
+
+	err := repo.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		err := tx.NewSelect().
+			Model(current).
+			Where("token_hash = ?", tokenHash).
+			For("UPDATE").
+			Scan(ctx)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return apiErr.ErrInvalidToken
+		}
+		if err != nil {
+			return err
+		}
+		if current.IsRevoked() {
+			return apiErr.ErrInvalidToken
+		}
+
+		now := time.Now()
+
+		// token was already rotated, so it is either stolen or replayed, revoke the whole family
+		if current.IsUsed() {
+			reused = true
+			return revokeFamily(ctx, tx, current.FamilyID, now)
+		}
+		if current.IsExpired() {
+			return apiErr.ErrExpiredToken
+		}
+
+		current.UsedAt = now
+		current.UpdatedAt = now
+		if _, err := tx.NewUpdate().Model(current).Column("used_at", "updated_at").WherePK().Exec(ctx); err != nil {
+			return err
+		}
+
+		next.UserID = current.UserID
+		next.FamilyID = current.FamilyID
+		if _, err := tx.NewInsert().Model(next).Exec(ctx); err != nil {
+			return err
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, apiErr.ErrTokenReuse
+	}
+	return current, nil
+}
+
+// RevokeFamily revokes all refresh tokens that belong to the specified family.
+func (repo *RefreshTokenRepo) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	return revokeFamily(ctx, repo.db, familyID, time.Now())
+}
+
+// RevokeAll revokes all refresh tokens issued to the specified user.
+func (repo *RefreshTokenRepo) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	now := time.Now()
+	_, err := repo.db.NewUpdate().
+		Model((*security.RefreshToken)(nil)).
+		Set("revoked_at = ?", now).
+		Set("updated_at = ?", now).
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	return err
+}
+
+func revokeFamily(ctx context.Context, db bun.IDB, familyID uuid.UUID, now time.Time) error {
+	_, err := db.NewUpdate().
+		Model((*security.RefreshToken)(nil)).
+		Set("revoked_at = ?", now).
+		Set("updated_at = ?", now).
+		Where("family_id = ?", familyID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	return err
+}
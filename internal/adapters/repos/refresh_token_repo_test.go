@@ -0,0 +1,92 @@
+package repos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/utils/testx"
+	"github.com/fmiskovic/go-starter/internal/utils/token"
+	"github.com/google/uuid"
+	"github.com/matryer/is"
+)
+
+func TestRefreshTokenRepo_Rotate(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewRefreshTokenRepo(testDb.BunDb)
+
+	newToken := func(plain string) *security.RefreshToken {
+		return security.NewRefreshToken(uuid.Nil, uuid.Nil, token.Hash(plain), time.Now().Add(time.Hour))
+	}
+
+	// setup test cases
+	tests := []struct {
+		name    string
+		plain   string
+		next    *security.RefreshToken
+		wantErr error
+	}{
+		{
+			name:    "given valid token should rotate it",
+			plain:   "refresh-valid",
+			next:    newToken("refresh-next"),
+			wantErr: nil,
+		},
+		{
+			name:    "given rotated token should detect reuse",
+			plain:   "refresh-valid",
+			next:    newToken("refresh-other"),
+			wantErr: apiErr.ErrTokenReuse,
+		},
+		{
+			name:    "given token from revoked family should return error",
+			plain:   "refresh-next",
+			next:    newToken("refresh-another"),
+			wantErr: apiErr.ErrInvalidToken,
+		},
+		{
+			name:    "given expired token should return error",
+			plain:   "refresh-expired",
+			next:    newToken("refresh-expired-next"),
+			wantErr: apiErr.ErrExpiredToken,
+		},
+		{
+			name:    "given unknown token should return error",
+			plain:   "refresh-unknown",
+			next:    newToken("refresh-unknown-next"),
+			wantErr: apiErr.ErrInvalidToken,
+		},
+		{
+			name:    "given nil next token should return error",
+			plain:   "refresh-valid",
+			next:    nil,
+			wantErr: ErrNilEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rotated, err := repo.Rotate(testDb.Ctx, token.Hash(tt.plain), tt.next)
+			assert.Equal(err, tt.wantErr)
+			if err == nil {
+				assert.Equal(rotated.UserID, uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01"))
+				assert.Equal(tt.next.FamilyID, rotated.FamilyID)
+				assert.True(rotated.IsUsed())
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package repos
+
+import (
+	"context"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// RevocationRepo is postgres implementation of ports.RevocationStore interface.
+type RevocationRepo struct {
+	db *bun.DB
+}
+
+// NewRevocationRepo instantiate new RevocationRepo.
+func NewRevocationRepo(db *bun.DB) *RevocationRepo {
+	return &RevocationRepo{db}
+}
+
+// Revoke persists revoked token until it expires.
+// Tokens that already expired are purged on the way.
+func (repo *RevocationRepo) Revoke(ctx context.Context, tokenID uuid.UUID, userID uuid.UUID, expiresAt time.Time) error {
+	_, err := repo.db.NewInsert().
+		Model(security.NewRevokedToken(tokenID, userID, expiresAt)).
+		On("CONFLICT (id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.db.NewDelete().
+		Model((*security.RevokedToken)(nil)).
+		Where("expires_at < ?", time.Now()).
+		Exec(ctx)
+	return err
+}
+
+// RevokeAll revokes all tokens issued to the user before specified time.
+// Token issue time has second precision, so the time is truncated to seconds and tokens issued within that second stay valid.
+func (repo *RevocationRepo) RevokeAll(ctx context.Context, userID uuid.UUID, before time.Time) error {
+	_, err := repo.db.NewInsert().
+		Model(security.NewTokenRevocation(userID, before.Truncate(time.Second))).
+		On("CONFLICT (user_id) DO UPDATE").
+		Set("revoked_before = EXCLUDED.revoked_before").
+		Set("updated_at = EXCLUDED.updated_at").
+		Exec(ctx)
+	return err
+}
+
+// IsRevoked returns true if the token itself is revoked or all user's tokens are revoked after issuedAt.
+func (repo *RevocationRepo) IsRevoked(ctx context.Context, tokenID uuid.UUID, userID uuid.UUID, issuedAt time.Time) (bool, error) {
+	revoked, err := repo.db.NewSelect().
+		Model((*security.RevokedToken)(nil)).
+		Where("? = ?", bun.Ident("id"), tokenID).
+		Exists(ctx)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	return repo.db.NewSelect().
+		Model((*security.TokenRevocation)(nil)).
+		Where("user_id = ?", userID).
+		Where("revoked_before > ?", issuedAt).
+		Exists(ctx)
+}
@@ -0,0 +1,203 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// RoleRepo is implementation of ports.RoleRepo interface.
+type RoleRepo struct {
+	db *bun.DB
+}
+
+// NewRoleRepo instantiate new RoleRepo.
+func NewRoleRepo(db *bun.DB) *RoleRepo {
+	return &RoleRepo{db}
+}
+
+// GetById returns role with its permissions by specified id.
+func (repo *RoleRepo) GetById(ctx context.Context, id uuid.UUID) (*security.Role, error) {
+	var r = new(security.Role)
+
+	err := repo.db.NewSelect().
+		Model(r).
+		Relation("Permissions", orderByName).
+		Where("? = ?", bun.Ident("r.id"), id).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetAll returns all catalog roles with their permissions.
+func (repo *RoleRepo) GetAll(ctx context.Context) ([]*security.Role, error) {
+	var roles []*security.Role
+
+	err := repo.db.NewSelect().
+		Model(&roles).
+		Relation("Permissions", orderByName).
+		Order("r.name").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// Create persists new role together with its permissions.
+func (repo *RoleRepo) Create(ctx context.Context, r *security.Role) error {
+	if r == nil {
+		return ErrNilEntity
+	}
+
+	return repo.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(r).Exec(ctx); err != nil {
+			return err
+		}
+
+		names := make([]string, len(r.Permissions))
+		for i, p := range r.Permissions {
+			names[i] = p.Name
+		}
+		perms, err := grantPermissions(ctx, tx, r.ID, names)
+		if err != nil {
+			return err
+		}
+		r.Permissions = perms
+		return nil
+	})
+}
+
+// Update updates role description, role name is immutable since it is referenced by issued tokens.
+func (repo *RoleRepo) Update(ctx context.Context, r *security.Role) error {
+	if r == nil {
+		return ErrNilEntity
+	}
+
+	r.UpdatedAt = time.Now()
+
+	res, err := repo.db.NewUpdate().
+		Model(r).
+		Column("description", "updated_at").
+		Where("id = ?", r.ID).
+		Exec(ctx)
+
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteById removes role by specified id, users lose the role as well.
+func (repo *RoleRepo) DeleteById(ctx context.Context, id uuid.UUID) error {
+	if _, err := repo.db.NewDelete().Model((*security.Role)(nil)).Where("id = ?", id).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetPermissions replaces role permissions with the specified ones.
+// Returns apiErr.ErrUnknownPermission if any of the names is not in the catalog.
+func (repo *RoleRepo) SetPermissions(ctx context.Context, id uuid.UUID, permissions []string) error {
+	return repo.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		r := &security.Role{}
+		r.ID = id
+		r.UpdatedAt = time.Now()
+
+		res, err := tx.NewUpdate().Model(r).Column("updated_at").Where("id = ?", id).Exec(ctx)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return apiErr.ErrInvalidId
+		}
+
+		if _, err := tx.NewDelete().Model((*security.RolePermission)(nil)).Where("role_id = ?", id).Exec(ctx); err != nil {
+			return err
+		}
+
+		_, err = grantPermissions(ctx, tx, id, permissions)
+		return err
+	})
+}
+
+// GetPermissions returns all catalog permissions.
+func (repo *RoleRepo) GetPermissions(ctx context.Context) ([]*security.Permission, error) {
+	var perms []*security.Permission
+
+	if err := repo.db.NewSelect().Model(&perms).Order("p.name").Scan(ctx); err != nil {
+		return nil, err
+	}
+	return perms, nil
+}
+
+// GetPermissionById returns permission by specified id.
+func (repo *RoleRepo) GetPermissionById(ctx context.Context, id uuid.UUID) (*security.Permission, error) {
+	var p = new(security.Permission)
+
+	if err := repo.db.NewSelect().Model(p).Where("? = ?", bun.Ident("p.id"), id).Scan(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// CreatePermission persists new permission.
+func (repo *RoleRepo) CreatePermission(ctx context.Context, p *security.Permission) error {
+	if p == nil {
+		return ErrNilEntity
+	}
+
+	_, err := repo.db.NewInsert().Model(p).Exec(ctx)
+	return err
+}
+
+// DeletePermissionById removes permission by specified id, roles lose the permission as well.
+func (repo *RoleRepo) DeletePermissionById(ctx context.Context, id uuid.UUID) error {
+	if _, err := repo.db.NewDelete().Model((*security.Permission)(nil)).Where("id = ?", id).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+// grantPermissions grants catalog permissions to the role.
+// Returns apiErr.ErrUnknownPermission if any of the names is not in the catalog.
+func grantPermissions(ctx context.Context, db bun.IDB, roleID uuid.UUID, names []string) ([]*security.Permission, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var perms []*security.Permission
+	if err := db.NewSelect().Model(&perms).Where("name IN (?)", bun.In(names)).Order("p.name").Scan(ctx); err != nil {
+		return nil, err
+	}
+	if unknown := unknownNames(names, perms, func(p *security.Permission) string { return p.Name }); len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: %s", apiErr.ErrUnknownPermission, strings.Join(unknown, ", "))
+	}
+
+	rolePerms := make([]*security.RolePermission, len(perms))
+	for i, p := range perms {
+		rolePerms[i] = &security.RolePermission{RoleID: roleID, PermissionID: p.ID}
+	}
+	if _, err := db.NewInsert().Model(&rolePerms).Exec(ctx); err != nil {
+		return nil, err
+	}
+	return perms, nil
+}
+
+func orderByName(q *bun.SelectQuery) *bun.SelectQuery {
+	return q.Order("p.name")
+}
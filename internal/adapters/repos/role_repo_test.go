@@ -0,0 +1,216 @@
+package repos
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/utils/testx"
+	"github.com/google/uuid"
+	"github.com/matryer/is"
+)
+
+var (
+	adminRoleId     = uuid.MustParse("200cea28-b2b0-4051-9eb6-9a99e451af01")
+	moderatorRoleId = uuid.MustParse("200cea28-b2b0-4051-9eb6-9a99e451af03")
+)
+
+func TestRoleRepo_GetAll(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewRoleRepo(testDb.BunDb)
+
+	roles, err := repo.GetAll(testDb.Ctx)
+	assert.NoErr(err)
+	assert.Equal(len(roles), 3)
+
+	// roles are ordered by name
+	assert.Equal(roles[0].Name, security.ROLE_ADMIN)
+	assert.Equal(len(roles[0].Permissions), 8)
+	assert.Equal(roles[1].Name, security.ROLE_MODERATOR)
+	assert.Equal(len(roles[1].Permissions), 3)
+	assert.Equal(roles[2].Name, security.ROLE_USER)
+	assert.Equal(len(roles[2].Permissions), 0)
+}
+
+func TestRoleRepo_Create(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewRoleRepo(testDb.BunDb)
+
+	tests := []struct {
+		name        string
+		role        *security.Role
+		permissions []string
+		wantErr     error
+	}{
+		{
+			name:        "given role with known permissions should create role",
+			role:        security.NewRole("ROLE_AUDITOR", security.RoleDescription("Read only access")),
+			permissions: []string{security.PERM_USER_READ, security.PERM_ROLE_READ},
+		},
+		{
+			name:        "given role with unknown permission should return error",
+			role:        security.NewRole("ROLE_SUPPORT"),
+			permissions: []string{security.PERM_USER_READ, "user:raed"},
+			wantErr:     apiErr.ErrUnknownPermission,
+		},
+		{
+			name:    "given nil role should return error",
+			wantErr: ErrNilEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.role != nil {
+				for _, name := range tt.permissions {
+					tt.role.Permissions = append(tt.role.Permissions, &security.Permission{Name: name})
+				}
+			}
+
+			err := repo.Create(testDb.Ctx, tt.role)
+			if tt.wantErr != nil {
+				assert.True(errors.Is(err, tt.wantErr))
+				return
+			}
+			assert.NoErr(err)
+
+			r, err := repo.GetById(testDb.Ctx, tt.role.ID)
+			assert.NoErr(err)
+			assert.Equal(r.Description, tt.role.Description)
+			assert.Equal(len(r.Permissions), len(tt.permissions))
+		})
+	}
+
+	t.Run("given role with unknown permission should not be persisted", func(t *testing.T) {
+		roles, err := repo.GetAll(testDb.Ctx)
+		assert.NoErr(err)
+		for _, r := range roles {
+			assert.True(r.Name != "ROLE_SUPPORT")
+		}
+	})
+}
+
+func TestRoleRepo_SetPermissions(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewRoleRepo(testDb.BunDb)
+
+	tests := []struct {
+		name        string
+		id          uuid.UUID
+		permissions []string
+		want        int
+		wantErr     error
+	}{
+		{
+			name:        "given known permissions should replace role permissions",
+			id:          moderatorRoleId,
+			permissions: []string{security.PERM_USER_READ},
+			want:        1,
+		},
+		{
+			name:        "given unknown permission should keep role permissions",
+			id:          moderatorRoleId,
+			permissions: []string{"user:raed"},
+			want:        1,
+			wantErr:     apiErr.ErrUnknownPermission,
+		},
+		{
+			name:        "given empty permissions should remove all role permissions",
+			id:          moderatorRoleId,
+			permissions: []string{},
+			want:        0,
+		},
+		{
+			name:        "given non-existing role should return error",
+			id:          uuid.MustParse("22222222-b2b0-4051-9eb6-9a99e451af01"),
+			permissions: []string{security.PERM_USER_READ},
+			wantErr:     apiErr.ErrInvalidId,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.SetPermissions(testDb.Ctx, tt.id, tt.permissions)
+			if tt.wantErr != nil {
+				assert.True(errors.Is(err, tt.wantErr))
+			} else {
+				assert.NoErr(err)
+			}
+
+			if r, err := repo.GetById(testDb.Ctx, tt.id); err == nil {
+				assert.Equal(len(r.Permissions), tt.want)
+			}
+		})
+	}
+}
+
+func TestRoleRepo_DeleteById(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewRoleRepo(testDb.BunDb)
+	userRepo := NewUserRepo(testDb.BunDb)
+
+	assert.NoErr(repo.DeleteById(testDb.Ctx, adminRoleId))
+
+	_, err = repo.GetById(testDb.Ctx, adminRoleId)
+	assert.True(errors.Is(err, sql.ErrNoRows))
+
+	// users lose deleted role
+	u, err := userRepo.GetById(testDb.Ctx, uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01"))
+	assert.NoErr(err)
+	assert.Equal(len(u.Roles), 1)
+	assert.Equal(u.Roles[0].Name, security.ROLE_USER)
+}
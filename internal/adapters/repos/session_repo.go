@@ -0,0 +1,66 @@
+package repos
+
+import (
+	"context"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// SessionRepo is postgres implementation of ports.SessionStore interface.
+type SessionRepo struct {
+	db *bun.DB
+}
+
+// NewSessionRepo instantiate new SessionRepo.
+func NewSessionRepo(db *bun.DB) *SessionRepo {
+	return &SessionRepo{db}
+}
+
+// Create persists new session.
+// Sessions that already expired are purged on the way.
+func (repo *SessionRepo) Create(ctx context.Context, s *security.Session) error {
+	if s == nil {
+		return ErrNilEntity
+	}
+
+	if _, err := repo.db.NewInsert().Model(s).Exec(ctx); err != nil {
+		return err
+	}
+
+	_, err := repo.db.NewDelete().
+		Model((*security.Session)(nil)).
+		Where("expires_at < ?", time.Now()).
+		Exec(ctx)
+	return err
+}
+
+// Get returns session by the hash of its token.
+func (repo *SessionRepo) Get(ctx context.Context, tokenHash string) (*security.Session, error) {
+	s := new(security.Session)
+	err := repo.db.NewSelect().Model(s).Where("token_hash = ?", tokenHash).Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Delete removes session by the hash of its token, unknown token is not an error.
+func (repo *SessionRepo) Delete(ctx context.Context, tokenHash string) error {
+	_, err := repo.db.NewDelete().
+		Model((*security.Session)(nil)).
+		Where("token_hash = ?", tokenHash).
+		Exec(ctx)
+	return err
+}
+
+// DeleteAll removes all sessions of the user.
+func (repo *SessionRepo) DeleteAll(ctx context.Context, userID uuid.UUID) error {
+	_, err := repo.db.NewDelete().
+		Model((*security.Session)(nil)).
+		Where("user_id = ?", userID).
+		Exec(ctx)
+	return err
+}
@@ -0,0 +1,72 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/fmiskovic/go-starter/internal/utils/testx"
+	"github.com/google/uuid"
+	"github.com/matryer/is"
+)
+
+func TestSessionRepo(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	ctx := context.Background()
+	repo := NewSessionRepo(testDb.BunDb)
+
+	userID := uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01")
+	otherUserID := uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af02")
+	now := time.Now()
+
+	assert.NoErr(repo.Create(ctx, security.NewSession(userID, "hash-expired", now.Add(-time.Hour))))
+	assert.NoErr(repo.Create(ctx, security.NewSession(userID, "hash-1", now.Add(time.Hour))))
+	assert.NoErr(repo.Create(ctx, security.NewSession(userID, "hash-2", now.Add(time.Hour))))
+	assert.NoErr(repo.Create(ctx, security.NewSession(otherUserID, "hash-other", now.Add(time.Hour))))
+
+	t.Run("given known token hash should return session", func(t *testing.T) {
+		s, err := repo.Get(ctx, "hash-1")
+		assert.NoErr(err)
+		assert.Equal(s.UserID, userID)
+		assert.True(!s.IsExpired())
+	})
+
+	t.Run("given expired session should purge it when new session is created", func(t *testing.T) {
+		_, err := repo.Get(ctx, "hash-expired")
+		assert.True(errors.Is(err, sql.ErrNoRows))
+	})
+
+	t.Run("given deleted session should not return it", func(t *testing.T) {
+		assert.NoErr(repo.Delete(ctx, "hash-1"))
+
+		_, err := repo.Get(ctx, "hash-1")
+		assert.True(errors.Is(err, sql.ErrNoRows))
+		_, err = repo.Get(ctx, "hash-2")
+		assert.NoErr(err)
+	})
+
+	t.Run("given deleted user sessions should keep sessions of other users", func(t *testing.T) {
+		assert.NoErr(repo.DeleteAll(ctx, userID))
+
+		_, err := repo.Get(ctx, "hash-2")
+		assert.True(errors.Is(err, sql.ErrNoRows))
+		_, err = repo.Get(ctx, "hash-other")
+		assert.NoErr(err)
+	})
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,7 +15,6 @@ import (
 	"github.com/fmiskovic/go-starter/internal/core/domain"
 	"github.com/fmiskovic/go-starter/internal/core/domain/security"
 	"github.com/fmiskovic/go-starter/internal/core/domain/user"
-	"github.com/fmiskovic/go-starter/internal/utils/password"
 	"github.com/google/uuid"
 
 	"github.com/uptrace/bun"
@@ -33,7 +35,13 @@ func NewUserRepo(db *bun.DB) *UserRepo {
 func (repo *UserRepo) GetById(ctx context.Context, id uuid.UUID) (*user.User, error) {
 	var u = &user.User{}
 
-	err := repo.db.NewSelect().Model(u).Where("? = ?", bun.Ident("id"), id).Scan(ctx)
+	err := repo.db.NewSelect().
+		Model(u).
+		Relation("Roles.Permissions").
+		Relation("Credentials").
+		Relation("Identities").
+		Where("? = ?", bun.Ident("u.id"), id).
+		Scan(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -58,17 +66,29 @@ func (repo *UserRepo) Create(ctx context.Context, u *user.User) error {
 				return err
 			}
 		}
+		if len(u.Identities) > 0 {
+			_, err = tx.NewInsert().Model(&u.Identities).Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
 		if u.Roles != nil {
-			_, err = tx.NewInsert().Model(&u.Roles).Exec(ctx)
+			names := make([]string, len(u.Roles))
+			for i, role := range u.Roles {
+				names[i] = role.Name
+			}
+			roles, err := assignRoles(ctx, tx, u.ID, names)
 			if err != nil {
 				return err
 			}
+			u.Roles = roles
 		}
 		return nil
 	})
 }
 
-// Update existing persisted user entity.
+// Update existing persisted user entity, u is refreshed with the stored row and its incremented version.
+// If u has version set, apiErr.ErrVersionMismatch is returned when the user was changed in the meantime.
 func (repo *UserRepo) Update(ctx context.Context, u *user.User) error {
 	if u == nil {
 		return ErrNilEntity
@@ -76,14 +96,65 @@ func (repo *UserRepo) Update(ctx context.Context, u *user.User) error {
 
 	u.UpdatedAt = time.Now()
 
-	if _, err := repo.db.NewUpdate().Model(u).OmitZero().Where("id = ?", u.ID).Exec(ctx); err != nil {
+	q := repo.db.NewUpdate().
+		Model(u).
+		OmitZero().
+		Value("version", "version + 1").
+		Where("id = ?", u.ID).
+		Returning("*")
+	if u.Version > 0 {
+		q = q.Where("version = ?", u.Version)
+	}
+
+	res, err := q.Exec(ctx)
+	if err != nil {
 		return err
 	}
+	if n, _ := res.RowsAffected(); n > 0 || u.Version == 0 {
+		return nil
+	}
+	return repo.versionConflict(ctx, u.ID)
+}
 
-	return nil
+// UpdateProfile writes all profile fields of the user, empty ones are stored as NULL.
+// u is refreshed with the stored row, apiErr.ErrVersionMismatch is returned when the user was changed in the meantime.
+func (repo *UserRepo) UpdateProfile(ctx context.Context, u *user.User) error {
+	if u == nil {
+		return ErrNilEntity
+	}
+
+	u.UpdatedAt = time.Now()
+
+	res, err := repo.db.NewUpdate().
+		Model(u).
+		Column("email", "full_name", "date_of_birth", "location", "gender", "updated_at", "version").
+		Value("version", "version + 1").
+		Where("id = ?", u.ID).
+		Where("version = ?", u.Version).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	return repo.versionConflict(ctx, u.ID)
+}
+
+// versionConflict tells apart missing user from the one changed in the meantime when versioned update changed nothing.
+func (repo *UserRepo) versionConflict(ctx context.Context, id uuid.UUID) error {
+	exists, err := repo.db.NewSelect().Model((*user.User)(nil)).Where("id = ?", id).Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return apiErr.ErrVersionMismatch
+	}
+	return sql.ErrNoRows
 }
 
-// DeleteById remove user entity by specified id.
+// DeleteById soft deletes user entity by specified id, it is excluded from all queries until restored.
 func (repo *UserRepo) DeleteById(ctx context.Context, id uuid.UUID) error {
 	if _, err := repo.db.NewDelete().Model(new(user.User)).Where("id = ?", id).Exec(ctx); err != nil {
 		return err
@@ -92,36 +163,158 @@ func (repo *UserRepo) DeleteById(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-// GetPage respond with a page of users.
-func (repo *UserRepo) GetPage(ctx context.Context, p domain.Pageable) (domain.Page[user.User], error) {
+// Restore undeletes soft deleted user, returns sql.ErrNoRows if there is no such deleted user.
+func (repo *UserRepo) Restore(ctx context.Context, id uuid.UUID) error {
+	res, err := repo.db.NewUpdate().
+		Model((*user.User)(nil)).
+		Set("deleted_at = NULL").
+		Set("updated_at = ?", time.Now()).
+		Set("version = version + 1").
+		Where("id = ?", id).
+		WhereDeleted().
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Purge permanently removes users soft deleted before the time, their credentials, roles and tokens cascade.
+func (repo *UserRepo) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	res, err := repo.db.NewDelete().
+		Model((*user.User)(nil)).
+		WhereDeleted().
+		Where("deleted_at < ?", deletedBefore).
+		ForceDelete().
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// GetPage respond with a page of users matching the filter.
+func (repo *UserRepo) GetPage(ctx context.Context, p domain.Pageable, f user.Filter) (domain.Page[user.User], error) {
+	if p.Keyset {
+		return repo.getKeysetPage(ctx, p, f)
+	}
+
 	var users []user.User
-	count, err := repo.db.
+	q := repo.db.
 		NewSelect().
 		Model(&users).
+		Apply(filterUsers(f)).
 		Limit(p.Size).
 		Offset(p.Offset).
-		Order(domain.StringifyOrders(p.Sort)...).
-		ScanAndCount(ctx)
+		Order(domain.StringifyOrders(p.Sort)...)
 
-	totalPages := 0
-	if count != 0 && p.Size != 0 {
-		totalPages = (len(users) + p.Size - 1) / p.Size
+	if p.SkipCount {
+		err := q.Scan(ctx)
+		return domain.Page[user.User]{TotalPages: -1, TotalElements: -1, Elements: users}, err
 	}
 
+	count, err := q.ScanAndCount(ctx)
 	return domain.Page[user.User]{
-		TotalPages:    totalPages,
+		TotalPages:    domain.TotalPages(count, p.Size),
 		TotalElements: count,
 		Elements:      users,
 	}, err
 }
 
+// keysetColumns are columns users can be sorted on in keyset mode, nullable columns can not be compared.
+var keysetColumns = []string{"created_at", "updated_at", "email", "id"}
+
+// getKeysetPage reads page of users next to the cursor, it seeks by sort key instead of skipping offset rows.
+// Id is appended to the sort so the key is unique, sort directions must be ASC or DESC.
+func (repo *UserRepo) getKeysetPage(ctx context.Context, p domain.Pageable, f user.Filter) (domain.Page[user.User], error) {
+	idDirection := domain.ASC
+	if n := len(p.Sort.Orders); n > 0 {
+		idDirection = p.Sort.Orders[n-1].Direction
+	}
+	orders := append(slices.Clone(p.Sort.Orders), domain.NewOrder(domain.WithProperty("id"), domain.WithDirection(idDirection)))
+
+	// no limit would be set and the extra row could not be cut off
+	if p.Size <= 0 {
+		return domain.Page[user.User]{}, apiErr.ErrInvalidPageSize
+	}
+
+	before := p.Cursor != nil && p.Cursor.Before
+	if p.Cursor != nil && !slices.Equal(p.Cursor.Sort, domain.StringifyOrders(p.Sort)) {
+		return domain.Page[user.User]{}, apiErr.ErrInvalidCursor
+	}
+
+	// rows before the cursor are read in reversed order and reversed back
+	seek := make([]*domain.Order, len(orders))
+	for i, o := range orders {
+		d := o.Direction
+		switch {
+		case !slices.Contains(keysetColumns, o.Property):
+			return domain.Page[user.User]{}, apiErr.ErrInvalidSort
+		case d != domain.ASC && d != domain.DESC:
+			return domain.Page[user.User]{}, apiErr.ErrInvalidSort
+		case before && d == domain.ASC:
+			d = domain.DESC
+		case before && d == domain.DESC:
+			d = domain.ASC
+		}
+		seek[i] = domain.NewOrder(domain.WithProperty(o.Property), domain.WithDirection(d))
+	}
+
+	var users []user.User
+	q := repo.db.
+		NewSelect().
+		Model(&users).
+		Apply(filterUsers(f)).
+		Order(domain.StringifyOrders(domain.NewSort(seek...))...).
+		Limit(p.Size + 1)
+	if p.Cursor != nil {
+		q = q.WhereGroup(" AND ", seekAfter(seek, p.Cursor.Values))
+	}
+	if err := q.Scan(ctx); err != nil {
+		return domain.Page[user.User]{}, err
+	}
+
+	// one more row than the page size tells there is another page in the direction of reading
+	more := len(users) > p.Size
+	if more {
+		users = users[:p.Size]
+	}
+	if before {
+		slices.Reverse(users)
+	}
+
+	page := domain.Page[user.User]{TotalPages: -1, TotalElements: -1, Elements: users}
+	if len(users) > 0 {
+		if more || before {
+			page.NextCursor = keysetCursor(p.Sort, orders, users[len(users)-1], false)
+		}
+		if (more && before) || (p.Cursor != nil && !before) {
+			page.PrevCursor = keysetCursor(p.Sort, orders, users[0], true)
+		}
+	}
+
+	if !p.SkipCount {
+		count, err := repo.db.NewSelect().Model((*user.User)(nil)).Apply(filterUsers(f)).Count(ctx)
+		if err != nil {
+			return domain.Page[user.User]{}, err
+		}
+		page.TotalElements, page.TotalPages = count, domain.TotalPages(count, p.Size)
+	}
+
+	return page, nil
+}
+
 // GetByUsername returns user by username.
 func (repo *UserRepo) GetByUsername(ctx context.Context, username string) (*user.User, error) {
 	var u = new(user.User)
 
 	err := repo.db.NewSelect().
 		Model(u).
-		Relation("Roles").
+		Relation("Roles.Permissions").
 		Relation("Credentials", func(sq *bun.SelectQuery) *bun.SelectQuery {
 			return sq.Where("username = ?", username)
 		}).
@@ -134,47 +327,91 @@ func (repo *UserRepo) GetByUsername(ctx context.Context, username string) (*user
 	return u, nil
 }
 
-// ChangePassword updates users password.
-func (repo *UserRepo) ChangePassword(ctx context.Context, req *user.ChangePasswordRequest) error {
-	return repo.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
-		repo.mutex.Lock()
-		defer repo.mutex.Unlock()
+// GetByIdentity returns user linked to the account of external identity provider.
+func (repo *UserRepo) GetByIdentity(ctx context.Context, provider string, subject string) (*user.User, error) {
+	var u = new(user.User)
 
-		var u = new(user.User)
+	err := repo.db.NewSelect().
+		Model(u).
+		Relation("Roles.Permissions").
+		Relation("Credentials").
+		Join("JOIN identities AS idn ON idn.user_id = u.id").
+		Where("idn.provider = ? AND idn.subject = ?", provider, subject).
+		Scan(ctx)
 
-		err := tx.NewSelect().
-			Model(u).
-			Relation("Credentials", func(sq *bun.SelectQuery) *bun.SelectQuery {
-				return sq.Where("username = ?", req.Username)
-			}).
-			Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		if err != nil {
-			return err
-		}
+	return u, nil
+}
 
-		if !password.CheckPasswordHash(req.OldPassword, u.Credentials.Password) {
-			return errors.New("invalid old password")
-		}
+// AddIdentity links existing user to the account of external identity provider.
+func (repo *UserRepo) AddIdentity(ctx context.Context, identity *security.Identity) error {
+	if identity == nil {
+		return ErrNilEntity
+	}
 
-		newPwd, err := password.HashPassword(req.NewPassword)
-		if err != nil {
-			return err
-		}
+	_, err := repo.db.NewInsert().Model(identity).Exec(ctx)
+	return err
+}
 
-		crd := u.Credentials
-		crd.Password = newPwd
-		crd.UpdatedAt = time.Now()
-		u.UpdatedAt = crd.UpdatedAt
+// GetByEmail returns user by email address.
+func (repo *UserRepo) GetByEmail(ctx context.Context, email string) (*user.User, error) {
+	var u = new(user.User)
 
-		if _, err := tx.NewUpdate().Model(crd).OmitZero().Where("user_id = ?", u.ID).Exec(ctx); err != nil {
-			return err
-		}
+	err := repo.db.NewSelect().
+		Model(u).
+		Where("? = ?", bun.Ident("u.email"), email).
+		Scan(ctx)
 
-		return nil
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// UpdatePassword replaces password hash of the user, the replaced hash is kept in the history of historySize last passwords.
+// Returns sql.ErrNoRows if the user has no credentials.
+func (repo *UserRepo) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string, historySize int) error {
+	return repo.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		return replacePassword(ctx, tx, id, passwordHash, historySize)
 	})
 }
 
+// RehashPassword replaces hash of the same password if it is still oldHash, password history is not changed.
+func (repo *UserRepo) RehashPassword(ctx context.Context, id uuid.UUID, oldHash string, newHash string) error {
+	_, err := repo.db.NewUpdate().
+		Model((*security.Credentials)(nil)).
+		Set("password_hash = ?", newHash).
+		Set("updated_at = ?", time.Now()).
+		Where("user_id = ?", id).
+		Where("password_hash = ?", oldHash).
+		Exec(ctx)
+	return err
+}
+
+// GetPasswordHistory returns hashes of up to limit passwords the user had before, the latest first.
+func (repo *UserRepo) GetPasswordHistory(ctx context.Context, id uuid.UUID, limit int) ([]string, error) {
+	var hashes []string
+	if limit <= 0 {
+		return hashes, nil
+	}
+
+	err := repo.db.NewSelect().
+		Model((*security.PasswordHistory)(nil)).
+		Column("password_hash").
+		Where("user_id = ?", id).
+		OrderExpr("created_at DESC").
+		Limit(limit).
+		Scan(ctx, &hashes)
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
 // AddRoles to existing user.
 func (repo *UserRepo) AddRoles(ctx context.Context, roleNames []string, id uuid.UUID) error {
 	l := len(roleNames)
@@ -197,22 +434,13 @@ func (repo *UserRepo) AddRoles(ctx context.Context, roleNames []string, id uuid.
 			return apiErr.ErrInvalidId
 		}
 
-		var roles = make([]*security.Role, l)
-		for i, name := range roleNames {
-			role := security.NewRole(name)
-			role.UserID = id
-			roles[i] = role
+		if _, err := assignRoles(ctx, tx, id, roleNames); err != nil {
+			return err
 		}
 
-		if len(roles) > 0 {
-			if _, err := tx.NewInsert().Model(&roles).Exec(ctx); err != nil {
-				return err
-			}
-
-			u := &user.User{Entity: domain.Entity{ID: id, UpdatedAt: time.Now()}}
-			if _, err := tx.NewUpdate().Model((u)).OmitZero().Where("? = ?", bun.Ident("id"), id).Exec(ctx); err != nil {
-				return err
-			}
+		u := &user.User{Entity: domain.Entity{ID: id, UpdatedAt: time.Now()}}
+		if _, err := tx.NewUpdate().Model((u)).OmitZero().Where("? = ?", bun.Ident("id"), id).Exec(ctx); err != nil {
+			return err
 		}
 
 		return nil
@@ -239,9 +467,9 @@ func (repo *UserRepo) RemoveRoles(ctx context.Context, roleNames []string, id uu
 
 		if len(roleNames) > 0 {
 			_, err = tx.NewDelete().
-				Model(&security.Role{}).
+				Model((*user.UserRole)(nil)).
 				Where("user_id = ?", id).
-				Where("name IN (?)", bun.In(roleNames)).
+				Where("role_id IN (?)", tx.NewSelect().Model((*security.Role)(nil)).Column("id").Where("name IN (?)", bun.In(roleNames))).
 				Exec(ctx)
 
 			if err != nil {
@@ -273,9 +501,344 @@ func (repo *UserRepo) EnableDisable(ctx context.Context, id uuid.UUID) error {
 		u.UpdatedAt = time.Now()
 		u.Enabled = !u.Enabled
 
-		if _, err := tx.NewUpdate().Model(u).Column("enabled", "updated_at").Where("id = ?", id).Exec(ctx); err != nil {
+		if _, err := tx.NewUpdate().
+			Model(u).
+			Column("enabled", "updated_at", "version").
+			Value("version", "version + 1").
+			Where("id = ?", id).
+			Exec(ctx); err != nil {
 			return err
 		}
 		return nil
 	})
 }
+
+// SaveEmailConfirmation persists new email confirmation code and discards previously issued ones.
+func (repo *UserRepo) SaveEmailConfirmation(ctx context.Context, c *security.EmailConfirmation) error {
+	if c == nil {
+		return ErrNilEntity
+	}
+
+	return repo.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().Model((*security.EmailConfirmation)(nil)).Where("user_id = ?", c.UserID).Exec(ctx); err != nil {
+			return err
+		}
+		if _, err := tx.NewInsert().Model(c).Exec(ctx); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// ConfirmEmail consumes confirmation code and enables the user it was issued to.
+// If the code confirms new email address, user email is changed instead,
+// apiErr.ErrEmailTaken is returned if the address is registered by another user meanwhile.
+func (repo *UserRepo) ConfirmEmail(ctx context.Context, id uuid.UUID, codeHash string) error {
+	return repo.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		var c = new(security.EmailConfirmation)
+
+		err := tx.NewSelect().
+			Model(c).
+			Where("user_id = ?", id).
+			Where("code_hash = ?", codeHash).
+			Scan(ctx)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return apiErr.ErrInvalidCode
+		}
+		if err != nil {
+			return err
+		}
+		if c.IsExpired() {
+			return apiErr.ErrExpiredCode
+		}
+
+		if _, err := tx.NewDelete().Model((*security.EmailConfirmation)(nil)).Where("user_id = ?", id).Exec(ctx); err != nil {
+			return err
+		}
+
+		u := &user.User{Entity: domain.Entity{ID: id, UpdatedAt: time.Now()}, Enabled: true, Email: c.Email}
+		column := "enabled"
+		if c.Email != "" {
+			column = "email"
+		}
+		if _, err := tx.NewUpdate().
+			Model(u).
+			Column(column, "updated_at", "version").
+			Value("version", "version + 1").
+			Where("id = ?", id).
+			Exec(ctx); err != nil {
+			// new email was registered by someone else after the code was issued
+			if isUniqueViolation(err) {
+				return apiErr.ErrEmailTaken
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// SavePasswordReset persists new password reset token and discards previously issued ones.
+func (repo *UserRepo) SavePasswordReset(ctx context.Context, r *security.PasswordReset) error {
+	if r == nil {
+		return ErrNilEntity
+	}
+
+	return repo.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().Model((*security.PasswordReset)(nil)).Where("user_id = ?", r.UserID).Exec(ctx); err != nil {
+			return err
+		}
+		if _, err := tx.NewInsert().Model(r).Exec(ctx); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// GetByResetToken returns user the password reset token was issued to.
+// Returns apiErr.ErrInvalidToken if the token is unknown and apiErr.ErrExpiredToken if it is expired.
+func (repo *UserRepo) GetByResetToken(ctx context.Context, tokenHash string) (*user.User, error) {
+	var r = new(security.PasswordReset)
+
+	err := repo.db.NewSelect().
+		Model(r).
+		Where("token_hash = ?", tokenHash).
+		Scan(ctx)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apiErr.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.IsExpired() {
+		return nil, apiErr.ErrExpiredToken
+	}
+
+	return repo.GetById(ctx, r.UserID)
+}
+
+// ResetPassword consumes password reset token and replaces password of the user it was issued to.
+// Returns ID of the user whose password is reset.
+// The replaced password is kept in the history of historySize last passwords.
+func (repo *UserRepo) ResetPassword(ctx context.Context, tokenHash string, passwordHash string, historySize int) (uuid.UUID, error) {
+	var userID uuid.UUID
+
+	err := repo.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		var r = new(security.PasswordReset)
+
+		// lock the token so concurrent requests can not consume it twice
+		err := tx.NewSelect().
+			Model(r).
+			Where("token_hash = ?", tokenHash).
+			For("UPDATE").
+			Scan(ctx)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return apiErr.ErrInvalidToken
+		}
+		if err != nil {
+			return err
+		}
+
+		if r.IsExpired() {
+			return apiErr.ErrExpiredToken
+		}
+
+		// token is single-use, consume it together with other tokens issued to the user
+		if _, err := tx.NewDelete().Model((*security.PasswordReset)(nil)).Where("user_id = ?", r.UserID).Exec(ctx); err != nil {
+			return err
+		}
+
+		err = replacePassword(ctx, tx, r.UserID, passwordHash, historySize)
+		if errors.Is(err, sql.ErrNoRows) {
+			return apiErr.ErrInvalidToken
+		}
+		if err != nil {
+			return err
+		}
+
+		userID = r.UserID
+		return nil
+	})
+
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
+
+// replacePassword replaces password hash of the user, the replaced hash is kept in the history of historySize last passwords.
+// Returns sql.ErrNoRows if the user has no credentials.
+func replacePassword(ctx context.Context, db bun.IDB, userID uuid.UUID, passwordHash string, historySize int) error {
+	var crd = new(security.Credentials)
+
+	// lock the credentials so concurrent changes can not lose the history
+	err := db.NewSelect().
+		Model(crd).
+		Where("user_id = ?", userID).
+		For("UPDATE").
+		Scan(ctx)
+	if err != nil {
+		return err
+	}
+
+	// passwords older than the history are forgotten
+	prune := db.NewDelete().Model((*security.PasswordHistory)(nil)).Where("user_id = ?", userID)
+	if historySize > 0 {
+		if _, err := db.NewInsert().Model(security.NewPasswordHistory(userID, crd.Password)).Exec(ctx); err != nil {
+			return err
+		}
+
+		kept := db.NewSelect().
+			Model((*security.PasswordHistory)(nil)).
+			Column("id").
+			Where("user_id = ?", userID).
+			OrderExpr("created_at DESC").
+			Limit(historySize)
+		prune = prune.Where("id NOT IN (?)", kept)
+	}
+	if _, err := prune.Exec(ctx); err != nil {
+		return err
+	}
+
+	crd.Password = passwordHash
+	crd.UpdatedAt = time.Now()
+	_, err = db.NewUpdate().
+		Model(crd).
+		Column("password_hash", "updated_at").
+		Where("user_id = ?", userID).
+		Exec(ctx)
+	return err
+}
+
+// assignRoles assigns catalog roles to the user, already assigned roles are skipped.
+// Returns ErrUnknownRole if any of the names is not in the catalog.
+func assignRoles(ctx context.Context, db bun.IDB, userID uuid.UUID, roleNames []string) ([]*security.Role, error) {
+	if len(roleNames) == 0 {
+		return nil, nil
+	}
+
+	var roles []*security.Role
+	if err := db.NewSelect().Model(&roles).Where("name IN (?)", bun.In(roleNames)).Scan(ctx); err != nil {
+		return nil, err
+	}
+	if unknown := unknownNames(roleNames, roles, func(r *security.Role) string { return r.Name }); len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: %s", apiErr.ErrUnknownRole, strings.Join(unknown, ", "))
+	}
+
+	userRoles := make([]*user.UserRole, len(roles))
+	for i, role := range roles {
+		userRoles[i] = &user.UserRole{UserID: userID, RoleID: role.ID}
+	}
+	if _, err := db.NewInsert().Model(&userRoles).On("CONFLICT DO NOTHING").Exec(ctx); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// unknownNames returns names that are not present in the found catalog entries.
+func unknownNames[T any](names []string, found []T, nameOf func(T) string) []string {
+	known := make(map[string]bool, len(found))
+	for _, f := range found {
+		known[nameOf(f)] = true
+	}
+
+	var unknown []string
+	for _, name := range names {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// filterUsers applies the filter to the users query, text is matched with ILIKE so trigram indexes on the columns are used.
+func filterUsers(f user.Filter) func(*bun.SelectQuery) *bun.SelectQuery {
+	return func(q *bun.SelectQuery) *bun.SelectQuery {
+		if f.IncludeDeleted {
+			q = q.WhereAllWithDeleted()
+		}
+		if f.Query != "" {
+			pattern := containsPattern(f.Query)
+			q = q.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+				return q.Where("u.email ILIKE ?", pattern).
+					WhereOr("u.full_name ILIKE ?", pattern).
+					WhereOr("u.location ILIKE ?", pattern)
+			})
+		}
+		if f.Email != "" {
+			q = q.Where("u.email ILIKE ?", containsPattern(f.Email))
+		}
+		if f.FullName != "" {
+			q = q.Where("u.full_name ILIKE ?", containsPattern(f.FullName))
+		}
+		if f.Location != "" {
+			q = q.Where("u.location ILIKE ?", containsPattern(f.Location))
+		}
+		if f.Gender != nil {
+			q = q.Where("u.gender = ?", *f.Gender)
+		}
+		if f.Enabled != nil {
+			q = q.Where("u.enabled = ?", *f.Enabled)
+		}
+		if f.Role != "" {
+			q = q.Where("EXISTS (SELECT 1 FROM user_roles AS ur JOIN roles AS r ON r.id = ur.role_id WHERE ur.user_id = u.id AND r.name = ?)", f.Role)
+		}
+		if !f.CreatedFrom.IsZero() {
+			q = q.Where("u.created_at >= ?", f.CreatedFrom)
+		}
+		if !f.CreatedTo.IsZero() {
+			q = q.Where("u.created_at < ?", f.CreatedTo)
+		}
+		if !f.UpdatedFrom.IsZero() {
+			q = q.Where("u.updated_at >= ?", f.UpdatedFrom)
+		}
+		if !f.UpdatedTo.IsZero() {
+			q = q.Where("u.updated_at < ?", f.UpdatedTo)
+		}
+		return q
+	}
+}
+
+// containsPattern escapes LIKE wildcards in s and returns pattern matching any text containing s.
+func containsPattern(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+	return "%" + s + "%"
+}
+
+// seekAfter selects rows after the sort key values in the order, i.e. (c1 > v1) OR (c1 = v1 AND c2 > v2) OR ...
+func seekAfter(orders []*domain.Order, values []any) func(*bun.SelectQuery) *bun.SelectQuery {
+	return func(q *bun.SelectQuery) *bun.SelectQuery {
+		for i, o := range orders {
+			q = q.WhereGroup(" OR ", func(q *bun.SelectQuery) *bun.SelectQuery {
+				for j, prev := range orders[:i] {
+					q = q.Where("u.? = ?", bun.Ident(prev.Property), values[j])
+				}
+				if o.Direction == domain.DESC {
+					return q.Where("u.? < ?", bun.Ident(o.Property), values[i])
+				}
+				return q.Where("u.? > ?", bun.Ident(o.Property), values[i])
+			})
+		}
+		return q
+	}
+}
+
+// keysetCursor makes cursor of the user row, orders are the sort orders followed by id.
+func keysetCursor(s domain.Sort, orders []*domain.Order, u user.User, before bool) string {
+	values := make([]any, len(orders))
+	for i, o := range orders {
+		switch o.Property {
+		case "created_at":
+			values[i] = u.CreatedAt.UTC().Format(time.RFC3339Nano)
+		case "updated_at":
+			values[i] = u.UpdatedAt.UTC().Format(time.RFC3339Nano)
+		case "email":
+			values[i] = u.Email
+		case "id":
+			values[i] = u.ID.String()
+		}
+	}
+	return domain.Cursor{Sort: domain.StringifyOrders(s), Values: values, Before: before}.Encode()
+}
@@ -1,15 +1,20 @@
 package repos
 
 import (
+	"database/sql"
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/fmiskovic/go-starter/internal/core/configs"
 	"github.com/fmiskovic/go-starter/internal/core/domain"
 	"github.com/fmiskovic/go-starter/internal/core/domain/security"
 	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
 	"github.com/fmiskovic/go-starter/internal/utils/password"
 	"github.com/fmiskovic/go-starter/internal/utils/testx"
+	"github.com/fmiskovic/go-starter/internal/utils/token"
 	"github.com/google/uuid"
 	"github.com/matryer/is"
 )
@@ -120,6 +125,62 @@ func TestUserRepo_DeleteById(t *testing.T) {
 	}
 }
 
+func TestUserRepo_RestoreAndPurge(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewUserRepo(testDb.BunDb)
+	id := uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af03")
+
+	// restoring user that is not deleted should fail
+	err = repo.Restore(testDb.Ctx, id)
+	assert.True(errors.Is(err, sql.ErrNoRows))
+
+	err = repo.DeleteById(testDb.Ctx, id)
+	assert.NoErr(err)
+
+	// deleted user is listed only when asked for
+	p, err := repo.GetPage(testDb.Ctx, domain.Pageable{Size: 5}, user.Filter{})
+	assert.NoErr(err)
+	assert.Equal(p.TotalElements, 2)
+	p, err = repo.GetPage(testDb.Ctx, domain.Pageable{Size: 5}, user.Filter{IncludeDeleted: true})
+	assert.NoErr(err)
+	assert.Equal(p.TotalElements, 3)
+
+	err = repo.Restore(testDb.Ctx, id)
+	assert.NoErr(err)
+	u, err := repo.GetById(testDb.Ctx, id)
+	assert.NoErr(err)
+	assert.True(u.DeletedAt.IsZero())
+
+	err = repo.DeleteById(testDb.Ctx, id)
+	assert.NoErr(err)
+
+	// user deleted within the retention is kept
+	n, err := repo.Purge(testDb.Ctx, time.Now().Add(-time.Hour))
+	assert.NoErr(err)
+	assert.Equal(n, 0)
+
+	n, err = repo.Purge(testDb.Ctx, time.Now().Add(time.Hour))
+	assert.NoErr(err)
+	assert.Equal(n, 1)
+
+	// purged user can not be restored
+	err = repo.Restore(testDb.Ctx, id)
+	assert.True(errors.Is(err, sql.ErrNoRows))
+}
+
 func TestUserRepo_Create(t *testing.T) {
 	// skip in short mode
 	if testing.Short() {
@@ -223,9 +284,23 @@ func TestUserRepo_Update(t *testing.T) {
 				u, err := repo.GetById(testDb.Ctx, id)
 				assert.NoErr(err)
 				assert.Equal("updated1@fake.com", u.Email)
+				assert.Equal(u.Version, int64(2))
 			},
 			wantErr: nil,
 		},
+		{
+			name: "given stale version should return version mismatch error",
+			args: args{
+				u: user.New(user.Email("updated2@fake.com"), user.Version(5),
+					user.Id(uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af02"))),
+			},
+			verify: func(id uuid.UUID, t *testing.T) {
+				u, err := repo.GetById(testDb.Ctx, id)
+				assert.NoErr(err)
+				assert.Equal("john@doe.com", u.Email)
+			},
+			wantErr: apiErr.ErrVersionMismatch,
+		},
 		{
 			name:    "given nil user input should return error",
 			args:    args{u: nil},
@@ -281,6 +356,7 @@ func TestUserRepo_GetPage(t *testing.T) {
 	// setup test cases
 	type args struct {
 		pageable domain.Pageable
+		filter   user.Filter
 	}
 	tests := []struct {
 		name    string
@@ -311,11 +387,38 @@ func TestUserRepo_GetPage(t *testing.T) {
 			want:    "john@smith.com", // value from ./testdata/fixutes.yml
 			wantErr: nil,
 		},
+		{
+			name: "given free text query should return matching users",
+			args: args{
+				pageable: domain.Pageable{Size: 5},
+				filter:   user.Filter{Query: "PARK"},
+			},
+			want:    "em@parker.com",
+			wantErr: nil,
+		},
+		{
+			name: "given email and location filter should return matching users",
+			args: args{
+				pageable: domain.Pageable{Size: 5},
+				filter:   user.Filter{Email: "john", Location: "york"},
+			},
+			want:    "john@doe.com",
+			wantErr: nil,
+		},
+		{
+			name: "given role filter should return users assigned to the role",
+			args: args{
+				pageable: domain.Pageable{Size: 5},
+				filter:   user.Filter{Role: "ROLE_ADMIN"},
+			},
+			want:    "john@smith.com",
+			wantErr: nil,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			p, err := repo.GetPage(testDb.Ctx, tt.args.pageable)
+			p, err := repo.GetPage(testDb.Ctx, tt.args.pageable, tt.args.filter)
 
 			assert.Equal(tt.wantErr, err)
 			if err == nil {
@@ -327,6 +430,107 @@ func TestUserRepo_GetPage(t *testing.T) {
 	}
 }
 
+func TestUserRepo_GetPageFilter(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.NewRelaxed(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewUserRepo(testDb.BunDb)
+	pageable := domain.Pageable{Size: 5}
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		filter    user.Filter
+		wantCount int
+	}{
+		{name: "given no filter should return all users", filter: user.Filter{}, wantCount: 3},
+		{name: "given wildcard query should match it literally", filter: user.Filter{Query: "%"}, wantCount: 0},
+		{name: "given unknown role should return no users", filter: user.Filter{Role: "ROLE_NONE"}, wantCount: 0},
+		{name: "given created range around now should return all users", filter: user.Filter{CreatedFrom: now.Add(-time.Hour), CreatedTo: now.Add(time.Hour)}, wantCount: 3},
+		{name: "given created range in the future should return no users", filter: user.Filter{CreatedFrom: now.Add(time.Hour)}, wantCount: 0},
+		{name: "given updated range in the past should return no users", filter: user.Filter{UpdatedTo: now.Add(-time.Hour)}, wantCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := repo.GetPage(testDb.Ctx, pageable, tt.filter)
+			assert.NoErr(err)
+			assert.Equal(p.TotalElements, tt.wantCount)
+			assert.Equal(len(p.Elements), tt.wantCount)
+		})
+	}
+}
+
+func TestUserRepo_GetKeysetPage(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewUserRepo(testDb.BunDb)
+	sort := domain.NewSort(domain.NewOrder(domain.WithProperty("email"), domain.WithDirection(domain.ASC)))
+	emails := func(p domain.Page[user.User]) []string {
+		var res []string
+		for _, u := range p.Elements {
+			res = append(res, u.Email)
+		}
+		return res
+	}
+	cursor := func(s string) *domain.Cursor {
+		c, err := domain.DecodeCursor(s)
+		assert.NoErr(err)
+		return c
+	}
+
+	// values from ./testdata/fixture.yml
+	first, err := repo.GetPage(testDb.Ctx, domain.Pageable{Size: 2, Sort: sort, Keyset: true}, user.Filter{})
+	assert.NoErr(err)
+	assert.Equal(emails(first), []string{"em@parker.com", "john@doe.com"})
+	assert.Equal(first.TotalElements, 3)
+	assert.Equal(first.TotalPages, 2)
+	assert.Equal(first.PrevCursor, "")
+
+	next, err := repo.GetPage(testDb.Ctx, domain.Pageable{Size: 2, Sort: sort, Keyset: true, Cursor: cursor(first.NextCursor), SkipCount: true}, user.Filter{})
+	assert.NoErr(err)
+	assert.Equal(emails(next), []string{"john@smith.com"})
+	assert.Equal(next.TotalElements, -1)
+	assert.Equal(next.NextCursor, "")
+
+	prev, err := repo.GetPage(testDb.Ctx, domain.Pageable{Size: 2, Sort: sort, Keyset: true, Cursor: cursor(next.PrevCursor)}, user.Filter{})
+	assert.NoErr(err)
+	assert.Equal(emails(prev), emails(first))
+	assert.Equal(prev.PrevCursor, "")
+	assert.True(prev.NextCursor != "")
+
+	// cursor made for another sort is rejected
+	_, err = repo.GetPage(testDb.Ctx, domain.Pageable{Size: 2, Keyset: true, Cursor: cursor(first.NextCursor)}, user.Filter{})
+	assert.Equal(err, apiErr.ErrInvalidCursor)
+
+	// page without rows is rejected instead of reading the whole table
+	_, err = repo.GetPage(testDb.Ctx, domain.Pageable{Size: -1, Sort: sort, Keyset: true}, user.Filter{})
+	assert.Equal(err, apiErr.ErrInvalidPageSize)
+}
+
 func TestUserRepo_GetByUsername(t *testing.T) {
 	// skip in short mode
 	if testing.Short() {
@@ -387,7 +591,7 @@ func TestUserRepo_GetByUsername(t *testing.T) {
 	}
 }
 
-func TestUserRepo_ChangePassword(t *testing.T) {
+func TestUserRepo_GetByIdentity(t *testing.T) {
 	// skip in short mode
 	if testing.Short() {
 		return
@@ -404,48 +608,174 @@ func TestUserRepo_ChangePassword(t *testing.T) {
 
 	repo := NewUserRepo(testDb.BunDb)
 
+	t.Run("given linked identity should return user", func(t *testing.T) {
+		u, err := repo.GetByIdentity(testDb.Ctx, "stub", "stub-subject-1")
+		assert.NoErr(err)
+		assert.Equal(u.Email, "john@doe.com")
+	})
+
+	t.Run("given subject of another provider should return error", func(t *testing.T) {
+		_, err := repo.GetByIdentity(testDb.Ctx, "other", "stub-subject-1")
+		assert.True(errors.Is(err, sql.ErrNoRows))
+	})
+
+	t.Run("given added identity should return linked user", func(t *testing.T) {
+		identity := security.NewIdentity("stub", "stub-subject-2", "john@smith.com")
+		identity.UserID = uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01")
+		assert.NoErr(repo.AddIdentity(testDb.Ctx, identity))
+
+		u, err := repo.GetByIdentity(testDb.Ctx, "stub", "stub-subject-2")
+		assert.NoErr(err)
+		assert.Equal(u.ID, identity.UserID)
+		assert.Equal(len(u.Roles), 2)
+	})
+
+	t.Run("given identity linked to another user should return error", func(t *testing.T) {
+		identity := security.NewIdentity("stub", "stub-subject-1", "em@parker.com")
+		identity.UserID = uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af03")
+		assert.True(repo.AddIdentity(testDb.Ctx, identity) != nil)
+	})
+
+	t.Run("given user created with identity should return user", func(t *testing.T) {
+		u := user.New(
+			user.Email("jit@fake.com"),
+			user.Enabled(true),
+			user.Identities(security.NewIdentity("stub", "stub-subject-3", "jit@fake.com")),
+			user.Roles(security.NewRole(security.ROLE_USER)),
+		)
+		assert.NoErr(repo.Create(testDb.Ctx, u))
+
+		got, err := repo.GetByIdentity(testDb.Ctx, "stub", "stub-subject-3")
+		assert.NoErr(err)
+		assert.Equal(got.ID, u.ID)
+	})
+}
+
+func TestUserRepo_UpdatePassword(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewUserRepo(testDb.BunDb)
+
+	hasher := password.NewHasher(configs.NewAuthConfig().PasswordHashing)
+	pwdHash, err := hasher.Hash("Password1234!")
+	assert.NoErr(err)
+	nextHash, err := hasher.Hash("Password5678!")
+	assert.NoErr(err)
+
+	id := uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01")
+	oldHash := "$2a$14$/uuEnoIH.v4TKiIp8x1pze58QvmA.rKpmLLrQ0/8Y910SaTo8UR1K"
+
 	type args struct {
-		req *user.ChangePasswordRequest
+		id           uuid.UUID
+		passwordHash string
+		historySize  int
 	}
 	tests := []struct {
 		name    string
 		args    args
-		verify  func(t *testing.T, username string)
+		verify  func(t *testing.T)
 		wantErr bool
 	}{
 		{
-			name: "given valid creadentials should change passwod",
-			args: args{&user.ChangePasswordRequest{Username: "username1", OldPassword: "password1", NewPassword: "Password1234!"}},
-			verify: func(t *testing.T, username string) {
+			name: "given existing user should replace password and keep the old one in history",
+			args: args{id, pwdHash, 2},
+			verify: func(t *testing.T) {
 				u, err := repo.GetByUsername(testDb.Ctx, "username1")
 				assert.NoErr(err)
-				assert.True(password.CheckPasswordHash("Password1234!", u.Credentials.Password))
+				assert.Equal(u.Credentials.Password, pwdHash)
+
+				history, err := repo.GetPasswordHistory(testDb.Ctx, id, 5)
+				assert.NoErr(err)
+				assert.Equal(history, []string{oldHash})
 			},
 			wantErr: false,
 		},
 		{
-			name:    "given invalid creadentials should return error",
-			args:    args{&user.ChangePasswordRequest{Username: "username1", OldPassword: "password11", NewPassword: "Password1234!"}},
-			verify:  func(t *testing.T, username string) {},
-			wantErr: true,
+			name: "given smaller history size should prune the oldest passwords",
+			args: args{id, nextHash, 1},
+			verify: func(t *testing.T) {
+				history, err := repo.GetPasswordHistory(testDb.Ctx, id, 5)
+				assert.NoErr(err)
+				assert.Equal(history, []string{pwdHash})
+			},
+			wantErr: false,
 		},
 		{
-			name:    "given invalid username should return error",
-			args:    args{&user.ChangePasswordRequest{Username: "username11", OldPassword: "password1", NewPassword: "Password1234!"}},
-			verify:  func(t *testing.T, username string) {},
+			name: "given zero history size should clear history",
+			args: args{id, pwdHash, 0},
+			verify: func(t *testing.T) {
+				history, err := repo.GetPasswordHistory(testDb.Ctx, id, 5)
+				assert.NoErr(err)
+				assert.Equal(len(history), 0)
+			},
+			wantErr: false,
+		},
+		{
+			name:    "given non-existing user should return error",
+			args:    args{uuid.MustParse("333cea28-b2b0-4051-9eb6-9a99e451af01"), pwdHash, 2},
+			verify:  func(t *testing.T) {},
 			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := repo.ChangePassword(testDb.Ctx, tt.args.req); (err != nil) != tt.wantErr {
-				t.Errorf("UserRepo.ChangePassword() error = %v, wantErr %v", err, tt.wantErr)
+			if err := repo.UpdatePassword(testDb.Ctx, tt.args.id, tt.args.passwordHash, tt.args.historySize); (err != nil) != tt.wantErr {
+				t.Errorf("UserRepo.UpdatePassword() error = %v, wantErr %v", err, tt.wantErr)
 			}
-			tt.verify(t, tt.args.req.Username)
+			tt.verify(t)
 		})
 	}
 }
 
+func TestUserRepo_RehashPassword(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewUserRepo(testDb.BunDb)
+
+	id := uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01")
+	oldHash := "$2a$14$/uuEnoIH.v4TKiIp8x1pze58QvmA.rKpmLLrQ0/8Y910SaTo8UR1K"
+
+	// stale hash does not overwrite password changed meanwhile
+	assert.NoErr(repo.RehashPassword(testDb.Ctx, id, "stale-hash", "new-hash"))
+	u, err := repo.GetById(testDb.Ctx, id)
+	assert.NoErr(err)
+	assert.Equal(u.Credentials.Password, oldHash)
+
+	assert.NoErr(repo.RehashPassword(testDb.Ctx, id, oldHash, "new-hash"))
+	u, err = repo.GetById(testDb.Ctx, id)
+	assert.NoErr(err)
+	assert.Equal(u.Credentials.Password, "new-hash")
+
+	// password history is not changed
+	history, err := repo.GetPasswordHistory(testDb.Ctx, id, 5)
+	assert.NoErr(err)
+	assert.Equal(len(history), 0)
+}
+
 func TestUserRepo_AddRoles(t *testing.T) {
 	// skip in short mode
 	if testing.Short() {
@@ -486,6 +816,22 @@ func TestUserRepo_AddRoles(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "given already assigned roles should not return an error",
+			args: args{
+				roleNames: []string{security.ROLE_ADMIN},
+				id:        uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01"),
+			},
+			wantErr: false,
+		},
+		{
+			name: "given role that is not in the catalog should return error",
+			args: args{
+				roleNames: []string{security.ROLE_USER, "ROLE_ADMNI"},
+				id:        uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af02"),
+			},
+			wantErr: true,
+		},
 		{
 			name: "given empty roles should not return an error",
 			args: args{
@@ -613,3 +959,156 @@ func TestUserRepo_EnableDisable(t *testing.T) {
 		})
 	}
 }
+
+func TestUserRepo_GetByResetToken(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewUserRepo(testDb.BunDb)
+
+	tests := []struct {
+		name      string
+		tokenHash string
+		wantId    uuid.UUID
+		wantErr   error
+	}{
+		{
+			name:      "given valid token should return user",
+			tokenHash: token.Hash("reset-token"),
+			wantId:    uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01"),
+		},
+		{
+			name:      "given expired token should return error",
+			tokenHash: token.Hash("expired-reset-token"),
+			wantErr:   apiErr.ErrExpiredToken,
+		},
+		{
+			name:      "given unknown token should return error",
+			tokenHash: token.Hash("unknown-token"),
+			wantErr:   apiErr.ErrInvalidToken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := repo.GetByResetToken(testDb.Ctx, tt.tokenHash)
+			if tt.wantErr != nil {
+				assert.True(errors.Is(err, tt.wantErr))
+				return
+			}
+			assert.NoErr(err)
+			assert.Equal(u.ID, tt.wantId)
+			assert.Equal(u.Credentials.Username, "username1")
+		})
+	}
+
+	// token is not consumed
+	_, err = repo.ResetPassword(testDb.Ctx, token.Hash("reset-token"), "hash", 0)
+	assert.NoErr(err)
+}
+
+func TestUserRepo_ResetPassword(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewUserRepo(testDb.BunDb)
+
+	newHash, err := password.NewHasher(configs.NewAuthConfig().PasswordHashing).Hash("Password1234!")
+	assert.NoErr(err)
+
+	tests := []struct {
+		name      string
+		tokenHash string
+		wantId    uuid.UUID
+		wantErr   error
+	}{
+		{
+			name:      "given valid token should reset password",
+			tokenHash: token.Hash("reset-token"),
+			wantId:    uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01"),
+		},
+		{
+			name:      "given already used token should return error",
+			tokenHash: token.Hash("reset-token"),
+			wantErr:   apiErr.ErrInvalidToken,
+		},
+		{
+			name:      "given expired token should return error",
+			tokenHash: token.Hash("expired-reset-token"),
+			wantErr:   apiErr.ErrExpiredToken,
+		},
+		{
+			name:      "given unknown token should return error",
+			tokenHash: token.Hash("unknown-token"),
+			wantErr:   apiErr.ErrInvalidToken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := repo.ResetPassword(testDb.Ctx, tt.tokenHash, newHash, 0)
+			if tt.wantErr != nil {
+				assert.True(errors.Is(err, tt.wantErr))
+				return
+			}
+			assert.NoErr(err)
+			assert.Equal(id, tt.wantId)
+
+			u, err := repo.GetByUsername(testDb.Ctx, "username1")
+			assert.NoErr(err)
+			assert.Equal(u.Credentials.Password, newHash)
+		})
+	}
+}
+
+func TestUserRepo_SavePasswordReset(t *testing.T) {
+	// skip in short mode
+	if testing.Short() {
+		return
+	}
+
+	assert := is.New(t)
+
+	// setup db
+	testDb, err := testx.SetUpDb()
+	if err != nil {
+		t.Errorf("failed to run test db: %v", err)
+	}
+	defer testDb.Shutdown()
+
+	repo := NewUserRepo(testDb.BunDb)
+	id := uuid.MustParse("220cea28-b2b0-4051-9eb6-9a99e451af01")
+
+	r := security.NewPasswordReset(id, token.Hash("new-reset-token"), time.Now().Add(time.Hour))
+	assert.NoErr(repo.SavePasswordReset(testDb.Ctx, r))
+
+	// previously issued token is discarded
+	_, err = repo.ResetPassword(testDb.Ctx, token.Hash("reset-token"), "hash", 0)
+	assert.True(errors.Is(err, apiErr.ErrInvalidToken))
+
+	got, err := repo.ResetPassword(testDb.Ctx, token.Hash("new-reset-token"), "hash", 0)
+	assert.NoErr(err)
+	assert.Equal(got, id)
+
+	assert.True(errors.Is(repo.SavePasswordReset(testDb.Ctx, nil), ErrNilEntity))
+}
@@ -1,18 +1,83 @@
 package configs
 
 import (
+	"runtime"
 	"time"
 )
 
 // Config holds auth related configuration
 type AuthConfig struct {
-	TokenExp time.Duration // Token expiration time
+	TokenExp time.Duration // Access token expiration time
 	Secret   string        // Signing token secret
 	Scopes   []string      // List of scopes required to access endpoint (default: none required)
+
+	Keys        []SigningKey // Asymmetric keys used instead of Secret, all of them are accepted for verification
+	ActiveKeyID string       // ID of the key used for signing new tokens (default: first key with private part)
+
+	RefreshTokenExp time.Duration // Refresh token expiration time
+	ConfirmationExp time.Duration // Email confirmation code expiration time
+
+	PasswordResetExp time.Duration // Password reset token expiration time
+	PasswordResetURL string        // Page the reset link points to, token is appended as query param (default: token only is sent)
+
+	PasswordPolicy  PasswordPolicy  // Rules new passwords are checked against
+	PasswordHashing PasswordHashing // Algorithm and parameters of new password hashes
+
+	MfaIssuer       string        // Issuer shown by authenticator apps
+	MfaChallengeExp time.Duration // Expiration of the token exchanged for access token together with the second factor
+
+	LockoutThreshold   int           // Failed sign in attempts per username before it is temporarily locked (0 disables)
+	LockoutIPThreshold int           // Failed sign in attempts per client IP before it is temporarily locked (0 disables)
+	LockoutDuration    time.Duration // Duration of the first lockout, every further lockout lasts twice as long
+	LockoutWindow      time.Duration // Failed attempts are forgotten when there was no new failure within the window
+
+	ApiKeyExp time.Duration // Default API key expiration, used when the key is created without one
+
+	SessionExp time.Duration // UI session expiration, user has to sign in again when it expires
+
+	DeletedUserRetention time.Duration // Soft deleted users can be restored until they are purged after the retention
+
+	OidcProviders []OidcProvider // External OpenID Connect identity providers users can sign in with
+
+	Ldap LdapConfig // User directory used for signing in when LDAP authenticator is selected
 }
 
 func NewAuthConfig(opts ...AuthConfigOptions) AuthConfig {
-	cfg := &AuthConfig{Secret: "secret"}
+	cfg := &AuthConfig{
+		TokenExp:         15 * time.Minute,
+		Secret:           "secret",
+		RefreshTokenExp:  30 * 24 * time.Hour,
+		ConfirmationExp:  24 * time.Hour,
+		PasswordResetExp: time.Hour,
+		MfaIssuer:        "go-starter",
+		MfaChallengeExp:  5 * time.Minute,
+
+		PasswordPolicy: PasswordPolicy{
+			MinLength:        8,
+			MaxLength:        72,
+			DisallowIdentity: true,
+		},
+		PasswordHashing: PasswordHashing{
+			Algorithm:     HashArgon2id,
+			BcryptCost:    14,
+			Argon2Memory:  64 * 1024,
+			Argon2Time:    3,
+			Argon2Threads: 4,
+			Concurrency:   runtime.NumCPU(),
+			QueueTimeout:  5 * time.Second,
+		},
+
+		LockoutThreshold:   5,
+		LockoutIPThreshold: 20,
+		LockoutDuration:    15 * time.Minute,
+		LockoutWindow:      time.Hour,
+
+		ApiKeyExp: 90 * 24 * time.Hour,
+
+		SessionExp: 8 * time.Hour,
+
+		DeletedUserRetention: 30 * 24 * time.Hour,
+	}
 	for _, opt := range opts {
 		opt(cfg)
 	}
@@ -38,3 +103,117 @@ func Scopes(s []string) AuthConfigOptions {
 		ac.Scopes = s
 	}
 }
+
+func SigningKeys(keys ...SigningKey) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.Keys = keys
+	}
+}
+
+func ActiveKeyID(id string) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.ActiveKeyID = id
+	}
+}
+
+func RefreshTokenExp(exp time.Duration) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.RefreshTokenExp = exp
+	}
+}
+
+func ConfirmationExp(exp time.Duration) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.ConfirmationExp = exp
+	}
+}
+
+func PasswordResetExp(exp time.Duration) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.PasswordResetExp = exp
+	}
+}
+
+func PasswordResetURL(url string) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.PasswordResetURL = url
+	}
+}
+
+func Passwords(p PasswordPolicy) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.PasswordPolicy = p
+	}
+}
+
+func PasswordHashes(h PasswordHashing) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.PasswordHashing = h
+	}
+}
+
+func MfaIssuer(issuer string) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.MfaIssuer = issuer
+	}
+}
+
+func MfaChallengeExp(exp time.Duration) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.MfaChallengeExp = exp
+	}
+}
+
+func LockoutThreshold(n int) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.LockoutThreshold = n
+	}
+}
+
+func LockoutIPThreshold(n int) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.LockoutIPThreshold = n
+	}
+}
+
+func LockoutDuration(d time.Duration) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.LockoutDuration = d
+	}
+}
+
+func LockoutWindow(d time.Duration) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.LockoutWindow = d
+	}
+}
+
+func ApiKeyExp(exp time.Duration) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.ApiKeyExp = exp
+	}
+}
+
+func SessionExp(exp time.Duration) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.SessionExp = exp
+	}
+}
+
+func DeletedUserRetention(d time.Duration) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.DeletedUserRetention = d
+	}
+}
+
+func OidcProviders(providers ...OidcProvider) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.OidcProviders = providers
+	}
+}
+
+func Ldap(cfg LdapConfig) AuthConfigOptions {
+	return func(ac *AuthConfig) {
+		ac.Ldap = cfg
+	}
+}
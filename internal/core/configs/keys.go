@@ -0,0 +1,124 @@
+package configs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	ErrInvalidPem     = errors.New("failed to decode pem block")
+	ErrUnsupportedKey = errors.New("unsupported key type, only RSA, ECDSA and Ed25519 keys are supported")
+	ErrNoSigningKey   = errors.New("no key available for signing tokens")
+)
+
+// SigningKey holds asymmetric key used for signing and verifying tokens.
+// Key without private part can only be used for verification, e.g. while rotating keys.
+type SigningKey struct {
+	ID         string           // Key ID, written into token "kid" header
+	Algorithm  string           // Signing algorithm: RS256, ES256, ES384, ES512 or EdDSA
+	PrivateKey crypto.Signer    // Private key, nil for verification only keys
+	PublicKey  crypto.PublicKey // Public key
+}
+
+// CanSign returns true if key holds private key.
+func (k SigningKey) CanSign() bool {
+	return k.PrivateKey != nil
+}
+
+// ActiveSigningKey returns the key tokens are signed with, it is the key with activeID
+// or the first key that can sign if activeID is empty. ErrNoSigningKey is returned if there is no such key.
+func ActiveSigningKey(keys []SigningKey, activeID string) (*SigningKey, error) {
+	for i := range keys {
+		k := keys[i]
+		if !k.CanSign() {
+			continue
+		}
+		if activeID == "" || activeID == k.ID {
+			return &k, nil
+		}
+	}
+	return nil, ErrNoSigningKey
+}
+
+// LoadSigningKey reads PEM encoded private or public key from the file.
+func LoadSigningKey(id string, path string) (SigningKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	return ParseSigningKey(id, b)
+}
+
+// ParseSigningKey parses PEM encoded private or public key.
+// Supported are PKCS#8, PKCS#1 and SEC 1 private keys and PKIX and PKCS#1 public keys.
+func ParseSigningKey(id string, pemBytes []byte) (SigningKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return SigningKey{}, ErrInvalidPem
+	}
+
+	var (
+		key any
+		err error
+	)
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return SigningKey{}, fmt.Errorf("%w: pem block type %q", ErrUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	return NewSigningKey(id, key)
+}
+
+// NewSigningKey creates SigningKey from private or public key, inferring algorithm from the key type.
+func NewSigningKey(id string, key any) (SigningKey, error) {
+	k := SigningKey{ID: id}
+
+	if signer, ok := key.(crypto.Signer); ok {
+		k.PrivateKey = signer
+		key = signer.Public()
+	}
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		k.Algorithm = "RS256"
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			k.Algorithm = "ES256"
+		case elliptic.P384():
+			k.Algorithm = "ES384"
+		case elliptic.P521():
+			k.Algorithm = "ES512"
+		default:
+			return SigningKey{}, ErrUnsupportedKey
+		}
+	case ed25519.PublicKey:
+		k.Algorithm = "EdDSA"
+	default:
+		return SigningKey{}, ErrUnsupportedKey
+	}
+
+	k.PublicKey = key
+	return k, nil
+}
@@ -0,0 +1,170 @@
+package configs
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+)
+
+func TestParseSigningKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDer, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		pem     []byte
+		alg     string
+		canSign bool
+		wantErr error
+	}{
+		{
+			name:    "given pkcs1 rsa private key should return RS256 key",
+			pem:     encodePem(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)),
+			alg:     "RS256",
+			canSign: true,
+		},
+		{
+			name:    "given pkix rsa public key should return verification only key",
+			pem:     encodePem(t, "PUBLIC KEY", marshalPKIX(t, &rsaKey.PublicKey)),
+			alg:     "RS256",
+			canSign: false,
+		},
+		{
+			name:    "given sec1 P-384 private key should return ES384 key",
+			pem:     encodePem(t, "EC PRIVATE KEY", ecDer),
+			alg:     "ES384",
+			canSign: true,
+		},
+		{
+			name:    "given pkcs8 ed25519 private key should return EdDSA key",
+			pem:     encodePem(t, "PRIVATE KEY", marshalPKCS8(t, edKey)),
+			alg:     "EdDSA",
+			canSign: true,
+		},
+		{
+			name:    "given invalid pem should return error",
+			pem:     []byte("not a pem"),
+			wantErr: ErrInvalidPem,
+		},
+		{
+			name:    "given unsupported block type should return error",
+			pem:     encodePem(t, "CERTIFICATE REQUEST", []byte("data")),
+			wantErr: ErrUnsupportedKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSigningKey("kid", tt.pem)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseSigningKey() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSigningKey() error = %v", err)
+			}
+			if got.ID != "kid" {
+				t.Errorf("ParseSigningKey() id = %s, want kid", got.ID)
+			}
+			if got.Algorithm != tt.alg {
+				t.Errorf("ParseSigningKey() algorithm = %s, want %s", got.Algorithm, tt.alg)
+			}
+			if got.CanSign() != tt.canSign {
+				t.Errorf("ParseSigningKey() can sign = %v, want %v", got.CanSign(), tt.canSign)
+			}
+			if got.PublicKey == nil {
+				t.Error("ParseSigningKey() public key is nil")
+			}
+		})
+	}
+}
+
+func TestActiveSigningKey(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := NewSigningKey("private", edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := NewSigningKey("public", edKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewSigningKey("other", edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		keys     []SigningKey
+		activeID string
+		want     string
+		wantErr  error
+	}{
+		{name: "given no active key should return first private key", keys: []SigningKey{public, private, other}, want: "private"},
+		{name: "given active key should return it", keys: []SigningKey{public, private, other}, activeID: "other", want: "other"},
+		{name: "given unknown active key should return error", keys: []SigningKey{private}, activeID: "unknown", wantErr: ErrNoSigningKey},
+		{name: "given public active key should return error", keys: []SigningKey{public, private}, activeID: "public", wantErr: ErrNoSigningKey},
+		{name: "given only public keys should return error", keys: []SigningKey{public}, wantErr: ErrNoSigningKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ActiveSigningKey(tt.keys, tt.activeID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ActiveSigningKey() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got.ID != tt.want {
+				t.Errorf("ActiveSigningKey() id = %s, want %s", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func encodePem(t *testing.T, typ string, der []byte) []byte {
+	t.Helper()
+	return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+}
+
+func marshalPKIX(t *testing.T, key any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func marshalPKCS8(t *testing.T, key any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
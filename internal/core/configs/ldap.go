@@ -0,0 +1,22 @@
+package configs
+
+import "time"
+
+// LdapConfig holds configuration of LDAP or Active Directory user directory used for signing in.
+type LdapConfig struct {
+	URL          string        // Directory url, e.g. ldap://ldap.example.com:389 or ldaps://ldap.example.com:636
+	StartTLS     bool          // Upgrade ldap:// connection to TLS before binding
+	BindDN       string        // Service account used for searching users, search is anonymous if empty
+	BindPassword string        // Service account password
+	BaseDN       string        // Users are searched within the subtree, e.g. ou=people,dc=example,dc=com
+	Timeout      time.Duration // Connection and request timeout (default: 10 seconds)
+
+	UserFilter        string // Filter with %s placeholder for escaped username (default: (&(objectClass=person)(uid=%s)), AD: (sAMAccountName=%s))
+	UsernameAttribute string // Attribute holding username (default: uid, AD: sAMAccountName)
+	EmailAttribute    string // Attribute holding email address (default: mail)
+	NameAttribute     string // Attribute holding full name (default: cn)
+	GroupAttribute    string // Attribute listing DNs of user groups (default: memberOf)
+
+	GroupRoles   map[string][]string // Roles granted to members of the group, group is matched by its DN or CN
+	DefaultRoles []string            // Roles of users provisioned on the first sign in (default: ROLE_USER)
+}
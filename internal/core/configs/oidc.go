@@ -0,0 +1,12 @@
+package configs
+
+// OidcProvider holds configuration of external OpenID Connect identity provider.
+type OidcProvider struct {
+	Name         string   // Provider name used in login and callback routes, e.g. "google"
+	IssuerURL    string   // Issuer, provider metadata is discovered from IssuerURL/.well-known/openid-configuration
+	ClientID     string   // Client ID registered with the provider
+	ClientSecret string   // Client secret, empty for public clients
+	RedirectURL  string   // Callback registered with the provider, e.g. https://example.com/auth/oidc/google/callback
+	Scopes       []string // Requested scopes, "openid" is always requested (default: openid email profile)
+	DefaultRoles []string // Roles of users provisioned on the first sign in (default: ROLE_USER)
+}
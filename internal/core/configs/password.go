@@ -0,0 +1,40 @@
+package configs
+
+import "time"
+
+// PasswordPolicy holds rules new passwords are checked against when user is created or changes its password.
+type PasswordPolicy struct {
+	MinLength int // Minimal number of characters (default: 8)
+	MaxLength int // Maximal number of bytes, bcrypt rejects anything longer (default: 72, 0 disables)
+
+	RequireUpper  bool // Password must contain an uppercase letter
+	RequireLower  bool // Password must contain a lowercase letter
+	RequireDigit  bool // Password must contain a digit
+	RequireSymbol bool // Password must contain a character that is not a letter nor a digit
+
+	DisallowIdentity bool // Password must not contain username nor email of the user (default: true)
+	History          int  // Number of last passwords, the current one included, that can not be reused (0 disables)
+
+	BreachedList string // File with SHA-1 hashes of passwords known from data breaches, one per line (default: check disabled)
+}
+
+// Password hashing algorithms.
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+)
+
+// PasswordHashing holds algorithm and parameters new password hashes are made with.
+// Hashes made with other algorithm or parameters are still verified and replaced on the next sign in.
+type PasswordHashing struct {
+	Algorithm string // HashBcrypt or HashArgon2id (default: argon2id)
+
+	BcryptCost int // Bcrypt cost, 4 to 31 (default: 14)
+
+	Argon2Memory  uint32 // Argon2id memory in KiB (default: 65536)
+	Argon2Time    uint32 // Argon2id number of passes over the memory (default: 3)
+	Argon2Threads uint8  // Argon2id degree of parallelism (default: 4)
+
+	Concurrency  int           // Maximal number of passwords hashed or verified at the same time (default: number of CPUs, 0 disables the limit)
+	QueueTimeout time.Duration // How long hashing waits for a free slot before the request is rejected as busy (default: 5 seconds)
+}
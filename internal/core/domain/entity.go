@@ -11,11 +11,29 @@ type Entity struct {
 	ID        uuid.UUID `bun:",pk,autoincrement"`
 	CreatedAt time.Time `bun:"created_at,notnull,default:current_timestamp"`
 	UpdatedAt time.Time `bun:"updated_at,notnull,default:current_timestamp"`
+	Version   int64     `bun:"version,notnull,default:1"` // Incremented on every update, used for optimistic locking
+}
+
+// SoftDelete is embedded into entities that are kept for a retention period after they are deleted.
+// Bun excludes soft deleted rows from queries of the entity unless WhereDeleted or WhereAllWithDeleted is used.
+type SoftDelete struct {
+	DeletedAt time.Time `bun:",soft_delete,nullzero"`
 }
 
 // Page is generic struct that represents response made by page request.
+// TotalPages and TotalElements are -1 if counting was skipped, cursors are set only in keyset mode.
 type Page[T any] struct {
 	TotalPages    int
 	TotalElements int
 	Elements      []T
+	NextCursor    string `json:",omitempty"`
+	PrevCursor    string `json:",omitempty"`
+}
+
+// TotalPages computes number of pages needed for count elements.
+func TotalPages(count int, size int) int {
+	if count <= 0 || size <= 0 {
+		return 0
+	}
+	return (count + size - 1) / size
 }
@@ -1,6 +1,13 @@
 package domain
 
-import "fmt"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
 
 // Direction can be ASC, DESC, ASC_NULLS_FIRST, DESC_NULLS_FIRST, ASC_NULLS_LAST or DESC_NULLS_LAST.
 type Direction string
@@ -14,6 +21,20 @@ const (
 	DESC_NULLS_LAST  Direction = "DESC NULLS LAST"
 )
 
+// directions are all valid directions, it is used for strict parsing of client input.
+var directions = []Direction{ASC, DESC, ASC_NULLS_FIRST, DESC_NULLS_FIRST, ASC_NULLS_LAST, DESC_NULLS_LAST}
+
+// ParseDirection parses case-insensitive direction, e.g. "desc" or "asc nulls last".
+func ParseDirection(s string) (Direction, error) {
+	d := Direction(strings.ToUpper(strings.Join(strings.Fields(s), " ")))
+	for _, valid := range directions {
+		if d == valid {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf("unknown sort direction %s", s)
+}
+
 // Order represent single sort instruction.
 type Order struct {
 	Property  string
@@ -54,11 +75,84 @@ func NewSort(order ...*Order) Sort {
 	return Sort{Orders: order}
 }
 
+// SortFields is registry of the fields entity can be sorted on, it maps public names of the fields to columns.
+type SortFields map[string]string
+
+// Names returns alphabetically sorted public names of the fields.
+func (f SortFields) Names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseSort parses comma separated orders in the form of "field [direction]", e.g. "email, createdAt desc".
+// Fields are resolved to columns and direction is ASC if omitted, unknown field or direction is an error.
+func (f SortFields) ParseSort(s string) (Sort, error) {
+	if strings.TrimSpace(s) == "" {
+		return NewSort(), nil
+	}
+
+	var orders []*Order
+	for _, o := range strings.Split(s, ",") {
+		name, dir, _ := strings.Cut(strings.TrimSpace(o), " ")
+		column, ok := f[name]
+		if !ok {
+			return Sort{}, fmt.Errorf("unknown sort field %s, allowed fields are %s", name, strings.Join(f.Names(), ", "))
+		}
+
+		direction := ASC
+		if strings.TrimSpace(dir) != "" {
+			var err error
+			if direction, err = ParseDirection(dir); err != nil {
+				return Sort{}, err
+			}
+		}
+		orders = append(orders, NewOrder(WithProperty(column), WithDirection(direction)))
+	}
+	return NewSort(orders...), nil
+}
+
 // Pageable represents the pagination request parameters.
+// Page is read either at Offset or, in keyset mode, next to the Cursor which does not degrade on large tables.
 type Pageable struct {
-	Size   int
-	Offset int
-	Sort   Sort
+	Size      int
+	Offset    int
+	Sort      Sort
+	Keyset    bool    // read page by cursor instead of offset, the first page is read without Cursor
+	Cursor    *Cursor // position the keyset page is read from
+	SkipCount bool    // do not count total elements, e.g. when it is too expensive
+}
+
+// Cursor is position in keyset ordered rows, it holds sort key of the row the page is read after or before.
+type Cursor struct {
+	Sort   []string `json:"s"` // stringified orders the cursor is made for
+	Values []any    `json:"v"` // values of the sort properties followed by id
+	Before bool     `json:"b"` // page is read before the row instead of after it
+}
+
+// Encode encodes cursor into opaque string for clients.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor decodes cursor encoded by Cursor.Encode.
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	c := new(Cursor)
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	if len(c.Values) == 0 || len(c.Values) != len(c.Sort)+1 {
+		return nil, errors.New("cursor has no sort key")
+	}
+	return c, nil
 }
 
 // StringifyOrders travers sort orders into a slice of strings in the following of "prop1 ASC, prop2 DESC".
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortFields_ParseSort(t *testing.T) {
+	fields := SortFields{"createdAt": "created_at", "email": "email"}
+
+	tests := []struct {
+		name    string
+		sort    string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "given empty sort should return no orders",
+			sort: "",
+			want: nil,
+		},
+		{
+			name: "given fields should resolve columns and directions",
+			sort: "email, createdAt desc nulls last",
+			want: []string{"email ASC", "created_at DESC NULLS LAST"},
+		},
+		{
+			name:    "given unknown field should list allowed fields",
+			sort:    "password_hash",
+			wantErr: "unknown sort field password_hash, allowed fields are createdAt, email",
+		},
+		{
+			name:    "given column name instead of field should return error",
+			sort:    "created_at DESC",
+			wantErr: "unknown sort field created_at, allowed fields are createdAt, email",
+		},
+		{
+			name:    "given SQL fragment as direction should return error",
+			sort:    "email ASC; DROP TABLE users",
+			wantErr: "unknown sort direction ASC; DROP TABLE users",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fields.ParseSort(tt.sort)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("ParseSort() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSort() unexpected error = %v", err)
+			}
+			if orders := StringifyOrders(got); !reflect.DeepEqual(orders, tt.want) {
+				t.Errorf("ParseSort() = %v, want %v", orders, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursor_Encode(t *testing.T) {
+	c := Cursor{Sort: []string{"email ASC"}, Values: []any{"john@doe.com", "220cea28-b2b0-4051-9eb6-9a99e451af01"}, Before: true}
+
+	got, err := DecodeCursor(c.Encode())
+	if err != nil {
+		t.Fatalf("DecodeCursor() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(*got, c) {
+		t.Errorf("DecodeCursor() = %v, want %v", *got, c)
+	}
+
+	for _, s := range []string{"", "not base64!", Cursor{Sort: []string{"email ASC"}, Values: []any{"id"}}.Encode()} {
+		if _, err := DecodeCursor(s); err == nil {
+			t.Errorf("DecodeCursor(%q) expected error", s)
+		}
+	}
+}
+
+func TestTotalPages(t *testing.T) {
+	tests := []struct {
+		count, size, want int
+	}{
+		{count: 0, size: 10, want: 0},
+		{count: 10, size: 10, want: 1},
+		{count: 11, size: 10, want: 2},
+		{count: 3, size: 0, want: 0},
+	}
+	for _, tt := range tests {
+		if got := TotalPages(tt.count, tt.size); got != tt.want {
+			t.Errorf("TotalPages(%d, %d) = %d, want %d", tt.count, tt.size, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package security
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// ApiKeyPrefix starts every API key, it tells API keys apart from JWTs in Authorization header.
+const ApiKeyPrefix = "gs_"
+
+// ApiKey represents personal access token used by machine clients instead of signing in.
+// Only the hash of the key is kept, Prefix is the beginning of the key shown to identify it.
+type ApiKey struct {
+	bun.BaseModel `bun:"table:api_keys,alias:ak"`
+
+	domain.Entity
+	UserID     uuid.UUID `bun:"user_id,notnull"`
+	Name       string    `bun:"name,notnull"`
+	Prefix     string    `bun:"prefix,notnull"`
+	KeyHash    string    `bun:"key_hash,notnull,unique"`
+	Scopes     []string  `bun:"scopes,array"`
+	ExpiresAt  time.Time `bun:"expires_at,notnull"`
+	LastUsedAt time.Time `bun:"last_used_at,nullzero"`
+}
+
+func NewApiKey(userID uuid.UUID, name string, prefix string, keyHash string, scopes []string, expiresAt time.Time) *ApiKey {
+	// recover in case uuid.New() panic
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Warn("Recovered in security.NewApiKey() when uuid.New() panic", "panic", r)
+		}
+	}()
+
+	now := time.Now()
+	return &ApiKey{
+		Entity: domain.Entity{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   keyHash,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// IsExpired returns true if the key is not accepted anymore.
+func (k ApiKey) IsExpired() bool {
+	return time.Now().After(k.ExpiresAt)
+}
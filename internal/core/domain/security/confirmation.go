@@ -0,0 +1,54 @@
+package security
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// EmailConfirmation holds hashed code sent to the user to confirm its email address.
+type EmailConfirmation struct {
+	bun.BaseModel `bun:"table:email_confirmations,alias:ec"`
+
+	domain.Entity
+	UserID    uuid.UUID `bun:"user_id,notnull"`
+	CodeHash  string    `bun:"code_hash,notnull,unique"`
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+	Email     string    `bun:"email,nullzero"` // New email address of the user, empty when confirming address user signed up with
+}
+
+func NewEmailConfirmation(userID uuid.UUID, codeHash string, expiresAt time.Time) *EmailConfirmation {
+	// recover in case uuid.New() panic
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Warn("Recovered in security.NewEmailConfirmation() when uuid.New() panic", "panic", r)
+		}
+	}()
+
+	now := time.Now()
+	return &EmailConfirmation{
+		Entity: domain.Entity{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		UserID:    userID,
+		CodeHash:  codeHash,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// NewEmailChange creates confirmation of the new email address, user email is changed once it is confirmed.
+func NewEmailChange(userID uuid.UUID, email string, codeHash string, expiresAt time.Time) *EmailConfirmation {
+	c := NewEmailConfirmation(userID, codeHash, expiresAt)
+	c.Email = email
+	return c
+}
+
+// IsExpired returns true if confirmation code is not valid anymore.
+func (c EmailConfirmation) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
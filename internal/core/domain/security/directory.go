@@ -0,0 +1,9 @@
+package security
+
+// DirectoryEntry holds attributes of the user authenticated by external user directory.
+type DirectoryEntry struct {
+	Username string
+	Email    string
+	FullName string
+	Groups   []string // DNs of the groups user is member of
+}
@@ -0,0 +1,77 @@
+package security
+
+import "time"
+
+// RoleDto represents role DTO.
+type RoleDto struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// PermissionDto represents permission DTO.
+type PermissionDto struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ApiKeyDto represents API key DTO, the key itself is never returned after it is created.
+type ApiKeyDto struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+// CreateApiKeyResponse holds newly created API key together with the plain key, it is returned only once.
+type CreateApiKeyResponse struct {
+	ApiKeyDto
+	Key string `json:"key"`
+}
+
+// ConvertToRoleDto converts Role entity into a Role DTO.
+func ConvertToRoleDto(r *Role) *RoleDto {
+	perms := make([]string, len(r.Permissions))
+	for i, p := range r.Permissions {
+		perms[i] = p.Name
+	}
+	return &RoleDto{
+		ID:          r.ID.String(),
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: perms,
+	}
+}
+
+// ConvertToPermissionDto converts Permission entity into a Permission DTO.
+func ConvertToPermissionDto(p *Permission) *PermissionDto {
+	return &PermissionDto{
+		ID:          p.ID.String(),
+		Name:        p.Name,
+		Description: p.Description,
+	}
+}
+
+// ConvertToApiKeyDto converts ApiKey entity into a ApiKey DTO.
+func ConvertToApiKeyDto(k *ApiKey) *ApiKeyDto {
+	dto := &ApiKeyDto{
+		ID:        k.ID.String(),
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    k.Scopes,
+		CreatedAt: k.CreatedAt,
+		ExpiresAt: k.ExpiresAt,
+	}
+	if dto.Scopes == nil {
+		dto.Scopes = []string{}
+	}
+	if !k.LastUsedAt.IsZero() {
+		dto.LastUsedAt = &k.LastUsedAt
+	}
+	return dto
+}
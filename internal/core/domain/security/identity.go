@@ -0,0 +1,52 @@
+package security
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Identity links user to the account of external OpenID Connect identity provider.
+// Subject is the provider's stable user identifier, email is kept only for information.
+type Identity struct {
+	bun.BaseModel `bun:"table:identities,alias:idn"`
+
+	domain.Entity
+	UserID   uuid.UUID `bun:"user_id,notnull"`
+	Provider string    `bun:"provider,notnull"`
+	Subject  string    `bun:"subject,notnull"`
+	Email    string    `bun:"email,nullzero"`
+}
+
+func NewIdentity(provider string, subject string, email string) *Identity {
+	// recover in case uuid.New() panic
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Warn("Recovered in security.NewIdentity() when uuid.New() panic", "panic", r)
+		}
+	}()
+
+	now := time.Now()
+	return &Identity{
+		Entity: domain.Entity{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		Provider: provider,
+		Subject:  subject,
+		Email:    email,
+	}
+}
+
+// IdentityClaims holds verified claims of the ID token issued by identity provider.
+type IdentityClaims struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
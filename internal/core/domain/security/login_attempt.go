@@ -0,0 +1,32 @@
+package security
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// LoginAttempts tracks failed sign in attempts of a single username or client IP.
+// Key is prefixed with the kind of the tracked value, e.g. "username:john" or "ip:10.0.0.1".
+type LoginAttempts struct {
+	bun.BaseModel `bun:"table:login_attempts,alias:la"`
+
+	Key           string    `bun:"key,pk"`
+	Failures      int       `bun:"failures,notnull"`
+	LastFailureAt time.Time `bun:"last_failure_at,notnull"`
+	LockedUntil   time.Time `bun:"locked_until,nullzero"`
+}
+
+// NewLoginAttempts creates LoginAttempts holding the first failure.
+func NewLoginAttempts(key string, failedAt time.Time) *LoginAttempts {
+	return &LoginAttempts{
+		Key:           key,
+		Failures:      1,
+		LastFailureAt: failedAt,
+	}
+}
+
+// IsLocked returns true if sign in is temporarily blocked at the specified time.
+func (a *LoginAttempts) IsLocked(at time.Time) bool {
+	return a.LockedUntil.After(at)
+}
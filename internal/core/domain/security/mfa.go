@@ -0,0 +1,113 @@
+package security
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// MfaSecret holds user TOTP secret. Secret is pending until the user proves possession by verifying the first code.
+type MfaSecret struct {
+	bun.BaseModel `bun:"table:mfa_secrets,alias:ms"`
+
+	domain.Entity
+	UserID       uuid.UUID `bun:"user_id,notnull,unique"`
+	Secret       string    `bun:"secret,notnull"`
+	EnabledAt    time.Time `bun:"enabled_at,nullzero"`
+	LastUsedStep int64     `bun:"last_used_step,notnull"` // Time step of the last accepted code, used to reject replayed codes
+}
+
+func NewMfaSecret(userID uuid.UUID, secret string) *MfaSecret {
+	// recover in case uuid.New() panic
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Warn("Recovered in security.NewMfaSecret() when uuid.New() panic", "panic", r)
+		}
+	}()
+
+	now := time.Now()
+	return &MfaSecret{
+		Entity: domain.Entity{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		UserID: userID,
+		Secret: secret,
+	}
+}
+
+// IsEnabled returns true if sign in requires the second factor.
+func (s MfaSecret) IsEnabled() bool {
+	return !s.EnabledAt.IsZero()
+}
+
+// RecoveryCode holds hashed one-time code used instead of TOTP code when authenticator is lost.
+type RecoveryCode struct {
+	bun.BaseModel `bun:"table:mfa_recovery_codes,alias:mrc"`
+
+	domain.Entity
+	UserID   uuid.UUID `bun:"user_id,notnull"`
+	CodeHash string    `bun:"code_hash,notnull,unique"`
+}
+
+func NewRecoveryCode(userID uuid.UUID, codeHash string) *RecoveryCode {
+	// recover in case uuid.New() panic
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Warn("Recovered in security.NewRecoveryCode() when uuid.New() panic", "panic", r)
+		}
+	}()
+
+	now := time.Now()
+	return &RecoveryCode{
+		Entity: domain.Entity{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		UserID:   userID,
+		CodeHash: codeHash,
+	}
+}
+
+// MfaChallenge holds hashed short-lived token handed out after password check,
+// it is exchanged for access and refresh tokens together with the second factor.
+type MfaChallenge struct {
+	bun.BaseModel `bun:"table:mfa_challenges,alias:mc"`
+
+	domain.Entity
+	UserID    uuid.UUID `bun:"user_id,notnull"`
+	TokenHash string    `bun:"token_hash,notnull,unique"`
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+	Attempts  int       `bun:"attempts,notnull"`
+}
+
+func NewMfaChallenge(userID uuid.UUID, tokenHash string, expiresAt time.Time) *MfaChallenge {
+	// recover in case uuid.New() panic
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Warn("Recovered in security.NewMfaChallenge() when uuid.New() panic", "panic", r)
+		}
+	}()
+
+	now := time.Now()
+	return &MfaChallenge{
+		Entity: domain.Entity{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// IsExpired returns true if challenge is not valid anymore.
+func (c MfaChallenge) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
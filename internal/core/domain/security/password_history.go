@@ -0,0 +1,39 @@
+package security
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// PasswordHistory holds hash of the password user had before, so it can not be reused.
+type PasswordHistory struct {
+	bun.BaseModel `bun:"table:password_history,alias:ph"`
+
+	domain.Entity
+	UserID       uuid.UUID `bun:"user_id,notnull"`
+	PasswordHash string    `bun:"password_hash,notnull"`
+}
+
+func NewPasswordHistory(userID uuid.UUID, passwordHash string) *PasswordHistory {
+	// recover in case uuid.New() panic
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Warn("Recovered in security.NewPasswordHistory() when uuid.New() panic", "panic", r)
+		}
+	}()
+
+	now := time.Now()
+	return &PasswordHistory{
+		Entity: domain.Entity{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		UserID:       userID,
+		PasswordHash: passwordHash,
+	}
+}
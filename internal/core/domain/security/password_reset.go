@@ -0,0 +1,46 @@
+package security
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// PasswordReset holds hashed single-use token sent to the user to reset forgotten password.
+type PasswordReset struct {
+	bun.BaseModel `bun:"table:password_resets,alias:pr"`
+
+	domain.Entity
+	UserID    uuid.UUID `bun:"user_id,notnull"`
+	TokenHash string    `bun:"token_hash,notnull,unique"`
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+}
+
+func NewPasswordReset(userID uuid.UUID, tokenHash string, expiresAt time.Time) *PasswordReset {
+	// recover in case uuid.New() panic
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Warn("Recovered in security.NewPasswordReset() when uuid.New() panic", "panic", r)
+		}
+	}()
+
+	now := time.Now()
+	return &PasswordReset{
+		Entity: domain.Entity{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// IsExpired returns true if reset token is not valid anymore.
+func (r PasswordReset) IsExpired() bool {
+	return time.Now().After(r.ExpiresAt)
+}
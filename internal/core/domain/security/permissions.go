@@ -0,0 +1,91 @@
+package security
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Permissions are embedded into access tokens as scopes and checked per route.
+// The catalog is seeded with these by migrations, additional ones can be managed via api.
+const (
+	PERM_USER_READ     = "user:read"
+	PERM_USER_WRITE    = "user:write"
+	PERM_USER_DELETE   = "user:delete"
+	PERM_USER_ROLES    = "user:roles"
+	PERM_USER_ENABLE   = "user:enable"
+	PERM_USER_LOGOUT   = "user:logout"
+	PERM_USER_MFA      = "user:mfa"
+	PERM_USER_PASSWORD = "user:password"
+	PERM_ROLE_READ     = "role:read"
+	PERM_ROLE_WRITE    = "role:write"
+)
+
+// IsBuiltInPermission returns true for permissions the routes rely on, they can not be deleted.
+func IsBuiltInPermission(name string) bool {
+	switch name {
+	case PERM_USER_READ, PERM_USER_WRITE, PERM_USER_DELETE, PERM_USER_ROLES,
+		PERM_USER_ENABLE, PERM_USER_LOGOUT, PERM_USER_MFA, PERM_USER_PASSWORD, PERM_ROLE_READ, PERM_ROLE_WRITE:
+		return true
+	}
+	return false
+}
+
+// Permission represents catalog entry granted to users through roles.
+type Permission struct {
+	bun.BaseModel `bun:"table:permissions,alias:p"`
+
+	domain.Entity
+	Name        string `bun:"name,notnull,unique"`
+	Description string `bun:"description,nullzero"`
+}
+
+func NewPermission(name string, description string) *Permission {
+	// recover in case uuid.New() panic
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Warn("Recovered in security.NewPermission() when uuid.New() panic", r)
+		}
+	}()
+
+	now := time.Now()
+
+	return &Permission{
+		Entity: domain.Entity{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		Name:        name,
+		Description: description,
+	}
+}
+
+// RolePermission represents many-to-many relation between roles and permissions.
+type RolePermission struct {
+	bun.BaseModel `bun:"table:role_permissions,alias:rp"`
+
+	RoleID       uuid.UUID   `bun:"role_id,pk,type:uuid"`
+	Role         *Role       `bun:"rel:belongs-to,join:role_id=id"`
+	PermissionID uuid.UUID   `bun:"permission_id,pk,type:uuid"`
+	Permission   *Permission `bun:"rel:belongs-to,join:permission_id=id"`
+}
+
+// PermissionsOf returns distinct permissions granted by the roles.
+// Roles must be loaded together with their permissions.
+func PermissionsOf(roles ...*Role) []string {
+	var perms []string
+	seen := make(map[string]bool)
+	for _, role := range roles {
+		for _, p := range role.Permissions {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				perms = append(perms, p.Name)
+			}
+		}
+	}
+	return perms
+}
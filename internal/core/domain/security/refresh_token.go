@@ -0,0 +1,61 @@
+package security
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// RefreshToken holds hashed opaque token used for issuing new access tokens.
+// Tokens issued by rotating each other belong to the same family.
+type RefreshToken struct {
+	bun.BaseModel `bun:"table:refresh_tokens,alias:rt"`
+
+	domain.Entity
+	UserID    uuid.UUID `bun:"user_id,notnull"`
+	FamilyID  uuid.UUID `bun:"family_id,notnull"`
+	TokenHash string    `bun:"token_hash,notnull,unique"`
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+	UsedAt    time.Time `bun:"used_at,nullzero"`
+	RevokedAt time.Time `bun:"revoked_at,nullzero"`
+}
+
+func NewRefreshToken(userID uuid.UUID, familyID uuid.UUID, tokenHash string, expiresAt time.Time) *RefreshToken {
+	// recover in case uuid.New() panic
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Warn("Recovered in security.NewRefreshToken() when uuid.New() panic", "panic", r)
+		}
+	}()
+
+	now := time.Now()
+	return &RefreshToken{
+		Entity: domain.Entity{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// IsExpired returns true if refresh token is not valid anymore.
+func (t RefreshToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+// IsUsed returns true if refresh token was already rotated.
+func (t RefreshToken) IsUsed() bool {
+	return !t.UsedAt.IsZero()
+}
+
+// IsRevoked returns true if refresh token was revoked.
+func (t RefreshToken) IsRevoked() bool {
+	return !t.RevokedAt.IsZero()
+}
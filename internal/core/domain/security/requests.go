@@ -0,0 +1,29 @@
+package security
+
+type RoleRequest struct {
+	Name        string   `validate:"required,min=3,max=64" json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type UpdateRoleRequest struct {
+	ID          string `validate:"required,uuid" json:"id"`
+	Description string `json:"description"`
+}
+
+type RolePermissionsRequest struct {
+	ID          string   `validate:"required,uuid" json:"id"`
+	Permissions []string `json:"permissions"`
+}
+
+type PermissionRequest struct {
+	Name        string `validate:"required,min=3,max=64" json:"name"`
+	Description string `json:"description"`
+}
+
+type ApiKeyRequest struct {
+	UserID        string   `validate:"required,uuid" json:"-"`
+	Name          string   `validate:"required,max=64" json:"name"`
+	Scopes        []string `validate:"dive,required" json:"scopes"`
+	ExpiresInDays int      `validate:"omitempty,min=1,max=365" json:"expiresInDays"` // Default is configured API key expiration
+}
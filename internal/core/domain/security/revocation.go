@@ -0,0 +1,49 @@
+package security
+
+import (
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// RevokedToken represents access token that is not accepted anymore even though it is not expired yet.
+// Entity ID is the token ID (jti claim).
+type RevokedToken struct {
+	bun.BaseModel `bun:"table:revoked_tokens,alias:rvt"`
+
+	domain.Entity
+	UserID    uuid.UUID `bun:"user_id,notnull"`
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+}
+
+func NewRevokedToken(tokenID uuid.UUID, userID uuid.UUID, expiresAt time.Time) *RevokedToken {
+	now := time.Now()
+	return &RevokedToken{
+		Entity: domain.Entity{
+			ID:        tokenID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// TokenRevocation represents revocation of all access tokens issued to the user before specified time.
+type TokenRevocation struct {
+	bun.BaseModel `bun:"table:token_revocations,alias:tr"`
+
+	UserID        uuid.UUID `bun:"user_id,pk"`
+	RevokedBefore time.Time `bun:"revoked_before,notnull"`
+	UpdatedAt     time.Time `bun:"updated_at,notnull,default:current_timestamp"`
+}
+
+func NewTokenRevocation(userID uuid.UUID, before time.Time) *TokenRevocation {
+	return &TokenRevocation{
+		UserID:        userID,
+		RevokedBefore: before,
+		UpdatedAt:     time.Now(),
+	}
+}
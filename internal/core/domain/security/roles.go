@@ -10,19 +10,22 @@ import (
 )
 
 var (
-	ROLE_ADMIN = "ROLE_ADMIN"
-	ROLE_USER  = "ROLE_USER"
+	ROLE_ADMIN     = "ROLE_ADMIN"
+	ROLE_MODERATOR = "ROLE_MODERATOR"
+	ROLE_USER      = "ROLE_USER"
 )
 
+// Role represents catalog entry that groups permissions and is assigned to users.
 type Role struct {
 	bun.BaseModel `bun:"table:roles,alias:r"`
 
 	domain.Entity
-	Name   string    `bun:"name,notnull"`
-	UserID uuid.UUID `bun:"user_id,notnull,unique"`
+	Name        string        `bun:"name,notnull,unique"`
+	Description string        `bun:"description,nullzero"`
+	Permissions []*Permission `bun:"m2m:role_permissions,join:Role=Permission"`
 }
 
-func NewRole(name string) *Role {
+func NewRole(name string, opts ...RoleOption) *Role {
 	// recover in case uuid.New() panic
 	defer func() {
 		if r := recover(); r != nil {
@@ -32,7 +35,7 @@ func NewRole(name string) *Role {
 
 	now := time.Now()
 
-	return &Role{
+	r := &Role{
 		Entity: domain.Entity{
 			ID:        uuid.New(),
 			CreatedAt: now,
@@ -40,6 +43,10 @@ func NewRole(name string) *Role {
 		},
 		Name: name,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 type RoleOption func(*Role)
@@ -49,3 +56,14 @@ func RoleName(name string) RoleOption {
 		r.Name = name
 	}
 }
+
+func RoleDescription(description string) RoleOption {
+	return func(r *Role) {
+		r.Description = description
+	}
+}
+
+// IsBuiltIn returns true for roles the application relies on, they can not be deleted.
+func (r *Role) IsBuiltIn() bool {
+	return r.Name == ROLE_ADMIN || r.Name == ROLE_USER
+}
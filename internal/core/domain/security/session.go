@@ -0,0 +1,47 @@
+package security
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Session is server-side session of the user signed in to the UI, the browser holds the session token in a cookie.
+// Only hash of the token is kept, so leaked sessions table can not be used for signing in.
+type Session struct {
+	bun.BaseModel `bun:"table:sessions,alias:ses"`
+
+	domain.Entity
+	UserID    uuid.UUID `bun:"user_id,notnull"`
+	TokenHash string    `bun:"token_hash,notnull,unique"`
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+}
+
+func NewSession(userID uuid.UUID, tokenHash string, expiresAt time.Time) *Session {
+	// recover in case uuid.New() panic
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Warn("Recovered in security.NewSession() when uuid.New() panic", "panic", r)
+		}
+	}()
+
+	now := time.Now()
+	return &Session{
+		Entity: domain.Entity{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// IsExpired returns true if session is not valid anymore.
+func (s Session) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
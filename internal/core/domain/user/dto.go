@@ -10,15 +10,18 @@ import (
 
 // UserDto represents user DTO.
 type Dto struct {
-	ID          string    `json:"id"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
-	Email       string    `validate:"required,min=3" json:"email"`
-	FullName    string    `json:"fullname"`
-	DateOfBirth time.Time `json:"dateOfBirth"`
-	Location    string    `json:"location"`
-	Gender      GenderDto `json:"gender"`
-	Enabled     bool      `json:"enabled"`
+	ID          string     `json:"id"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	Version     int64      `json:"version"`
+	Email       string     `validate:"required,min=3" json:"email"`
+	FullName    string     `json:"fullname"`
+	DateOfBirth time.Time  `json:"dateOfBirth"`
+	Location    string     `json:"location"`
+	Gender      GenderDto  `json:"gender"`
+	Enabled     bool       `json:"enabled"`
+	Roles       []string   `json:"roles,omitempty"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
 }
 
 // GenderDto can be Male, Female and Other.
@@ -66,14 +69,27 @@ func ConvertToUser(r *Dto) (*User, error) {
 
 // ConvertToDto converts User entity into a User DTO.
 func ConvertToDto(u *User) *Dto {
+	var roles []string
+	for _, r := range u.Roles {
+		roles = append(roles, r.Name)
+	}
+	var deletedAt *time.Time
+	if !u.DeletedAt.IsZero() {
+		deletedAt = &u.DeletedAt
+	}
 	return &Dto{
 		ID:          u.ID.String(),
+		Version:     u.Version,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
 		Email:       u.Email,
 		FullName:    u.FullName,
 		DateOfBirth: u.DateOfBirth,
 		Location:    u.Location,
 		Gender:      GenderDto(u.Gender.Stringify()),
 		Enabled:     u.Enabled,
+		Roles:       roles,
+		DeletedAt:   deletedAt,
 	}
 }
 
@@ -87,5 +103,24 @@ func ConvertToPageDto(page domain.Page[User]) *domain.Page[Dto] {
 		TotalPages:    page.TotalPages,
 		TotalElements: page.TotalElements,
 		Elements:      dtos,
+		NextCursor:    page.NextCursor,
+		PrevCursor:    page.PrevCursor,
+	}
+}
+
+// ConvertToPatchRequest converts User DTO into the profile document merge patch is applied to.
+func ConvertToPatchRequest(d *Dto) *PatchRequest {
+	req := &PatchRequest{
+		ID:       d.ID,
+		Version:  d.Version,
+		Email:    d.Email,
+		FullName: d.FullName,
+		Location: d.Location,
+		Gender:   d.Gender,
+	}
+	if !d.DateOfBirth.IsZero() {
+		dob := d.DateOfBirth
+		req.DateOfBirth = &dob
 	}
+	return req
 }
@@ -17,6 +17,7 @@ type User struct {
 	bun.BaseModel `bun:"table:users,alias:u"`
 
 	domain.Entity
+	domain.SoftDelete
 	Email       string                `bun:"email,notnull,unique"`
 	FullName    string                `bun:"full_name,nullzero"`
 	DateOfBirth time.Time             `bun:"date_of_birth,nullzero"`
@@ -24,7 +25,18 @@ type User struct {
 	Gender      Gender                `bun:"gender,nullzero"`
 	Enabled     bool                  `bun:"enabled"`
 	Credentials *security.Credentials `bun:"rel:has-one,join:id=user_id"`
-	Roles       []*security.Role      `bun:"rel:has-many,join:id=user_id"`
+	Roles       []*security.Role      `bun:"m2m:user_roles,join:User=Role"`
+	Identities  []*security.Identity  `bun:"rel:has-many,join:id=user_id"`
+}
+
+// UserRole represents many-to-many relation between users and catalog roles.
+type UserRole struct {
+	bun.BaseModel `bun:"table:user_roles,alias:ur"`
+
+	UserID uuid.UUID      `bun:"user_id,pk,type:uuid"`
+	User   *User          `bun:"rel:belongs-to,join:user_id=id"`
+	RoleID uuid.UUID      `bun:"role_id,pk,type:uuid"`
+	Role   *security.Role `bun:"rel:belongs-to,join:role_id=id"`
 }
 
 func New(opts ...Option) *User {
@@ -51,6 +63,13 @@ func Id(id uuid.UUID) Option {
 	}
 }
 
+// Version sets version of the user the change is based on.
+func Version(v int64) Option {
+	return func(u *User) {
+		u.Version = v
+	}
+}
+
 func Email(e string) Option {
 	return func(u *User) {
 		u.Email = e
@@ -94,15 +113,23 @@ func Credentials(crd *security.Credentials) Option {
 	}
 }
 
+// Roles sets user roles, they are assigned from the role catalog by name.
 func Roles(roles ...*security.Role) Option {
 	return func(u *User) {
-		for _, role := range roles {
-			role.UserID = u.ID
-		}
 		u.Roles = roles
 	}
 }
 
+// Identities links user to the accounts of external identity providers.
+func Identities(identities ...*security.Identity) Option {
+	return func(u *User) {
+		for _, idn := range identities {
+			idn.UserID = u.ID
+		}
+		u.Identities = identities
+	}
+}
+
 // Gender is either MALE, FEMALE or OTHER.
 type Gender uint8
 
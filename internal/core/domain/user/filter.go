@@ -0,0 +1,42 @@
+package user
+
+import (
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain"
+)
+
+// Filter specifies which users are selected into a page, zero value fields are not applied.
+// Text fields are matched as case-insensitive substrings, date ranges include From and exclude To.
+type Filter struct {
+	Query       string // free text matched against email, full name and location
+	Email       string
+	FullName    string
+	Location    string
+	Gender      *Gender
+	Enabled     *bool
+	Role        string // name of the role user is assigned to
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	UpdatedFrom time.Time
+	UpdatedTo   time.Time
+
+	IncludeDeleted bool // soft deleted users are selected too
+}
+
+// SortFields are the fields users can be sorted on.
+var SortFields = domain.SortFields{
+	"createdAt":   "created_at",
+	"updatedAt":   "updated_at",
+	"email":       "email",
+	"fullName":    "full_name",
+	"dateOfBirth": "date_of_birth",
+	"location":    "location",
+}
+
+// KeysetSortFields are SortFields users can be sorted on in keyset paging mode, only not null columns are suitable.
+var KeysetSortFields = domain.SortFields{
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+	"email":     "email",
+}
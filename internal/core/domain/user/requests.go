@@ -19,31 +19,113 @@ type Request struct {
 
 type SignInRequest struct {
 	Username string `validate:"required,min=3,max=24" json:"username"`
-	Password string `validate:"required,min=8,max=72" json:"password"`
+	Password string `validate:"required,max=72" json:"password"`
+	ClientIP string `json:"-"` // Address of the client, used to track failed attempts per IP
 }
 
+// SessionRequest signs user in to the UI with the login form.
+// Code is required only if user has enabled two-factor authentication, it is either TOTP code or one of the recovery codes.
+type SessionRequest struct {
+	Username string `validate:"required,min=3,max=24" form:"username"`
+	Password string `validate:"required,max=72" form:"password"`
+	Code     string `validate:"omitempty,min=6,max=16" form:"code"`
+	ClientIP string `form:"-"` // Address of the client, used to track failed attempts per IP
+}
+
+// OidcCallbackRequest holds authorization response of identity provider.
+// ExpectedState, Nonce and Verifier are the values generated when the login was started.
+type OidcCallbackRequest struct {
+	Provider      string `validate:"required"`
+	Code          string `validate:"required"`
+	State         string `validate:"required"`
+	ExpectedState string `validate:"required"`
+	Nonce         string `validate:"required"`
+	Verifier      string `validate:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `validate:"required" json:"refreshToken"`
+}
+
+// SignOutRequest holds claims of the access token that is being revoked.
+type SignOutRequest struct {
+	TokenID   string
+	UserID    string
+	SessionID string
+	ExpiresAt time.Time
+}
+
+// ChangePasswordRequest replaces password of the signed in user, the old password has to be confirmed.
+// New password is checked against the password policy by the service.
 type ChangePasswordRequest struct {
-	Username    string `json:"username"`
-	OldPassword string `json:"oldPassword"`
-	NewPassword string `validate:"required,min=8,max=72" json:"newPassword"`
+	ID          string `validate:"required,uuid" json:"-"`
+	OldPassword string `validate:"required" json:"oldPassword"`
+	NewPassword string `validate:"required,nefield=OldPassword" json:"newPassword"`
+}
+
+// SetPasswordRequest is for admin usage only, to force new password of the user.
+type SetPasswordRequest struct {
+	ID          string `validate:"required,uuid" json:"-"`
+	NewPassword string `validate:"required" json:"newPassword"`
 }
 
 type ConfirmEmailRequest struct {
-	ID   string `json:"id"`
-	Code string `json:"code"`
+	ID   string `validate:"required,uuid" json:"id"`
+	Code string `validate:"required" json:"code"`
+}
+
+// ChangeEmailRequest starts change of the user email address, it is changed once the new address is confirmed.
+type ChangeEmailRequest struct {
+	ID    string `validate:"required,uuid" json:"-"`
+	Email string `validate:"required,email" json:"email"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `validate:"required,email" json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `validate:"required" json:"token"`
+	NewPassword string `validate:"required" json:"newPassword"`
+}
+
+// MfaCodeRequest holds second factor code of the signed in user, ID is taken from the access token.
+type MfaCodeRequest struct {
+	ID   string `validate:"required,uuid" json:"-"`
+	Code string `validate:"required,min=6,max=16" json:"code"`
+}
+
+// VerifyMfaRequest completes sign in of the user with enabled two-factor authentication.
+// Code is either TOTP code or one of the recovery codes.
+type VerifyMfaRequest struct {
+	MfaToken string `validate:"required" json:"mfaToken"`
+	Code     string `validate:"required,min=6,max=16" json:"code"`
 }
 
 type CreateRequest struct {
 	Username string `validate:"required,min=3,max=24" json:"username"`
-	Password string `validate:"required,min=8,max=72" json:"password"`
+	Password string `validate:"required" json:"password"` // checked against the password policy by the service
 	Request
 }
 
 type UpdateRequest struct {
-	ID string `json:"id"`
+	ID      string `json:"id"`
+	Version int64  `json:"version,omitempty"` // Version the change is based on, If-Match header takes precedence
 	Request
 }
 
+// PatchRequest is the user profile produced by merge patch of the stored one, it replaces the stored profile.
+// Fields missing from the patched document are cleared.
+type PatchRequest struct {
+	ID          string     `json:"-"`
+	Version     int64      `json:"-"` // Version the patch was applied to
+	Email       string     `validate:"required,min=3" json:"email"`
+	FullName    string     `json:"fullname,omitempty"`
+	DateOfBirth *time.Time `json:"dateOfBirth,omitempty"`
+	Location    string     `json:"location,omitempty"`
+	Gender      GenderDto  `validate:"required,oneof=Male Female Other" json:"gender"`
+}
+
 type RolesRequest struct {
 	ID    string   `json:"id"`
 	Roles []string `json:"roles"`
@@ -1,7 +1,39 @@
 package user
 
+import "time"
+
+// SignInResponse holds issued tokens.
+// If two-factor authentication is enabled only MfaToken is returned, it is exchanged for the tokens together with the code.
 type SignInResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	ExpiresIn    int64  `json:"expiresIn,omitempty"` // Access token lifetime in seconds
+	MfaRequired  bool   `json:"mfaRequired,omitempty"`
+	MfaToken     string `json:"mfaToken,omitempty"`
+}
+
+// OidcLoginResponse holds identity provider authorization url together with the values
+// that have to be kept by the client until the provider redirects back to the callback.
+type OidcLoginResponse struct {
+	URL      string
+	State    string
+	Nonce    string
+	Verifier string // PKCE code verifier
+}
+
+// SessionResponse holds token of the UI session, it is kept by the browser in a cookie.
+type SessionResponse struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+type MfaEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"` // otpauth key uri, usually rendered as QR code
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
 }
 
 type SignUpResponse struct {
@@ -12,6 +44,17 @@ type CreateResponse struct {
 	Dto
 }
 
+// UpdateResponse is the stored user after the update, date of birth is null if it is not set.
 type UpdateResponse struct {
 	Dto
+	DateOfBirth *time.Time `json:"dateOfBirth"`
+}
+
+// NewUpdateResponse converts updated User entity into UpdateResponse.
+func NewUpdateResponse(u *User) *UpdateResponse {
+	res := &UpdateResponse{Dto: *ConvertToDto(u)}
+	if !u.DateOfBirth.IsZero() {
+		res.DateOfBirth = &u.DateOfBirth
+	}
+	return res
 }
@@ -1,23 +1,110 @@
 package error
 
-import "errors"
+import (
+	"errors"
+	"strings"
+	"time"
+)
 
 var (
 	ErrParseReqBody      = errors.New("failed to parse request body")
 	ErrEntityCreate      = errors.New("failed to create entity")
 	ErrEntityUpdate      = errors.New("failed to update entity")
 	ErrEntityDelete      = errors.New("failed to delete entity")
+	ErrEntityRestore     = errors.New("failed to restore entity")
+	ErrVersionRequired   = errors.New("entity version is required, send it in If-Match header")
+	ErrVersionMismatch   = errors.New("entity was modified in the meantime, get it again and retry")
+	ErrInvalidPatch      = errors.New("invalid patch")
+	ErrUnsupportedPatch  = errors.New("unsupported patch format, use application/merge-patch+json")
 	ErrGetById           = errors.New("failed to get entity by id")
 	ErrInvalidId         = errors.New("invalid id")
 	ErrInvalidCode       = errors.New("invalid code")
+	ErrExpiredCode       = errors.New("expired code")
 	ErrDeleteById        = errors.New("failed to delete entity by id")
 	ErrInvalidPageSize   = errors.New("invalid page size number")
 	ErrInvalidPageOffset = errors.New("invalid page offset number")
 	ErrGetPage           = errors.New("failed to get entities page")
+	ErrInvalidFilter     = errors.New("invalid filter")
+	ErrInvalidSort       = errors.New("invalid sort")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrInvalidPaging     = errors.New("invalid paging")
+	ErrGetAll            = errors.New("failed to get entities")
 	ErrInvalidAuthReq    = errors.New("invalid username or password")
+	ErrInvalidCreds      = errors.New("invalid credentials")
+	ErrTooManyAttempts   = errors.New("too many failed sign in attempts, try again later")
 	ErrSignUp            = errors.New("failed to register user")
+	ErrConfirmEmail      = errors.New("failed to confirm email")
+	ErrChangeEmail       = errors.New("failed to change email")
+	ErrEmailTaken        = errors.New("email is already registered")
+	ErrUserDisabled      = errors.New("user is disabled")
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrExpiredToken      = errors.New("expired token")
+	ErrTokenReuse        = errors.New("token reuse detected")
+	ErrRefreshToken      = errors.New("failed to refresh token")
+	ErrSignOut           = errors.New("failed to sign out")
+	ErrResetPassword     = errors.New("failed to reset password")
+	ErrChangePassword    = errors.New("failed to change password")
+	ErrInvalidPassword   = errors.New("invalid old password")
+	ErrMfaEnabled        = errors.New("two-factor authentication is already enabled")
+	ErrMfaNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrMfaNotEnrolled    = errors.New("two-factor authentication is not enrolled")
+	ErrMfaVerify         = errors.New("failed to verify two-factor authentication")
+	ErrMfaRequired       = errors.New("two-factor authentication code is required")
+	ErrMfaUpdate         = errors.New("failed to update two-factor authentication")
+	ErrUnknownRole       = errors.New("unknown role")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrScopeNotGranted   = errors.New("scope is not granted to the user")
+	ErrApiKeyNotAllowed  = errors.New("api key can not be used for this operation")
+	ErrUnknownProvider   = errors.New("unknown identity provider")
+	ErrInvalidState      = errors.New("invalid state")
+	ErrOidcSignIn        = errors.New("failed to sign in with identity provider")
+	ErrEmailNotVerified  = errors.New("email is already registered, identity provider did not verify it")
+	ErrEmailRequired     = errors.New("identity provider did not return email address")
+	ErrBuiltInRole       = errors.New("built-in role can not be deleted")
+	ErrBuiltInPermission = errors.New("built-in permission can not be deleted")
+	ErrServerBusy        = errors.New("server is busy, try again later")
 )
 
+// LockoutError is returned when sign in is temporarily blocked after too many failed attempts.
+type LockoutError struct {
+	Until time.Time // Time when sign in is allowed again
+}
+
+// Error is implementation of error interface.
+func (x LockoutError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+// Unwrap makes LockoutError match ErrTooManyAttempts.
+func (x LockoutError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// BusyError is returned when the request is rejected because the server is saturated, e.g. by password hashing.
+type BusyError struct {
+	RetryAfter time.Duration // Time after which the request can be retried
+}
+
+// Error is implementation of error interface.
+func (x BusyError) Error() string {
+	return ErrServerBusy.Error()
+}
+
+// Unwrap makes BusyError match ErrServerBusy.
+func (x BusyError) Unwrap() error {
+	return ErrServerBusy
+}
+
+// PasswordPolicyError is returned when new password breaks rules of the password policy.
+type PasswordPolicyError struct {
+	Violations []string // Rules the password breaks, e.g. "must contain a digit"
+}
+
+// Error is implementation of error interface.
+func (x PasswordPolicyError) Error() string {
+	return "password does not meet the policy: " + strings.Join(x.Violations, ", ")
+}
+
 // ApiError represents a custom error struct that contains optionally service and application error.
 type ApiError struct {
 	srvErr error
@@ -4,20 +4,56 @@ import (
 	"context"
 
 	"github.com/fmiskovic/go-starter/internal/core/domain"
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
 	"github.com/fmiskovic/go-starter/internal/core/domain/user"
 )
 
 type UserService[ID any] interface {
 	SingIn(ctx context.Context, req *user.SignInRequest) (*user.SignInResponse, error)
+	Refresh(ctx context.Context, req *user.RefreshRequest) (*user.SignInResponse, error)
+	SignOut(ctx context.Context, req *user.SignOutRequest) error
+	StartSession(ctx context.Context, req *user.SessionRequest) (*user.SessionResponse, error)
+	EndSession(ctx context.Context, sessionToken string) error
+	StartOidcLogin(ctx context.Context, provider string) (*user.OidcLoginResponse, error)
+	CompleteOidcLogin(ctx context.Context, req *user.OidcCallbackRequest) (*user.SignInResponse, error)
+	SignOutAll(ctx context.Context, id ID) error
 	SingUp(ctx context.Context, req *user.CreateRequest) (*user.SignUpResponse, error)
 	ConfirmEmail(ctx context.Context, req user.ConfirmEmailRequest) error
+	ChangeEmail(ctx context.Context, req user.ChangeEmailRequest) error
 	Create(ctx context.Context, req *user.CreateRequest) (*user.CreateResponse, error)
 	Update(ctx context.Context, req *user.UpdateRequest) (*user.UpdateResponse, error)
+	Patch(ctx context.Context, req *user.PatchRequest) (*user.UpdateResponse, error)
 	GetById(ctx context.Context, id ID) (*user.Dto, error)
 	DeleteById(ctx context.Context, id ID) error
-	GetPage(ctx context.Context, pagabale domain.Pageable) (*domain.Page[user.Dto], error)
+	Restore(ctx context.Context, id ID) error
+	PurgeDeleted(ctx context.Context) (int, error)
+	GetPage(ctx context.Context, pagabale domain.Pageable, filter user.Filter) (*domain.Page[user.Dto], error)
 	AddRoles(ctx context.Context, roles []string, id ID) error
 	RemoveRoles(ctx context.Context, roles []string, id ID) error
 	EnableDisable(ctx context.Context, id ID) error
-	ChangePassword(ctx context.Context, req *user.ChangePasswordRequest) error
+	Unlock(ctx context.Context, id ID) error
+	ChangePassword(ctx context.Context, req *user.ChangePasswordRequest) (*user.SignInResponse, error)
+	SetPassword(ctx context.Context, req *user.SetPasswordRequest) error
+	ForgotPassword(ctx context.Context, req *user.ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, req *user.ResetPasswordRequest) error
+	EnrollMfa(ctx context.Context, id ID) (*user.MfaEnrollResponse, error)
+	EnableMfa(ctx context.Context, req *user.MfaCodeRequest) (*user.RecoveryCodesResponse, error)
+	DisableMfa(ctx context.Context, req *user.MfaCodeRequest) error
+	VerifyMfa(ctx context.Context, req *user.VerifyMfaRequest) (*user.SignInResponse, error)
+	ResetMfa(ctx context.Context, id ID) error
+	CreateApiKey(ctx context.Context, req *security.ApiKeyRequest) (*security.CreateApiKeyResponse, error)
+	GetApiKeys(ctx context.Context, userID ID) ([]*security.ApiKeyDto, error)
+	RevokeApiKey(ctx context.Context, id ID, userID ID) error
+}
+
+type RoleService[ID any] interface {
+	GetById(ctx context.Context, id ID) (*security.RoleDto, error)
+	GetAll(ctx context.Context) ([]*security.RoleDto, error)
+	Create(ctx context.Context, req *security.RoleRequest) (*security.RoleDto, error)
+	Update(ctx context.Context, req *security.UpdateRoleRequest) (*security.RoleDto, error)
+	DeleteById(ctx context.Context, id ID) error
+	SetPermissions(ctx context.Context, req *security.RolePermissionsRequest) error
+	GetPermissions(ctx context.Context) ([]*security.PermissionDto, error)
+	CreatePermission(ctx context.Context, req *security.PermissionRequest) (*security.PermissionDto, error)
+	DeletePermissionById(ctx context.Context, id ID) error
 }
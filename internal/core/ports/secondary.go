@@ -2,8 +2,10 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/fmiskovic/go-starter/internal/core/domain"
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
 	"github.com/fmiskovic/go-starter/internal/core/domain/user"
 	"github.com/uptrace/bun"
 )
@@ -26,11 +28,131 @@ type UserRepo[ID any] interface {
 	GetById(ctx context.Context, id ID) (*user.User, error)
 	Create(ctx context.Context, user *user.User) error
 	Update(ctx context.Context, user *user.User) error
+	UpdateProfile(ctx context.Context, user *user.User) error
 	DeleteById(ctx context.Context, id ID) error
-	GetPage(ctx context.Context, p domain.Pageable) (domain.Page[user.User], error)
+	Restore(ctx context.Context, id ID) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int, error)
+	GetPage(ctx context.Context, p domain.Pageable, f user.Filter) (domain.Page[user.User], error)
 	GetByUsername(ctx context.Context, username string) (*user.User, error)
-	ChangePassword(ctx context.Context, req *user.ChangePasswordRequest) error
+	GetByEmail(ctx context.Context, email string) (*user.User, error)
+	GetByIdentity(ctx context.Context, provider string, subject string) (*user.User, error)
+	AddIdentity(ctx context.Context, identity *security.Identity) error
+	// UpdatePassword replaces password of the user, the replaced one is kept in the history of historySize last passwords.
+	UpdatePassword(ctx context.Context, id ID, passwordHash string, historySize int) error
+	// RehashPassword replaces hash of the same password made with outdated algorithm or parameters.
+	// Nothing is changed if the hash is not oldHash anymore, e.g. the password was changed meanwhile.
+	RehashPassword(ctx context.Context, id ID, oldHash string, newHash string) error
+	// GetPasswordHistory returns hashes of up to limit passwords the user had before, the latest first.
+	GetPasswordHistory(ctx context.Context, id ID, limit int) ([]string, error)
 	AddRoles(ctx context.Context, roles []string, id ID) error
 	RemoveRoles(ctx context.Context, roles []string, id ID) error
 	EnableDisable(ctx context.Context, id ID) error
+	SaveEmailConfirmation(ctx context.Context, c *security.EmailConfirmation) error
+	ConfirmEmail(ctx context.Context, id ID, codeHash string) error
+	SavePasswordReset(ctx context.Context, r *security.PasswordReset) error
+	// GetByResetToken returns user the password reset token was issued to, the token is not consumed.
+	GetByResetToken(ctx context.Context, tokenHash string) (*user.User, error)
+	ResetPassword(ctx context.Context, tokenHash string, passwordHash string, historySize int) (ID, error)
+}
+
+// RoleRepo represents role and permission catalog repository interface.
+type RoleRepo[ID any] interface {
+	GetById(ctx context.Context, id ID) (*security.Role, error)
+	GetAll(ctx context.Context) ([]*security.Role, error)
+	Create(ctx context.Context, role *security.Role) error
+	Update(ctx context.Context, role *security.Role) error
+	DeleteById(ctx context.Context, id ID) error
+	SetPermissions(ctx context.Context, id ID, permissions []string) error
+	GetPermissions(ctx context.Context) ([]*security.Permission, error)
+	GetPermissionById(ctx context.Context, id ID) (*security.Permission, error)
+	CreatePermission(ctx context.Context, permission *security.Permission) error
+	DeletePermissionById(ctx context.Context, id ID) error
+}
+
+// MfaRepo represents two-factor authentication repository interface.
+type MfaRepo[ID any] interface {
+	GetSecret(ctx context.Context, userID ID) (*security.MfaSecret, error)
+	SaveSecret(ctx context.Context, s *security.MfaSecret) error
+	Enable(ctx context.Context, userID ID, step int64, codes []*security.RecoveryCode) error
+	UseStep(ctx context.Context, userID ID, step int64) error
+	UseRecoveryCode(ctx context.Context, userID ID, codeHash string) error
+	DeleteByUserId(ctx context.Context, userID ID) error
+	CreateChallenge(ctx context.Context, c *security.MfaChallenge) error
+	GetChallenge(ctx context.Context, tokenHash string) (*security.MfaChallenge, error)
+	FailChallenge(ctx context.Context, id ID) error
+	DeleteChallenge(ctx context.Context, id ID) error
+}
+
+// RefreshTokenRepo represents refresh token repository interface.
+type RefreshTokenRepo[ID any] interface {
+	Create(ctx context.Context, t *security.RefreshToken) error
+	Rotate(ctx context.Context, tokenHash string, next *security.RefreshToken) (*security.RefreshToken, error)
+	RevokeFamily(ctx context.Context, familyID ID) error
+	RevokeAll(ctx context.Context, userID ID) error
+}
+
+// RevocationStore keeps track of revoked access tokens.
+type RevocationStore[ID any] interface {
+	Revoke(ctx context.Context, tokenID ID, userID ID, expiresAt time.Time) error
+	RevokeAll(ctx context.Context, userID ID, before time.Time) error
+	IsRevoked(ctx context.Context, tokenID ID, userID ID, issuedAt time.Time) (bool, error)
+}
+
+// SessionStore keeps server-side sessions of users signed in to the UI.
+type SessionStore[ID any] interface {
+	Create(ctx context.Context, s *security.Session) error
+	// Get returns session with the token hash, sql.ErrNoRows is returned if there is none.
+	Get(ctx context.Context, tokenHash string) (*security.Session, error)
+	Delete(ctx context.Context, tokenHash string) error
+	DeleteAll(ctx context.Context, userID ID) error
+}
+
+// ApiKeyRepo persists personal access tokens of machine clients.
+type ApiKeyRepo[ID any] interface {
+	Create(ctx context.Context, k *security.ApiKey) error
+	GetByHash(ctx context.Context, keyHash string) (*security.ApiKey, error)
+	GetByUserId(ctx context.Context, userID ID) ([]*security.ApiKey, error)
+	Touch(ctx context.Context, id ID, usedAt time.Time) error
+	DeleteById(ctx context.Context, id ID, userID ID) error
+	RevokeAll(ctx context.Context, userID ID) error
+}
+
+// LoginAttemptStore keeps track of failed sign in attempts.
+type LoginAttemptStore interface {
+	// Get returns attempts tracked under the key, zero attempts are returned if there are none.
+	Get(ctx context.Context, key string) (*security.LoginAttempts, error)
+	// Fail counts failed attempt, failures that happened before since are forgotten.
+	Fail(ctx context.Context, key string, since time.Time) (*security.LoginAttempts, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+// BreachedPasswords tells whether password is known from data breaches.
+type BreachedPasswords interface {
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
+
+// Mailer sends email messages.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// IdentityProvider signs users in with external OpenID Connect provider using authorization code flow with PKCE.
+type IdentityProvider interface {
+	// AuthCodeURL returns provider's authorization url the user is redirected to.
+	AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error)
+	// Exchange exchanges authorization code for ID token and returns its verified claims.
+	Exchange(ctx context.Context, code string, verifier string, nonce string) (*security.IdentityClaims, error)
+}
+
+// Authenticator verifies username and password of the user signing in.
+type Authenticator interface {
+	// Authenticate returns user with matching credentials, unknown username and wrong password both return apiErr.ErrInvalidCreds.
+	Authenticate(ctx context.Context, username string, password string) (*user.User, error)
+}
+
+// Directory verifies credentials against external user directory such as LDAP or Active Directory.
+type Directory interface {
+	// Authenticate returns directory entry of the user, unknown username and wrong password both return apiErr.ErrInvalidCreds.
+	Authenticate(ctx context.Context, username string, password string) (*security.DirectoryEntry, error)
 }
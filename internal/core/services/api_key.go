@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/utils/token"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// apiKeyPrefixLen is length of the key beginning kept in plain to identify the key.
+	apiKeyPrefixLen = len(security.ApiKeyPrefix) + 8
+	// apiKeyTouchInterval limits how often last used time is written, so every request does not update the key.
+	apiKeyTouchInterval = time.Minute
+)
+
+// CreateApiKey creates new API key of the user, it can hold only scopes granted to the user.
+// Plain key is returned only once, only its hash is persisted.
+func (s UserService) CreateApiKey(ctx context.Context, req *security.ApiKeyRequest) (*security.CreateApiKeyResponse, error) {
+	if s.apiKeyRepo == nil {
+		return nil, ErrApiKeyRepoNotConfigured
+	}
+
+	id, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return nil, apiErr.ErrInvalidId
+	}
+	u, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := s.grantedScopes(u)
+	for _, scope := range req.Scopes {
+		if !slices.Contains(granted, scope) {
+			return nil, fmt.Errorf("%w: %s", apiErr.ErrScopeNotGranted, scope)
+		}
+	}
+	scopes := append([]string{}, req.Scopes...)
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+
+	exp := s.authConfig.ApiKeyExp
+	if req.ExpiresInDays > 0 {
+		exp = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+
+	plain, err := token.Generate()
+	if err != nil {
+		return nil, err
+	}
+	plain = security.ApiKeyPrefix + plain
+
+	k := security.NewApiKey(u.ID, req.Name, plain[:apiKeyPrefixLen], token.Hash(plain), scopes, time.Now().Add(exp))
+	if err := s.apiKeyRepo.Create(ctx, k); err != nil {
+		return nil, err
+	}
+
+	return &security.CreateApiKeyResponse{ApiKeyDto: *security.ConvertToApiKeyDto(k), Key: plain}, nil
+}
+
+// GetApiKeys returns all API keys of the user.
+func (s UserService) GetApiKeys(ctx context.Context, userID uuid.UUID) ([]*security.ApiKeyDto, error) {
+	if s.apiKeyRepo == nil {
+		return nil, ErrApiKeyRepoNotConfigured
+	}
+
+	keys, err := s.apiKeyRepo.GetByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]*security.ApiKeyDto, len(keys))
+	for i, k := range keys {
+		dtos[i] = security.ConvertToApiKeyDto(k)
+	}
+	return dtos, nil
+}
+
+// RevokeApiKey deletes API key of the user.
+func (s UserService) RevokeApiKey(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
+	if s.apiKeyRepo == nil {
+		return ErrApiKeyRepoNotConfigured
+	}
+	return s.apiKeyRepo.DeleteById(ctx, id, userID)
+}
+
+// AuthenticateApiKey returns claims of the API key owner, they are used in place of access token claims.
+// Key scopes that are not granted to the user anymore are left out, "api_key" claim holds the key ID.
+func (s UserService) AuthenticateApiKey(ctx context.Context, key string) (jwt.MapClaims, error) {
+	if s.apiKeyRepo == nil {
+		return nil, ErrApiKeyRepoNotConfigured
+	}
+
+	k, err := s.apiKeyRepo.GetByHash(ctx, token.Hash(key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apiErr.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if k.IsExpired() {
+		return nil, apiErr.ErrExpiredToken
+	}
+
+	u, err := s.repo.GetById(ctx, k.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !u.Enabled {
+		return nil, apiErr.ErrUserDisabled
+	}
+
+	now := time.Now()
+	if now.Sub(k.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.apiKeyRepo.Touch(ctx, k.ID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	// configured scopes are granted to every key
+	granted := s.grantedScopes(u)
+	scopes := append([]string{}, s.authConfig.Scopes...)
+	for _, scope := range k.Scopes {
+		if slices.Contains(granted, scope) && !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	claims := s.accessClaims(u, uuid.Nil, now)
+	delete(claims, "sid")
+	claims["scope"] = strings.Join(scopes, " ")
+	claims["exp"] = k.ExpiresAt.Unix()
+	claims["jti"] = k.ID
+	claims["api_key"] = k.ID
+	return claims, nil
+}
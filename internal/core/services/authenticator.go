@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/fmiskovic/go-starter/internal/core/configs"
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/core/ports"
+	"github.com/google/uuid"
+)
+
+// DirectoryProvider is the provider name of identities that link users to the user directory.
+const DirectoryProvider = "ldap"
+
+// PasswordAuthenticator is implementation of ports.Authenticator interface,
+// it checks password against the hash kept in user credentials.
+// Hash made with outdated algorithm or parameters is replaced once the password is verified.
+type PasswordAuthenticator struct {
+	repo    ports.UserRepo[uuid.UUID]
+	hashing *HashingPool
+	dummy   string
+}
+
+// NewPasswordAuthenticator instantiate new PasswordAuthenticator.
+// Hashing pool should be shared with the user service, so the concurrency limit applies to all the operations.
+func NewPasswordAuthenticator(repo ports.UserRepo[uuid.UUID], hashing *HashingPool) PasswordAuthenticator {
+	// compared against when username does not exist, it is made by the same hasher as real hashes,
+	// so response time does not reveal whether the username exists
+	dummy, err := hashing.Hash(context.Background(), "dummy-password")
+	if err != nil {
+		slog.Error("failed to hash dummy password", "error", err.Error())
+	}
+	return PasswordAuthenticator{repo: repo, hashing: hashing, dummy: dummy}
+}
+
+// Authenticate returns user with matching username and password.
+// Unknown username and wrong password both return apiErr.ErrInvalidCreds and take the same time.
+func (a PasswordAuthenticator) Authenticate(ctx context.Context, username string, pwd string) (*user.User, error) {
+	u, err := a.repo.GetByUsername(ctx, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := a.hashing.Verify(ctx, pwd, a.dummy); errors.Is(err, apiErr.ErrServerBusy) {
+			return nil, err
+		}
+		return nil, apiErr.ErrInvalidCreds
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := a.hashing.Verify(ctx, pwd, u.Credentials.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, apiErr.ErrInvalidCreds
+	}
+
+	if a.hashing.NeedsRehash(u.Credentials.Password) {
+		a.rehash(ctx, u, pwd)
+	}
+	return u, nil
+}
+
+// rehash replaces hash of the verified password with the one made with current algorithm and parameters.
+// Failure does not prevent signing in, the hash is replaced on the next sign in.
+func (a PasswordAuthenticator) rehash(ctx context.Context, u *user.User, pwd string) {
+	hash, err := a.hashing.Hash(ctx, pwd)
+	if err == nil {
+		err = a.repo.RehashPassword(ctx, u.ID, u.Credentials.Password, hash)
+	}
+	if err != nil {
+		slog.Warn("failed to rehash password", "user", u.ID, "error", err.Error())
+		return
+	}
+	u.Credentials.Password = hash
+}
+
+// DirectoryAuthenticator is implementation of ports.Authenticator interface,
+// it verifies credentials against the user directory, passwords are never stored.
+// User signing in for the first time is provisioned, roles mapped from directory groups are synced on every sign in.
+type DirectoryAuthenticator struct {
+	directory ports.Directory
+	repo      ports.UserRepo[uuid.UUID]
+	cfg       configs.LdapConfig
+}
+
+// NewDirectoryAuthenticator instantiate new DirectoryAuthenticator.
+func NewDirectoryAuthenticator(directory ports.Directory, repo ports.UserRepo[uuid.UUID], cfg configs.LdapConfig) DirectoryAuthenticator {
+	return DirectoryAuthenticator{directory: directory, repo: repo, cfg: cfg}
+}
+
+// Authenticate returns user authenticated by the directory.
+func (a DirectoryAuthenticator) Authenticate(ctx context.Context, username string, pwd string) (*user.User, error) {
+	entry, err := a.directory.Authenticate(ctx, username, pwd)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := a.groupRoles(entry.Groups)
+	provisioned := append([]string{}, a.cfg.DefaultRoles...)
+	if len(provisioned) == 0 {
+		provisioned = append(provisioned, security.ROLE_USER)
+	}
+
+	// emails are managed by the organization, so the directory is trusted to have verified them
+	u, err := linkIdentity(ctx, a.repo, &security.IdentityClaims{
+		Provider:      DirectoryProvider,
+		Subject:       entry.Username,
+		Email:         entry.Email,
+		EmailVerified: true,
+		Name:          entry.FullName,
+	}, append(provisioned, roles...))
+	if err != nil {
+		return nil, err
+	}
+
+	return a.syncRoles(ctx, u, roles)
+}
+
+// syncRoles adds mapped roles of the groups user is member of and removes mapped roles of other groups.
+// Roles that are not mapped to any group are managed by admins and are left as they are.
+func (a DirectoryAuthenticator) syncRoles(ctx context.Context, u *user.User, roles []string) (*user.User, error) {
+	var add, remove []string
+	for _, role := range a.mappedRoles() {
+		has := slices.ContainsFunc(u.Roles, func(r *security.Role) bool { return r.Name == role })
+		want := slices.Contains(roles, role)
+		if want && !has {
+			add = append(add, role)
+		}
+		if !want && has {
+			remove = append(remove, role)
+		}
+	}
+	if len(add) == 0 && len(remove) == 0 {
+		return u, nil
+	}
+
+	if len(add) > 0 {
+		if err := a.repo.AddRoles(ctx, add, u.ID); err != nil {
+			return nil, err
+		}
+	}
+	if len(remove) > 0 {
+		if err := a.repo.RemoveRoles(ctx, remove, u.ID); err != nil {
+			return nil, err
+		}
+	}
+	return a.repo.GetById(ctx, u.ID)
+}
+
+// groupRoles returns roles mapped to the groups.
+func (a DirectoryAuthenticator) groupRoles(groups []string) []string {
+	var roles []string
+	for group, mapped := range a.cfg.GroupRoles {
+		if slices.ContainsFunc(groups, func(dn string) bool { return groupMatches(group, dn) }) {
+			roles = append(roles, mapped...)
+		}
+	}
+	slices.Sort(roles)
+	return slices.Compact(roles)
+}
+
+// mappedRoles returns all roles that are mapped to any group.
+func (a DirectoryAuthenticator) mappedRoles() []string {
+	var roles []string
+	for _, mapped := range a.cfg.GroupRoles {
+		roles = append(roles, mapped...)
+	}
+	slices.Sort(roles)
+	return slices.Compact(roles)
+}
+
+// groupMatches returns true if the group is the full DN or the value of its first RDN, e.g. CN.
+func groupMatches(group string, dn string) bool {
+	if strings.EqualFold(group, dn) {
+		return true
+	}
+	rdn, _, _ := strings.Cut(dn, ",")
+	_, name, ok := strings.Cut(rdn, "=")
+	return ok && strings.EqualFold(group, strings.TrimSpace(name))
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/utils/password"
+)
+
+// HashingPool runs password hashing with bounded concurrency, so a burst of sign ins can not take every CPU
+// and starve other requests. Operation waits for a free slot until the queue timeout elapses or the context is done,
+// apiErr.BusyError is returned on timeout.
+type HashingPool struct {
+	hasher  password.Hasher
+	slots   chan struct{} // nil if concurrency is not limited
+	timeout time.Duration
+}
+
+// NewHashingPool instantiate new HashingPool running at most limit operations of the hasher at the same time.
+// Limit less than 1 disables the limit.
+func NewHashingPool(hasher password.Hasher, limit int, timeout time.Duration) *HashingPool {
+	p := &HashingPool{hasher: hasher, timeout: timeout}
+	if limit > 0 {
+		p.slots = make(chan struct{}, limit)
+	}
+	return p
+}
+
+// Hash returns hash of the password once a slot is free.
+func (p *HashingPool) Hash(ctx context.Context, pwd string) (string, error) {
+	if err := p.acquire(ctx); err != nil {
+		return "", err
+	}
+	defer p.release()
+
+	return p.hasher.Hash(pwd)
+}
+
+// Verify returns true if the password matches the hash once a slot is free.
+func (p *HashingPool) Verify(ctx context.Context, pwd string, hash string) (bool, error) {
+	if err := p.acquire(ctx); err != nil {
+		return false, err
+	}
+	defer p.release()
+
+	return p.hasher.Verify(pwd, hash)
+}
+
+// NeedsRehash returns true if the hash is made with outdated algorithm or parameters, it does not need a slot.
+func (p *HashingPool) NeedsRehash(hash string) bool {
+	return p.hasher.NeedsRehash(hash)
+}
+
+func (p *HashingPool) acquire(ctx context.Context) error {
+	if p.slots == nil {
+		return ctx.Err()
+	}
+
+	// free slot is taken right away, even if the timeout is zero
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return apiErr.BusyError{RetryAfter: p.timeout}
+	}
+}
+
+func (p *HashingPool) release() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
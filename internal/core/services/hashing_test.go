@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/matryer/is"
+)
+
+// blockingHasher blocks every operation until release is closed.
+type blockingHasher struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h blockingHasher) Hash(pwd string) (string, error) {
+	h.started <- struct{}{}
+	<-h.release
+	return "hash", nil
+}
+
+func (h blockingHasher) Verify(pwd string, hash string) (bool, error) {
+	h.started <- struct{}{}
+	<-h.release
+	return pwd == hash, nil
+}
+
+func (h blockingHasher) NeedsRehash(hash string) bool {
+	return false
+}
+
+func TestHashingPool(t *testing.T) {
+	assert := is.New(t)
+
+	h := blockingHasher{started: make(chan struct{}, 2), release: make(chan struct{})}
+	pool := NewHashingPool(h, 1, 50*time.Millisecond)
+
+	// first operation takes the only slot
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := pool.Hash(context.Background(), "password1")
+		assert.NoErr(err)
+	}()
+	<-h.started
+
+	// queued operation is rejected once the queue timeout elapses
+	_, err := pool.Verify(context.Background(), "password1", "password1")
+	var busy apiErr.BusyError
+	assert.True(errors.As(err, &busy))
+	assert.True(errors.Is(err, apiErr.ErrServerBusy))
+	assert.Equal(busy.RetryAfter, 50*time.Millisecond)
+
+	// queued operation gives up when its context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = pool.Hash(ctx, "password1")
+	assert.True(errors.Is(err, context.Canceled))
+
+	// slot is free again once the first operation is done
+	close(h.release)
+	wg.Wait()
+	ok, err := pool.Verify(context.Background(), "password1", "password1")
+	assert.NoErr(err)
+	assert.True(ok)
+}
+
+func TestHashingPool_Unlimited(t *testing.T) {
+	assert := is.New(t)
+
+	h := blockingHasher{started: make(chan struct{}, 3), release: make(chan struct{})}
+	pool := NewHashingPool(h, 0, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := pool.Hash(context.Background(), "password1")
+			assert.NoErr(err)
+		}()
+	}
+
+	// all operations run at the same time
+	for i := 0; i < 3; i++ {
+		<-h.started
+	}
+	close(h.release)
+	wg.Wait()
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/utils"
+	"github.com/google/uuid"
+)
+
+// maxLockoutDuration caps progressive lockout duration.
+const maxLockoutDuration = 24 * time.Hour
+
+// loginKey identifies tracked sign in attempts together with the threshold applied to them.
+type loginKey struct {
+	key       string
+	threshold int
+}
+
+// loginKeys returns keys sign in attempts are tracked under, attempts are tracked per username and per client IP.
+func (s UserService) loginKeys(req *user.SignInRequest) []loginKey {
+	var keys []loginKey
+	if s.authConfig.LockoutThreshold > 0 {
+		keys = append(keys, loginKey{usernameKey(req.Username), s.authConfig.LockoutThreshold})
+	}
+	if s.authConfig.LockoutIPThreshold > 0 && !utils.IsBlank(req.ClientIP) {
+		keys = append(keys, loginKey{"ip:" + req.ClientIP, s.authConfig.LockoutIPThreshold})
+	}
+	return keys
+}
+
+// checkLockout returns apiErr.LockoutError if sign in is blocked for any of the keys.
+func (s UserService) checkLockout(ctx context.Context, keys []loginKey, now time.Time) error {
+	if s.loginAttempts == nil {
+		return nil
+	}
+
+	for _, k := range keys {
+		a, err := s.loginAttempts.Get(ctx, k.key)
+		if err != nil {
+			return err
+		}
+		if a.IsLocked(now) {
+			return apiErr.LockoutError{Until: a.LockedUntil}
+		}
+	}
+	return nil
+}
+
+// loginFailed counts failed attempt and locks the keys that reached their threshold.
+// Every further threshold reached within the window doubles the lockout duration.
+func (s UserService) loginFailed(ctx context.Context, keys []loginKey, now time.Time) error {
+	if s.loginAttempts == nil {
+		return nil
+	}
+
+	for _, k := range keys {
+		a, err := s.loginAttempts.Fail(ctx, k.key, now.Add(-s.authConfig.LockoutWindow))
+		if err != nil {
+			return err
+		}
+		if a.Failures%k.threshold != 0 {
+			continue
+		}
+
+		d := s.authConfig.LockoutDuration
+		for i := 1; i < a.Failures/k.threshold && d < maxLockoutDuration; i++ {
+			d *= 2
+		}
+		if err := s.loginAttempts.Lock(ctx, k.key, now.Add(min(d, maxLockoutDuration))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unlock lifts sign in lockout of the user and forgets its failed attempts.
+// Lockout of the client IP is not affected.
+func (s UserService) Unlock(ctx context.Context, id uuid.UUID) error {
+	if s.loginAttempts == nil {
+		return ErrLoginAttemptsNotConfigured
+	}
+
+	u, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// attempts are tracked under the username typed at sign in, directory users type their directory username,
+	// users signing in with identity provider only have neither and can not be locked out
+	var usernames []string
+	if u.Credentials != nil {
+		usernames = append(usernames, u.Credentials.Username)
+	}
+	for _, identity := range u.Identities {
+		if identity.Provider == DirectoryProvider {
+			usernames = append(usernames, identity.Subject)
+		}
+	}
+
+	for _, username := range usernames {
+		if err := s.loginAttempts.Reset(ctx, usernameKey(username)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usernameKey returns key attempts are tracked under, it is case insensitive so letter case does not bypass the lockout.
+func usernameKey(username string) string {
+	return "username:" + strings.ToLower(username)
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/adapters/memory"
+	"github.com/fmiskovic/go-starter/internal/core/configs"
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	"github.com/fmiskovic/go-starter/internal/core/ports"
+	"github.com/google/uuid"
+	"github.com/matryer/is"
+)
+
+// userRepo returns the same user for any id.
+type userRepo struct {
+	ports.UserRepo[uuid.UUID]
+	u *user.User
+}
+
+func (r userRepo) GetById(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	return r.u, nil
+}
+
+func TestUserService_Unlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     *user.User
+		username string // username typed at sign in
+		wantLock bool
+	}{
+		{
+			name:     "given user with credentials should unlock its username",
+			user:     user.New(user.Credentials(security.NewCredentials("username1", "hash"))),
+			username: "Username1",
+		},
+		{
+			name:     "given directory user without credentials should unlock its directory username",
+			user:     user.New(user.Identities(security.NewIdentity(DirectoryProvider, "jdoe", "jdoe@example.com"))),
+			username: "jdoe",
+		},
+		{
+			name:     "given identity provider user without credentials should not fail",
+			user:     user.New(user.Identities(security.NewIdentity("google", "1234567890", "john@doe.com"))),
+			username: "john@doe.com",
+			wantLock: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := is.New(t)
+			ctx := context.Background()
+
+			attempts := memory.NewLoginAttemptStore()
+			s := NewUserService(userRepo{u: tt.user}, configs.NewAuthConfig(), WithLoginAttemptStore(attempts))
+
+			key := usernameKey(tt.username)
+			_, err := attempts.Fail(ctx, key, time.Now().Add(-time.Hour))
+			assert.NoErr(err)
+			assert.NoErr(attempts.Lock(ctx, key, time.Now().Add(time.Hour)))
+
+			assert.NoErr(s.Unlock(ctx, tt.user.ID))
+
+			a, err := attempts.Get(ctx, key)
+			assert.NoErr(err)
+			assert.Equal(a.IsLocked(time.Now()), tt.wantLock)
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/utils/token"
+	"github.com/fmiskovic/go-starter/internal/utils/totp"
+	"github.com/google/uuid"
+)
+
+const (
+	// number of recovery codes issued when two-factor authentication is enabled
+	recoveryCodeCount = 10
+	// number of wrong codes after which sign in challenge can not be used anymore
+	maxMfaAttempts = 5
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EnrollMfa generates new pending TOTP secret for the user.
+// Returns the secret and otpauth uri to be imported into authenticator app.
+func (s UserService) EnrollMfa(ctx context.Context, id uuid.UUID) (*user.MfaEnrollResponse, error) {
+	if s.mfaRepo == nil {
+		return nil, ErrMfaRepoNotConfigured
+	}
+
+	u, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.SaveSecret(ctx, security.NewMfaSecret(u.ID, secret)); err != nil {
+		return nil, err
+	}
+
+	return &user.MfaEnrollResponse{
+		Secret: secret,
+		URI:    totp.URI(s.authConfig.MfaIssuer, u.Email, secret),
+	}, nil
+}
+
+// EnableMfa enables pending TOTP secret once the user proves possession with a valid code.
+// Returns recovery codes, they are shown only once.
+func (s UserService) EnableMfa(ctx context.Context, req *user.MfaCodeRequest) (*user.RecoveryCodesResponse, error) {
+	if s.mfaRepo == nil {
+		return nil, ErrMfaRepoNotConfigured
+	}
+
+	id, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, apiErr.ErrInvalidId
+	}
+
+	secret, err := s.mfaRepo.GetSecret(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apiErr.ErrMfaNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret.IsEnabled() {
+		return nil, apiErr.ErrMfaEnabled
+	}
+
+	step, ok := totp.Validate(secret.Secret, req.Code, time.Now())
+	if !ok {
+		return nil, apiErr.ErrInvalidCode
+	}
+
+	plain := make([]string, recoveryCodeCount)
+	codes := make([]*security.RecoveryCode, recoveryCodeCount)
+	for i := range plain {
+		if plain[i], err = newRecoveryCode(); err != nil {
+			return nil, err
+		}
+		codes[i] = security.NewRecoveryCode(id, token.Hash(normalizeRecoveryCode(plain[i])))
+	}
+
+	if err := s.mfaRepo.Enable(ctx, id, step, codes); err != nil {
+		return nil, err
+	}
+	return &user.RecoveryCodesResponse{RecoveryCodes: plain}, nil
+}
+
+// DisableMfa turns two-factor authentication off, the user has to confirm it with TOTP or recovery code.
+func (s UserService) DisableMfa(ctx context.Context, req *user.MfaCodeRequest) error {
+	if s.mfaRepo == nil {
+		return ErrMfaRepoNotConfigured
+	}
+
+	id, err := uuid.Parse(req.ID)
+	if err != nil {
+		return apiErr.ErrInvalidId
+	}
+
+	secret, err := s.enabledMfaSecret(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.verifySecondFactor(ctx, secret, req.Code); err != nil {
+		return err
+	}
+
+	return s.mfaRepo.DeleteByUserId(ctx, id)
+}
+
+// ResetMfa turns two-factor authentication off without the second factor.
+// This function is for admin user only, e.g. when user lost both authenticator and recovery codes.
+func (s UserService) ResetMfa(ctx context.Context, id uuid.UUID) error {
+	if s.mfaRepo == nil {
+		return ErrMfaRepoNotConfigured
+	}
+	return s.mfaRepo.DeleteByUserId(ctx, id)
+}
+
+// VerifyMfa completes sign in started by SingIn.
+// Returns new signed jwt access token and refresh token that starts a new token family.
+func (s UserService) VerifyMfa(ctx context.Context, req *user.VerifyMfaRequest) (*user.SignInResponse, error) {
+	if s.mfaRepo == nil {
+		return nil, ErrMfaRepoNotConfigured
+	}
+
+	c, err := s.mfaRepo.GetChallenge(ctx, token.Hash(req.MfaToken))
+	if err != nil {
+		return nil, err
+	}
+	if c.IsExpired() {
+		return nil, apiErr.ErrExpiredToken
+	}
+	if c.Attempts >= maxMfaAttempts {
+		return nil, apiErr.ErrInvalidToken
+	}
+
+	secret, err := s.enabledMfaSecret(ctx, c.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySecondFactor(ctx, secret, req.Code); err != nil {
+		if errors.Is(err, apiErr.ErrInvalidCode) {
+			if err := s.mfaRepo.FailChallenge(ctx, c.ID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	// challenge is single-use
+	if err := s.mfaRepo.DeleteChallenge(ctx, c.ID); err != nil {
+		return nil, err
+	}
+
+	u, err := s.repo.GetById(ctx, c.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !u.Enabled {
+		return nil, apiErr.ErrUserDisabled
+	}
+
+	return s.issueTokens(ctx, u, uuid.New())
+}
+
+// mfaChallenge starts second step of the sign in if user has enabled two-factor authentication.
+// Returns nil response if the second factor is not required.
+func (s UserService) mfaChallenge(ctx context.Context, u *user.User) (*user.SignInResponse, error) {
+	if s.mfaRepo == nil {
+		return nil, nil
+	}
+
+	secret, err := s.mfaRepo.GetSecret(ctx, u.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !secret.IsEnabled() {
+		return nil, nil
+	}
+
+	plain, err := token.Generate()
+	if err != nil {
+		return nil, err
+	}
+	c := security.NewMfaChallenge(u.ID, token.Hash(plain), time.Now().Add(s.authConfig.MfaChallengeExp))
+	if err := s.mfaRepo.CreateChallenge(ctx, c); err != nil {
+		return nil, err
+	}
+
+	return &user.SignInResponse{MfaRequired: true, MfaToken: plain}, nil
+}
+
+// enabledMfaSecret returns TOTP secret of the user or apiErr.ErrMfaNotEnabled.
+func (s UserService) enabledMfaSecret(ctx context.Context, id uuid.UUID) (*security.MfaSecret, error) {
+	secret, err := s.mfaRepo.GetSecret(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apiErr.ErrMfaNotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !secret.IsEnabled() {
+		return nil, apiErr.ErrMfaNotEnabled
+	}
+	return secret, nil
+}
+
+// verifySecondFactor accepts either TOTP code or one of the recovery codes, each of them can be used only once.
+func (s UserService) verifySecondFactor(ctx context.Context, secret *security.MfaSecret, code string) error {
+	if step, ok := totp.Validate(secret.Secret, code, time.Now()); ok {
+		return s.mfaRepo.UseStep(ctx, secret.UserID, step)
+	}
+	return s.mfaRepo.UseRecoveryCode(ctx, secret.UserID, token.Hash(normalizeRecoveryCode(code)))
+}
+
+// newRecoveryCode generates random code formatted for easier typing, e.g. "abcde-fghij".
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode makes recovery code comparison case and separator insensitive.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/core/ports"
+	"github.com/fmiskovic/go-starter/internal/utils/token"
+	"github.com/google/uuid"
+)
+
+// StartOidcLogin starts authorization code flow with the identity provider.
+// Returned state, nonce and PKCE verifier have to be kept by the caller and passed to CompleteOidcLogin.
+func (s UserService) StartOidcLogin(ctx context.Context, provider string) (*user.OidcLoginResponse, error) {
+	p, ok := s.identityProviders[provider]
+	if !ok {
+		return nil, apiErr.ErrUnknownProvider
+	}
+
+	res := &user.OidcLoginResponse{}
+	for _, v := range []*string{&res.State, &res.Nonce, &res.Verifier} {
+		t, err := token.Generate()
+		if err != nil {
+			return nil, err
+		}
+		*v = t
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, res.State, res.Nonce, res.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	res.URL = authURL
+	return res, nil
+}
+
+// CompleteOidcLogin exchanges authorization code for the identity of the user and signs the user in.
+// User signing in for the first time is linked to the existing account with the same verified email,
+// otherwise new user with provider's default roles is provisioned.
+func (s UserService) CompleteOidcLogin(ctx context.Context, req *user.OidcCallbackRequest) (*user.SignInResponse, error) {
+	p, ok := s.identityProviders[req.Provider]
+	if !ok {
+		return nil, apiErr.ErrUnknownProvider
+	}
+	if subtle.ConstantTimeCompare([]byte(req.State), []byte(req.ExpectedState)) != 1 {
+		return nil, apiErr.ErrInvalidState
+	}
+
+	claims, err := p.Exchange(ctx, req.Code, req.Verifier, req.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := s.identityUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.Enabled {
+		return nil, apiErr.ErrUserDisabled
+	}
+
+	// tokens are issued by VerifyMfa if the second factor is required
+	if res, err := s.mfaChallenge(ctx, u); err != nil || res != nil {
+		return res, err
+	}
+
+	return s.issueTokens(ctx, u, uuid.New())
+}
+
+// identityUser returns user linked to the identity, linking or provisioning the user on the first sign in.
+func (s UserService) identityUser(ctx context.Context, claims *security.IdentityClaims) (*user.User, error) {
+	return linkIdentity(ctx, s.repo, claims, s.defaultRoles(claims.Provider))
+}
+
+// linkIdentity returns user linked to the external identity.
+// On the first sign in identity is linked to the user with the same email, if the email is verified,
+// otherwise new user with the specified roles is provisioned.
+func linkIdentity(ctx context.Context, repo ports.UserRepo[uuid.UUID], claims *security.IdentityClaims, roles []string) (*user.User, error) {
+	u, err := repo.GetByIdentity(ctx, claims.Provider, claims.Subject)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return u, err
+	}
+
+	if claims.Email == "" {
+		return nil, apiErr.ErrEmailRequired
+	}
+	identity := security.NewIdentity(claims.Provider, claims.Subject, claims.Email)
+
+	existing, err := repo.GetByEmail(ctx, claims.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if existing != nil {
+		// otherwise anyone could take over the account by registering its email with the provider
+		if !claims.EmailVerified {
+			return nil, apiErr.ErrEmailNotVerified
+		}
+		identity.UserID = existing.ID
+		if err := repo.AddIdentity(ctx, identity); err != nil {
+			return nil, err
+		}
+		return repo.GetById(ctx, existing.ID)
+	}
+
+	var catalogRoles []*security.Role
+	for _, name := range roles {
+		catalogRoles = append(catalogRoles, security.NewRole(name))
+	}
+	u = user.New(
+		user.Email(claims.Email),
+		user.FullName(claims.Name),
+		user.Enabled(true),
+		user.Identities(identity),
+		user.Roles(catalogRoles...),
+	)
+	if err := repo.Create(ctx, u); err != nil {
+		return nil, err
+	}
+	// reloaded so the token carries permissions of the assigned roles
+	return repo.GetById(ctx, u.ID)
+}
+
+// defaultRoles returns roles of users provisioned on the first sign in with the provider.
+func (s UserService) defaultRoles(provider string) []string {
+	for _, p := range s.authConfig.OidcProviders {
+		if p.Name == provider && len(p.DefaultRoles) > 0 {
+			return p.DefaultRoles
+		}
+	}
+	return []string{security.ROLE_USER}
+}
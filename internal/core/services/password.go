@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+)
+
+// minIdentityLen is the shortest username or email part the password is checked not to contain,
+// shorter ones would reject too many good passwords.
+const minIdentityLen = 3
+
+// checkPassword returns apiErr.PasswordPolicyError listing rules of the password policy the new password breaks.
+// Identity of the user, such as username and email, is passed to check the password does not contain it.
+// Password is checked against breached passwords only if it passes all other rules.
+func (s UserService) checkPassword(ctx context.Context, pwd string, identity ...string) error {
+	p := s.authConfig.PasswordPolicy
+
+	var violations []string
+	if utf8.RuneCountInString(pwd) < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && len(pwd) > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes long", p.MaxLength))
+	}
+	if p.RequireUpper && !strings.ContainsFunc(pwd, unicode.IsUpper) {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !strings.ContainsFunc(pwd, unicode.IsLower) {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !strings.ContainsFunc(pwd, unicode.IsDigit) {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.RequireSymbol && !strings.ContainsFunc(pwd, isSymbol) {
+		violations = append(violations, "must contain a symbol")
+	}
+	if p.DisallowIdentity && containsIdentity(pwd, identity) {
+		violations = append(violations, "must not contain the username or email")
+	}
+
+	if len(violations) == 0 && s.breached != nil {
+		breached, err := s.breached.IsBreached(ctx, pwd)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, "is known from data breaches, choose another one")
+		}
+	}
+
+	if len(violations) > 0 {
+		return apiErr.PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// checkPasswordHistory returns apiErr.PasswordPolicyError if the new password is the current password of the user
+// or any of the previous ones kept by the password history.
+func (s UserService) checkPasswordHistory(ctx context.Context, u *user.User, pwd string) error {
+	n := s.authConfig.PasswordPolicy.History
+	if n <= 0 || u.Credentials == nil {
+		return nil
+	}
+
+	hashes, err := s.repo.GetPasswordHistory(ctx, u.ID, n-1)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range append([]string{u.Credentials.Password}, hashes...) {
+		ok, err := s.hashing.Verify(ctx, pwd, hash)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return apiErr.PasswordPolicyError{Violations: []string{fmt.Sprintf("must not be any of the last %d passwords", n)}}
+		}
+	}
+	return nil
+}
+
+// passwordHistorySize returns number of replaced passwords kept in the history, the current one is kept in credentials.
+func (s UserService) passwordHistorySize() int {
+	return max(s.authConfig.PasswordPolicy.History-1, 0)
+}
+
+// containsIdentity returns true if the password contains any of the identities or local part of the email, ignoring case.
+func containsIdentity(pwd string, identity []string) bool {
+	pwd = strings.ToLower(pwd)
+	for _, id := range identity {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if local, _, ok := strings.Cut(id, "@"); ok && len(local) >= minIdentityLen && strings.Contains(pwd, local) {
+			return true
+		}
+		if len(id) >= minIdentityLen && strings.Contains(pwd, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSymbol returns true for characters that are neither letters nor digits nor spaces.
+func isSymbol(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
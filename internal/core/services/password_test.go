@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fmiskovic/go-starter/internal/core/configs"
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/core/ports"
+	"github.com/fmiskovic/go-starter/internal/utils/token"
+	"github.com/google/uuid"
+	"github.com/matryer/is"
+)
+
+// plainHasher keeps passwords as they are, so tests do not pay for real hashing.
+type plainHasher struct{}
+
+func (plainHasher) Hash(pwd string) (string, error) {
+	return pwd, nil
+}
+
+func (plainHasher) Verify(pwd string, hash string) (bool, error) {
+	return pwd == hash, nil
+}
+
+func (plainHasher) NeedsRehash(hash string) bool {
+	return false
+}
+
+// resetRepo resets password of the user the token was issued to.
+type resetRepo struct {
+	ports.UserRepo[uuid.UUID]
+	u         *user.User
+	tokenHash string
+	history   []string
+	reset     string
+}
+
+func (r *resetRepo) GetByResetToken(ctx context.Context, tokenHash string) (*user.User, error) {
+	if tokenHash != r.tokenHash {
+		return nil, apiErr.ErrInvalidToken
+	}
+	return r.u, nil
+}
+
+func (r *resetRepo) GetPasswordHistory(ctx context.Context, id uuid.UUID, limit int) ([]string, error) {
+	return r.history[:min(limit, len(r.history))], nil
+}
+
+func (r *resetRepo) ResetPassword(ctx context.Context, tokenHash string, passwordHash string, historySize int) (uuid.UUID, error) {
+	r.reset = passwordHash
+	return r.u.ID, nil
+}
+
+func TestUserService_ResetPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		password string
+		wantErr  error
+		policy   bool // password breaks the policy
+	}{
+		{
+			name:     "given valid token and password should reset password",
+			token:    "reset-token",
+			password: "NewPassword1!",
+		},
+		{
+			name:     "given unknown token should return error",
+			token:    "unknown-token",
+			password: "NewPassword1!",
+			wantErr:  apiErr.ErrInvalidToken,
+		},
+		{
+			name:     "given password containing username should return policy error",
+			token:    "reset-token",
+			password: "Username1234!",
+			policy:   true,
+		},
+		{
+			name:     "given password containing email should return policy error",
+			token:    "reset-token",
+			password: "JohnDoe1234!",
+			policy:   true,
+		},
+		{
+			name:     "given current password should return policy error",
+			token:    "reset-token",
+			password: "Current1234!",
+			policy:   true,
+		},
+		{
+			name:     "given previous password should return policy error",
+			token:    "reset-token",
+			password: "Previous1234!",
+			policy:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := is.New(t)
+
+			repo := &resetRepo{
+				u: user.New(
+					user.Email("johndoe@example.com"),
+					user.Credentials(security.NewCredentials("username1", "Current1234!")),
+				),
+				tokenHash: token.Hash("reset-token"),
+				history:   []string{"Previous1234!"},
+			}
+			authConfig := configs.NewAuthConfig()
+			authConfig.PasswordPolicy.History = 3
+			s := NewUserService(repo, authConfig, WithHashingPool(NewHashingPool(plainHasher{}, 0, 0)))
+
+			err := s.ResetPassword(context.Background(), &user.ResetPasswordRequest{Token: tt.token, NewPassword: tt.password})
+			if tt.wantErr != nil || tt.policy {
+				var policy apiErr.PasswordPolicyError
+				assert.True(errors.Is(err, tt.wantErr) || (tt.policy && errors.As(err, &policy)))
+				assert.Equal(repo.reset, "") // password is not replaced
+				return
+			}
+			assert.NoErr(err)
+			assert.Equal(repo.reset, tt.password)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/core/ports"
+	"github.com/google/uuid"
+)
+
+// RoleService manages role and permission catalog.
+type RoleService struct {
+	repo ports.RoleRepo[uuid.UUID]
+}
+
+// NewRoleService instantiate new RoleService.
+func NewRoleService(repo ports.RoleRepo[uuid.UUID]) RoleService {
+	return RoleService{repo: repo}
+}
+
+// GetById returns existing role.
+func (s RoleService) GetById(ctx context.Context, id uuid.UUID) (*security.RoleDto, error) {
+	r, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return security.ConvertToRoleDto(r), nil
+}
+
+// GetAll returns all catalog roles.
+func (s RoleService) GetAll(ctx context.Context) ([]*security.RoleDto, error) {
+	roles, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*security.RoleDto, len(roles))
+	for i, r := range roles {
+		res[i] = security.ConvertToRoleDto(r)
+	}
+	return res, nil
+}
+
+// Create adds new role into the catalog.
+func (s RoleService) Create(ctx context.Context, req *security.RoleRequest) (*security.RoleDto, error) {
+	r := security.NewRole(req.Name, security.RoleDescription(req.Description))
+	for _, name := range req.Permissions {
+		r.Permissions = append(r.Permissions, &security.Permission{Name: name})
+	}
+
+	if err := s.repo.Create(ctx, r); err != nil {
+		return nil, err
+	}
+	return security.ConvertToRoleDto(r), nil
+}
+
+// Update updates existing role description.
+func (s RoleService) Update(ctx context.Context, req *security.UpdateRoleRequest) (*security.RoleDto, error) {
+	id, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &security.Role{Description: req.Description}
+	r.ID = id
+	if err := s.repo.Update(ctx, r); err != nil {
+		return nil, err
+	}
+	return s.GetById(ctx, id)
+}
+
+// DeleteById removes existing role, built-in roles can not be removed.
+func (s RoleService) DeleteById(ctx context.Context, id uuid.UUID) error {
+	r, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if r.IsBuiltIn() {
+		return apiErr.ErrBuiltInRole
+	}
+	return s.repo.DeleteById(ctx, id)
+}
+
+// SetPermissions replaces role permissions.
+// Tokens issued before the change keep their scopes until they expire.
+func (s RoleService) SetPermissions(ctx context.Context, req *security.RolePermissionsRequest) error {
+	id, err := uuid.Parse(req.ID)
+	if err != nil {
+		return err
+	}
+	return s.repo.SetPermissions(ctx, id, req.Permissions)
+}
+
+// GetPermissions returns all catalog permissions.
+func (s RoleService) GetPermissions(ctx context.Context) ([]*security.PermissionDto, error) {
+	perms, err := s.repo.GetPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*security.PermissionDto, len(perms))
+	for i, p := range perms {
+		res[i] = security.ConvertToPermissionDto(p)
+	}
+	return res, nil
+}
+
+// CreatePermission adds new permission into the catalog.
+func (s RoleService) CreatePermission(ctx context.Context, req *security.PermissionRequest) (*security.PermissionDto, error) {
+	p := security.NewPermission(req.Name, req.Description)
+	if err := s.repo.CreatePermission(ctx, p); err != nil {
+		return nil, err
+	}
+	return security.ConvertToPermissionDto(p), nil
+}
+
+// DeletePermissionById removes existing permission, built-in permissions can not be removed.
+func (s RoleService) DeletePermissionById(ctx context.Context, id uuid.UUID) error {
+	p, err := s.repo.GetPermissionById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if security.IsBuiltInPermission(p.Name) {
+		return apiErr.ErrBuiltInPermission
+	}
+	return s.repo.DeletePermissionById(ctx, id)
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/utils/token"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// StartSession signs user in to the UI and returns token of the new session.
+// User with enabled two-factor authentication has to send the code together with the password,
+// apiErr.ErrMfaRequired is returned if it is missing.
+func (s UserService) StartSession(ctx context.Context, req *user.SessionRequest) (*user.SessionResponse, error) {
+	if s.sessions == nil {
+		return nil, ErrSessionStoreNotConfigured
+	}
+
+	signIn := &user.SignInRequest{Username: req.Username, Password: req.Password, ClientIP: req.ClientIP}
+	u, err := s.authenticate(ctx, signIn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verifySessionMfa(ctx, u, req.Code); err != nil {
+		// wrong codes count towards the lockout like wrong passwords, otherwise the code could be guessed
+		if errors.Is(err, apiErr.ErrInvalidCode) {
+			if err := s.loginFailed(ctx, s.loginKeys(signIn), time.Now()); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	plain, err := token.Generate()
+	if err != nil {
+		return nil, err
+	}
+	session := security.NewSession(u.ID, token.Hash(plain), time.Now().Add(s.authConfig.SessionExp))
+	if err := s.sessions.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return &user.SessionResponse{Token: plain, ExpiresAt: session.ExpiresAt}, nil
+}
+
+// AuthenticateSession returns claims of the user signed in with the session token, they are used in place of access token claims.
+// Session of the user that got disabled is ended.
+func (s UserService) AuthenticateSession(ctx context.Context, sessionToken string) (jwt.MapClaims, error) {
+	if s.sessions == nil {
+		return nil, ErrSessionStoreNotConfigured
+	}
+
+	session, err := s.sessions.Get(ctx, token.Hash(sessionToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apiErr.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.IsExpired() {
+		return nil, apiErr.ErrExpiredToken
+	}
+
+	u, err := s.repo.GetById(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !u.Enabled {
+		if err := s.sessions.Delete(ctx, session.TokenHash); err != nil {
+			return nil, err
+		}
+		return nil, apiErr.ErrUserDisabled
+	}
+
+	claims := s.accessClaims(u, session.ID, time.Now())
+	claims["exp"] = session.ExpiresAt.Unix()
+	return claims, nil
+}
+
+// EndSession signs user out of the UI, unknown session token is not an error.
+func (s UserService) EndSession(ctx context.Context, sessionToken string) error {
+	if s.sessions == nil {
+		return ErrSessionStoreNotConfigured
+	}
+	return s.sessions.Delete(ctx, token.Hash(sessionToken))
+}
+
+// verifySessionMfa verifies the second factor if user has enabled two-factor authentication.
+func (s UserService) verifySessionMfa(ctx context.Context, u *user.User, code string) error {
+	if s.mfaRepo == nil {
+		return nil
+	}
+
+	secret, err := s.enabledMfaSecret(ctx, u.ID)
+	if errors.Is(err, apiErr.ErrMfaNotEnabled) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if code == "" {
+		return apiErr.ErrMfaRequired
+	}
+	return s.verifySecondFactor(ctx, secret, code)
+}
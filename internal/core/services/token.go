@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/domain/security"
+	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
+	"github.com/fmiskovic/go-starter/internal/utils/token"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Refresh rotates refresh token and issues new access token.
+// Presenting already rotated refresh token revokes the whole token family.
+func (s UserService) Refresh(ctx context.Context, req *user.RefreshRequest) (*user.SignInResponse, error) {
+	if s.refreshRepo == nil {
+		return nil, ErrRefreshRepoNotConfigured
+	}
+
+	now := time.Now()
+	plain, next, err := s.newRefreshToken(uuid.Nil, uuid.Nil, now)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, err := s.refreshRepo.Rotate(ctx, token.Hash(req.RefreshToken), next)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := s.repo.GetById(ctx, rotated.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !u.Enabled {
+		if err := s.refreshRepo.RevokeFamily(ctx, rotated.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, apiErr.ErrUserDisabled
+	}
+
+	accessToken, err := s.signAccessToken(u, rotated.FamilyID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user.SignInResponse{
+		Token:        accessToken,
+		RefreshToken: plain,
+		ExpiresIn:    int64(s.authConfig.TokenExp.Seconds()),
+	}, nil
+}
+
+// issueTokens issues new access token and refresh token that belongs to the specified family.
+func (s UserService) issueTokens(ctx context.Context, u *user.User, familyID uuid.UUID) (*user.SignInResponse, error) {
+	if s.refreshRepo == nil {
+		return nil, ErrRefreshRepoNotConfigured
+	}
+
+	now := time.Now()
+
+	accessToken, err := s.signAccessToken(u, familyID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, rt, err := s.newRefreshToken(u.ID, familyID, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refreshRepo.Create(ctx, rt); err != nil {
+		return nil, err
+	}
+
+	return &user.SignInResponse{
+		Token:        accessToken,
+		RefreshToken: plain,
+		ExpiresIn:    int64(s.authConfig.TokenExp.Seconds()),
+	}, nil
+}
+
+// SignOut revokes the access token and refresh token family it was issued with.
+func (s UserService) SignOut(ctx context.Context, req *user.SignOutRequest) error {
+	if s.revocations == nil {
+		return ErrRevocationNotConfigured
+	}
+
+	tokenID, err := uuid.Parse(req.TokenID)
+	if err != nil {
+		return apiErr.ErrInvalidToken
+	}
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return apiErr.ErrInvalidId
+	}
+
+	if err := s.revocations.Revoke(ctx, tokenID, userID, req.ExpiresAt); err != nil {
+		return err
+	}
+
+	sessionID, err := uuid.Parse(req.SessionID)
+	if err != nil || s.refreshRepo == nil {
+		return nil
+	}
+	return s.refreshRepo.RevokeFamily(ctx, sessionID)
+}
+
+// SignOutAll revokes all access and refresh tokens and API keys issued to the user and ends its UI sessions.
+func (s UserService) SignOutAll(ctx context.Context, id uuid.UUID) error {
+	if s.revocations == nil {
+		return ErrRevocationNotConfigured
+	}
+	return s.revokeSessions(ctx, id)
+}
+
+// revokeSessions revokes all access and refresh tokens, API keys and UI sessions of the user with stores that are configured.
+func (s UserService) revokeSessions(ctx context.Context, id uuid.UUID) error {
+	if s.revocations != nil {
+		if err := s.revocations.RevokeAll(ctx, id, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	// API keys are not checked against the revocation, leaked key would outlive password reset otherwise
+	if s.apiKeyRepo != nil {
+		if err := s.apiKeyRepo.RevokeAll(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	if s.sessions != nil {
+		if err := s.sessions.DeleteAll(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	if s.refreshRepo == nil {
+		return nil
+	}
+	return s.refreshRepo.RevokeAll(ctx, id)
+}
+
+// renewSessions revokes all sessions of the user and issues new token pair to the caller, so only the caller stays signed in.
+// Nil is returned if refresh tokens are not configured.
+func (s UserService) renewSessions(ctx context.Context, u *user.User) (*user.SignInResponse, error) {
+	if err := s.revokeSessions(ctx, u.ID); err != nil {
+		return nil, err
+	}
+	if s.refreshRepo == nil {
+		return nil, nil
+	}
+	return s.issueTokens(ctx, u, uuid.New())
+}
+
+// signAccessToken creates new short-lived signed jwt for the user.
+// Session ID claim refers to the refresh token family the access token was issued with.
+func (s UserService) signAccessToken(u *user.User, sessionID uuid.UUID, now time.Time) (string, error) {
+	// Generate signed token with the active key
+	return s.keys.Sign(s.accessClaims(u, sessionID, now))
+}
+
+// accessClaims creates claims of the access token issued to the user.
+func (s UserService) accessClaims(u *user.User, sessionID uuid.UUID, now time.Time) jwt.MapClaims {
+	var roles []string
+	for _, role := range u.Roles {
+		roles = append(roles, role.Name)
+	}
+
+	return jwt.MapClaims{
+		"email": u.Email,
+		"sub":   u.ID,
+		"name":  u.FullName,
+		"roles": roles,
+		"scope": strings.Join(s.grantedScopes(u), " "),
+		"exp":   now.Add(s.authConfig.TokenExp).Unix(),
+		"iat":   now.Unix(),
+		"jti":   uuid.New(),
+		"sid":   sessionID,
+	}
+}
+
+// grantedScopes returns scopes of the user, configured scopes are granted to everyone, permissions depend on the roles.
+func (s UserService) grantedScopes(u *user.User) []string {
+	scopes := append([]string{}, s.authConfig.Scopes...)
+	return append(scopes, security.PermissionsOf(u.Roles...)...)
+}
+
+// newRefreshToken generates new opaque refresh token.
+// Returns plain token that is handed to the client and its hashed form to be persisted.
+func (s UserService) newRefreshToken(userID uuid.UUID, familyID uuid.UUID, now time.Time) (string, *security.RefreshToken, error) {
+	plain, err := token.Generate()
+	if err != nil {
+		return "", nil, err
+	}
+	rt := security.NewRefreshToken(userID, familyID, token.Hash(plain), now.Add(s.authConfig.RefreshTokenExp))
+	return plain, rt, nil
+}
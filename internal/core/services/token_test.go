@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fmiskovic/go-starter/internal/adapters/memory"
+	"github.com/fmiskovic/go-starter/internal/core/configs"
+	"github.com/fmiskovic/go-starter/internal/core/ports"
+	"github.com/google/uuid"
+	"github.com/matryer/is"
+)
+
+// apiKeyRepo records users whose keys are revoked.
+type apiKeyRepo struct {
+	ports.ApiKeyRepo[uuid.UUID]
+	revoked []uuid.UUID
+}
+
+func (r *apiKeyRepo) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	r.revoked = append(r.revoked, userID)
+	return nil
+}
+
+func TestUserService_SignOutAll(t *testing.T) {
+	assert := is.New(t)
+
+	keys := &apiKeyRepo{}
+	s := NewUserService(userRepo{}, configs.NewAuthConfig(),
+		WithRevocationStore(memory.NewRevocationStore()),
+		WithApiKeyRepo(keys),
+	)
+
+	id := uuid.New()
+	assert.NoErr(s.SignOutAll(context.Background(), id))
+	assert.Equal(keys.revoked, []uuid.UUID{id}) // API keys are revoked too
+}
@@ -2,84 +2,270 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/fmiskovic/go-starter/internal/core/configs"
 	"github.com/fmiskovic/go-starter/internal/core/domain"
 	"github.com/fmiskovic/go-starter/internal/core/domain/security"
 	"github.com/fmiskovic/go-starter/internal/core/domain/user"
+	apiErr "github.com/fmiskovic/go-starter/internal/core/error"
 	"github.com/fmiskovic/go-starter/internal/core/ports"
+	"github.com/fmiskovic/go-starter/internal/utils/jwks"
 	"github.com/fmiskovic/go-starter/internal/utils/password"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/fmiskovic/go-starter/internal/utils/token"
 	"github.com/google/uuid"
 )
 
+var (
+	ErrMailerNotConfigured      = errors.New("mailer is not configured")
+	ErrRefreshRepoNotConfigured = errors.New("refresh token repository is not configured")
+	ErrRevocationNotConfigured  = errors.New("revocation store is not configured")
+	ErrMfaRepoNotConfigured     = errors.New("mfa repository is not configured")
+
+	ErrLoginAttemptsNotConfigured = errors.New("login attempt store is not configured")
+	ErrApiKeyRepoNotConfigured    = errors.New("api key repository is not configured")
+	ErrSessionStoreNotConfigured  = errors.New("session store is not configured")
+)
+
 // UserService.
 type UserService struct {
-	repo       ports.UserRepo[uuid.UUID]
-	authConfig configs.AuthConfig
+	repo        ports.UserRepo[uuid.UUID]
+	authConfig  configs.AuthConfig
+	mailer      ports.Mailer
+	refreshRepo ports.RefreshTokenRepo[uuid.UUID]
+	revocations ports.RevocationStore[uuid.UUID]
+	mfaRepo     ports.MfaRepo[uuid.UUID]
+	keys        jwks.KeySet
+	hashing     *HashingPool
+
+	loginAttempts ports.LoginAttemptStore
+	apiKeyRepo    ports.ApiKeyRepo[uuid.UUID]
+
+	identityProviders map[string]ports.IdentityProvider
+	authenticator     ports.Authenticator
+	sessions          ports.SessionStore[uuid.UUID]
+	breached          ports.BreachedPasswords
 }
 
 // NewUserService instantiate new UserService.
-func NewUserService(userRepo ports.UserRepo[uuid.UUID], authConfig configs.AuthConfig) UserService {
-	return UserService{userRepo, authConfig}
+func NewUserService(userRepo ports.UserRepo[uuid.UUID], authConfig configs.AuthConfig, opts ...Option) UserService {
+	hashing := authConfig.PasswordHashing
+	s := &UserService{
+		repo:       userRepo,
+		authConfig: authConfig,
+		keys:       jwks.New(authConfig),
+		hashing:    NewHashingPool(password.NewHasher(hashing), hashing.Concurrency, hashing.QueueTimeout),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.authenticator == nil {
+		s.authenticator = NewPasswordAuthenticator(userRepo, s.hashing)
+	}
+	return *s
+}
+
+// Option func used to configure optional UserService dependencies.
+type Option func(s *UserService)
+
+// WithMailer sets mailer used for sending email confirmation codes.
+func WithMailer(m ports.Mailer) Option {
+	return func(s *UserService) {
+		s.mailer = m
+	}
+}
+
+// WithHashingPool sets pool used for hashing and verifying passwords instead of the one configured by the auth config.
+// The same pool should be shared with the authenticator, so the concurrency limit applies to all the operations.
+func WithHashingPool(p *HashingPool) Option {
+	return func(s *UserService) {
+		s.hashing = p
+	}
+}
+
+// WithRefreshTokenRepo sets repository used for persisting refresh tokens.
+func WithRefreshTokenRepo(r ports.RefreshTokenRepo[uuid.UUID]) Option {
+	return func(s *UserService) {
+		s.refreshRepo = r
+	}
+}
+
+// WithRevocationStore sets store used for revoking access tokens.
+func WithRevocationStore(r ports.RevocationStore[uuid.UUID]) Option {
+	return func(s *UserService) {
+		s.revocations = r
+	}
+}
+
+// WithLoginAttemptStore sets store used for tracking failed sign in attempts.
+// Sign in is not throttled if the store is not set.
+func WithLoginAttemptStore(r ports.LoginAttemptStore) Option {
+	return func(s *UserService) {
+		s.loginAttempts = r
+	}
+}
+
+// WithApiKeyRepo sets repository used for persisting API keys of machine clients.
+func WithApiKeyRepo(r ports.ApiKeyRepo[uuid.UUID]) Option {
+	return func(s *UserService) {
+		s.apiKeyRepo = r
+	}
+}
+
+// WithIdentityProviders sets external OpenID Connect providers users can sign in with, keyed by provider name.
+func WithIdentityProviders(providers map[string]ports.IdentityProvider) Option {
+	return func(s *UserService) {
+		s.identityProviders = providers
+	}
+}
+
+// WithAuthenticator sets authenticator used for verifying sign in credentials.
+// Password is checked against the hash kept in user credentials if it is not set.
+func WithAuthenticator(a ports.Authenticator) Option {
+	return func(s *UserService) {
+		s.authenticator = a
+	}
+}
+
+// WithSessionStore sets store used for keeping UI sessions.
+func WithSessionStore(r ports.SessionStore[uuid.UUID]) Option {
+	return func(s *UserService) {
+		s.sessions = r
+	}
+}
+
+// WithBreachedPasswords sets list of passwords known from data breaches new passwords are checked against.
+// New passwords are not checked against breached passwords if it is not set.
+func WithBreachedPasswords(b ports.BreachedPasswords) Option {
+	return func(s *UserService) {
+		s.breached = b
+	}
+}
+
+// WithMfaRepo sets repository used for two-factor authentication.
+func WithMfaRepo(r ports.MfaRepo[uuid.UUID]) Option {
+	return func(s *UserService) {
+		s.mfaRepo = r
+	}
 }
 
 // SingIn authenticates user.
-// Returns new signed jwt token.
+// Returns new signed jwt access token and refresh token that starts a new token family,
+// or MFA challenge token if user has enabled two-factor authentication.
+// Sign in is temporarily blocked after too many failed attempts of the username or client IP.
 func (s UserService) SingIn(ctx context.Context, req *user.SignInRequest) (*user.SignInResponse, error) {
-	u, err := s.repo.GetByUsername(ctx, req.Username)
+	u, err := s.authenticate(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	if !password.CheckPasswordHash(req.Password, u.Credentials.Password) {
-		return nil, errors.New("invalid credentials")
+	// tokens are issued by VerifyMfa if the second factor is required
+	if res, err := s.mfaChallenge(ctx, u); err != nil || res != nil {
+		return res, err
 	}
 
-	var roles []string
-	for _, role := range u.Roles {
-		roles = append(roles, role.Name)
-	}
+	return s.issueTokens(ctx, u, uuid.New())
+}
 
+// authenticate returns enabled user with matching username and password, failed attempts are counted towards the lockout.
+func (s UserService) authenticate(ctx context.Context, req *user.SignInRequest) (*user.User, error) {
 	now := time.Now()
-
-	// Create the Claims
-	claims := jwt.MapClaims{
-		"email": u.Email,
-		"sub":   u.ID,
-		"name":  u.FullName,
-		"roles": roles,
-		"exp":   now.Add(time.Hour * s.authConfig.TokenExp).Unix(),
-		"iat":   now.Unix(),
+	keys := s.loginKeys(req)
+	if err := s.checkLockout(ctx, keys, now); err != nil {
+		return nil, err
 	}
 
-	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	//Generate signed token and send it as response.
-	signedToken, err := token.SignedString([]byte(s.authConfig.Secret))
+	u, err := s.authenticator.Authenticate(ctx, req.Username, req.Password)
+	if errors.Is(err, apiErr.ErrInvalidCreds) {
+		if err := s.loginFailed(ctx, keys, now); err != nil {
+			return nil, err
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &user.SignInResponse{Token: signedToken}, nil
+	// failures of the client IP are kept, otherwise signing in to own account would reset them
+	if s.loginAttempts != nil {
+		if err := s.loginAttempts.Reset(ctx, usernameKey(req.Username)); err != nil {
+			return nil, err
+		}
+	}
+
+	if !u.Enabled {
+		return nil, apiErr.ErrUserDisabled
+	}
+	return u, nil
 }
 
 // ConfirmEmail enables user when user confirs it's email address.
 func (s UserService) ConfirmEmail(ctx context.Context, req user.ConfirmEmailRequest) error {
-	// TODO: implement
-	return nil
+	id, err := uuid.Parse(req.ID)
+	if err != nil {
+		return apiErr.ErrInvalidId
+	}
+	return s.repo.ConfirmEmail(ctx, id, token.Hash(req.Code))
+}
+
+// ChangeEmail sends confirmation code to the new email address of the user.
+// Email is changed once the code is confirmed, until then the user keeps signing in with the current one.
+func (s UserService) ChangeEmail(ctx context.Context, req user.ChangeEmailRequest) error {
+	if s.mailer == nil {
+		return ErrMailerNotConfigured
+	}
+
+	id, err := uuid.Parse(req.ID)
+	if err != nil {
+		return apiErr.ErrInvalidId
+	}
+
+	u, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.repo.GetByEmail(ctx, req.Email); err == nil {
+		return apiErr.ErrEmailTaken
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	code, err := token.Generate()
+	if err != nil {
+		return err
+	}
+
+	c := security.NewEmailChange(u.ID, req.Email, token.Hash(code), time.Now().Add(s.authConfig.ConfirmationExp))
+	if err := s.repo.SaveEmailConfirmation(ctx, c); err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi!\n\nTo confirm this is your new email address use the following id and code:\n\nid: %s\ncode: %s\n\nThe code expires at %s.",
+		u.ID, code, c.ExpiresAt.Format(time.RFC1123))
+
+	return s.mailer.Send(ctx, req.Email, "Confirm your new email address", body)
 }
 
 // SingUp register new user.
+// User is disabled until it confirms email address with the code sent by mail.
 func (s UserService) SingUp(ctx context.Context, req *user.CreateRequest) (*user.SignUpResponse, error) {
-	u, err := s.createUser(ctx, req)
+	if s.mailer == nil {
+		return nil, ErrMailerNotConfigured
+	}
+
+	u, err := s.createUser(ctx, req, false)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.sendEmailConfirmation(ctx, u); err != nil {
+		return nil, err
+	}
+
 	return &user.SignUpResponse{ID: u.ID.String()}, nil
 }
 
@@ -87,7 +273,7 @@ func (s UserService) SingUp(ctx context.Context, req *user.CreateRequest) (*user
 // This function is for admin user only.
 // Returns newly created user.
 func (s UserService) Create(ctx context.Context, req *user.CreateRequest) (*user.CreateResponse, error) {
-	u, err := s.createUser(ctx, req)
+	u, err := s.createUser(ctx, req, true)
 	if err != nil {
 		return nil, err
 	}
@@ -96,14 +282,19 @@ func (s UserService) Create(ctx context.Context, req *user.CreateRequest) (*user
 }
 
 // Update updates existing user.
+// Change must be based on the current version of the user, otherwise apiErr.ErrVersionMismatch is returned.
 // Returns user with fresh changes.
 func (s UserService) Update(ctx context.Context, req *user.UpdateRequest) (*user.UpdateResponse, error) {
 	id, err := uuid.Parse(req.ID)
 	if err != nil {
 		return nil, err
 	}
+	if req.Version <= 0 {
+		return nil, apiErr.ErrVersionRequired
+	}
 	u := user.New(
 		user.Id(id),
+		user.Version(req.Version),
 		user.Email(req.Email),
 		user.DateOfBirth(req.DateOfBirth),
 		user.FullName(req.FullName),
@@ -115,7 +306,36 @@ func (s UserService) Update(ctx context.Context, req *user.UpdateRequest) (*user
 		return nil, err
 	}
 
-	return &user.UpdateResponse{Dto: *user.ConvertToDto(u)}, nil
+	return user.NewUpdateResponse(u), nil
+}
+
+// Patch replaces profile of existing user with the patched one, fields missing from it are cleared.
+// Patch must be applied to the current version of the user, otherwise apiErr.ErrVersionMismatch is returned.
+func (s UserService) Patch(ctx context.Context, req *user.PatchRequest) (*user.UpdateResponse, error) {
+	id, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Version <= 0 {
+		return nil, apiErr.ErrVersionRequired
+	}
+	u := user.New(
+		user.Id(id),
+		user.Version(req.Version),
+		user.Email(req.Email),
+		user.FullName(req.FullName),
+		user.Location(req.Location),
+		user.Sex(req.Gender.Numberfy()),
+	)
+	if req.DateOfBirth != nil {
+		u.DateOfBirth = *req.DateOfBirth
+	}
+
+	if err = s.repo.UpdateProfile(ctx, u); err != nil {
+		return nil, err
+	}
+
+	return user.NewUpdateResponse(u), nil
 }
 
 // GetById returns existing user.
@@ -127,14 +347,27 @@ func (s UserService) GetById(ctx context.Context, id uuid.UUID) (*user.Dto, erro
 	return user.ConvertToDto(u), nil
 }
 
-// DeleteById deletes existing user.
+// DeleteById soft deletes user and signs it out everywhere, user can be restored until it is purged.
 func (s UserService) DeleteById(ctx context.Context, id uuid.UUID) error {
-	return s.repo.DeleteById(ctx, id)
+	if err := s.repo.DeleteById(ctx, id); err != nil {
+		return err
+	}
+	return s.revokeSessions(ctx, id)
+}
+
+// Restore brings back soft deleted user.
+func (s UserService) Restore(ctx context.Context, id uuid.UUID) error {
+	return s.repo.Restore(ctx, id)
 }
 
-// GetPage returns page of users.
-func (s UserService) GetPage(ctx context.Context, pagabale domain.Pageable) (*domain.Page[user.Dto], error) {
-	page, err := s.repo.GetPage(ctx, pagabale)
+// PurgeDeleted permanently removes users deleted longer than the retention ago and returns how many were removed.
+func (s UserService) PurgeDeleted(ctx context.Context) (int, error) {
+	return s.repo.Purge(ctx, time.Now().Add(-s.authConfig.DeletedUserRetention))
+}
+
+// GetPage returns page of users matching the filter.
+func (s UserService) GetPage(ctx context.Context, pagabale domain.Pageable, filter user.Filter) (*domain.Page[user.Dto], error) {
+	page, err := s.repo.GetPage(ctx, pagabale, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -151,9 +384,141 @@ func (s UserService) RemoveRoles(ctx context.Context, roles []string, id uuid.UU
 	return s.repo.RemoveRoles(ctx, roles, id)
 }
 
-// ChangePassword updates user password.
-func (s UserService) ChangePassword(ctx context.Context, req *user.ChangePasswordRequest) error {
-	return s.repo.ChangePassword(ctx, req)
+// ChangePassword replaces password of the signed in user once the old password is verified.
+// Wrong old password counts towards the sign in lockout, so a stolen token can not be used to guess it.
+// Other sessions of the user are revoked and the caller gets new token pair, nil if refresh tokens are not configured.
+func (s UserService) ChangePassword(ctx context.Context, req *user.ChangePasswordRequest) (*user.SignInResponse, error) {
+	id, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, apiErr.ErrInvalidId
+	}
+
+	u, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u.Credentials == nil {
+		return nil, apiErr.ErrInvalidPassword
+	}
+
+	now := time.Now()
+	keys := s.loginKeys(&user.SignInRequest{Username: u.Credentials.Username})
+	if err := s.checkLockout(ctx, keys, now); err != nil {
+		return nil, err
+	}
+	ok, err := s.hashing.Verify(ctx, req.OldPassword, u.Credentials.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.loginFailed(ctx, keys, now); err != nil {
+			return nil, err
+		}
+		return nil, apiErr.ErrInvalidPassword
+	}
+
+	if err := s.checkPassword(ctx, req.NewPassword, u.Credentials.Username, u.Email); err != nil {
+		return nil, err
+	}
+	if err := s.checkPasswordHistory(ctx, u, req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	pwdHash, err := s.hashing.Hash(ctx, req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdatePassword(ctx, id, pwdHash, s.passwordHistorySize()); err != nil {
+		return nil, err
+	}
+
+	return s.renewSessions(ctx, u)
+}
+
+// SetPassword is for admin usage only, to force new password of the user, e.g. when the account is compromised.
+// The password policy applies to the new password. All existing sessions of the user are revoked.
+func (s UserService) SetPassword(ctx context.Context, req *user.SetPasswordRequest) error {
+	id, err := uuid.Parse(req.ID)
+	if err != nil {
+		return apiErr.ErrInvalidId
+	}
+
+	u, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	var username string
+	if u.Credentials != nil {
+		username = u.Credentials.Username
+	}
+	if err := s.checkPassword(ctx, req.NewPassword, username, u.Email); err != nil {
+		return err
+	}
+	if err := s.checkPasswordHistory(ctx, u, req.NewPassword); err != nil {
+		return err
+	}
+
+	pwdHash, err := s.hashing.Hash(ctx, req.NewPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(ctx, id, pwdHash, s.passwordHistorySize()); err != nil {
+		return err
+	}
+
+	return s.revokeSessions(ctx, id)
+}
+
+// ForgotPassword sends single-use password reset token to the user with specified email address.
+// Unknown email address is not reported, so the endpoint can not be used to find out who is registered.
+func (s UserService) ForgotPassword(ctx context.Context, req *user.ForgotPasswordRequest) error {
+	if s.mailer == nil {
+		return ErrMailerNotConfigured
+	}
+
+	u, err := s.repo.GetByEmail(ctx, req.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.sendPasswordReset(ctx, u)
+}
+
+// ResetPassword replaces user password using the token sent by ForgotPassword.
+// All existing sessions of the user are revoked once the password is reset.
+func (s UserService) ResetPassword(ctx context.Context, req *user.ResetPasswordRequest) error {
+	tokenHash := token.Hash(req.Token)
+
+	u, err := s.repo.GetByResetToken(ctx, tokenHash)
+	if err != nil {
+		return err
+	}
+	if u.Credentials == nil {
+		return apiErr.ErrInvalidToken
+	}
+
+	if err := s.checkPassword(ctx, req.NewPassword, u.Credentials.Username, u.Email); err != nil {
+		return err
+	}
+	if err := s.checkPasswordHistory(ctx, u, req.NewPassword); err != nil {
+		return err
+	}
+
+	pwdHash, err := s.hashing.Hash(ctx, req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	id, err := s.repo.ResetPassword(ctx, tokenHash, pwdHash, s.passwordHistorySize())
+	if err != nil {
+		return err
+	}
+
+	return s.revokeSessions(ctx, id)
 }
 
 // EnableDisable is for admin usage only, to enable user if disabled and vice versa.
@@ -161,8 +526,12 @@ func (s UserService) EnableDisable(ctx context.Context, id uuid.UUID) error {
 	return s.repo.EnableDisable(ctx, id)
 }
 
-func (s UserService) createUser(ctx context.Context, req *user.CreateRequest) (*user.User, error) {
-	pwdHash, err := password.HashPassword(req.Password)
+func (s UserService) createUser(ctx context.Context, req *user.CreateRequest, enabled bool) (*user.User, error) {
+	if err := s.checkPassword(ctx, req.Password, req.Username, req.Email); err != nil {
+		return nil, err
+	}
+
+	pwdHash, err := s.hashing.Hash(ctx, req.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -174,6 +543,7 @@ func (s UserService) createUser(ctx context.Context, req *user.CreateRequest) (*
 		user.FullName(req.FullName),
 		user.Location(req.Location),
 		user.Sex(req.Gender.Numberfy()),
+		user.Enabled(enabled),
 		user.Credentials(crd),
 		user.Roles(role),
 	)
@@ -183,3 +553,44 @@ func (s UserService) createUser(ctx context.Context, req *user.CreateRequest) (*
 	}
 	return u, nil
 }
+
+// sendEmailConfirmation generates new confirmation code, persists its hash and sends the code to the user.
+func (s UserService) sendEmailConfirmation(ctx context.Context, u *user.User) error {
+	code, err := token.Generate()
+	if err != nil {
+		return err
+	}
+
+	c := security.NewEmailConfirmation(u.ID, token.Hash(code), time.Now().Add(s.authConfig.ConfirmationExp))
+	if err := s.repo.SaveEmailConfirmation(ctx, c); err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Welcome!\n\nTo confirm your email address use the following id and code:\n\nid: %s\ncode: %s\n\nThe code expires at %s.",
+		u.ID, code, c.ExpiresAt.Format(time.RFC1123))
+
+	return s.mailer.Send(ctx, u.Email, "Confirm your email address", body)
+}
+
+// sendPasswordReset generates new password reset token, persists its hash and sends the token to the user.
+func (s UserService) sendPasswordReset(ctx context.Context, u *user.User) error {
+	t, err := token.Generate()
+	if err != nil {
+		return err
+	}
+
+	r := security.NewPasswordReset(u.ID, token.Hash(t), time.Now().Add(s.authConfig.PasswordResetExp))
+	if err := s.repo.SavePasswordReset(ctx, r); err != nil {
+		return err
+	}
+
+	instructions := "use the following token:\n\n" + t
+	if s.authConfig.PasswordResetURL != "" {
+		instructions = "open the following link:\n\n" + s.authConfig.PasswordResetURL + "?token=" + url.QueryEscape(t)
+	}
+
+	body := fmt.Sprintf("Hello!\n\nTo reset your password %s\n\nThe token expires at %s and can be used only once. If you did not ask for a password reset, you can ignore this email.",
+		instructions, r.ExpiresAt.Format(time.RFC1123))
+
+	return s.mailer.Send(ctx, u.Email, "Reset your password", body)
+}
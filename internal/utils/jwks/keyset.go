@@ -0,0 +1,157 @@
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+
+	"github.com/fmiskovic/go-starter/internal/core/configs"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrNoSigningKey = configs.ErrNoSigningKey
+	ErrUnknownKey   = errors.New("token is signed with unknown key")
+	ErrKeyAlgorithm = errors.New("token algorithm does not match the key algorithm")
+)
+
+// KeySet signs and verifies tokens with asymmetric keys from configs.AuthConfig.
+// If no keys are configured it falls back to HS256 with the shared secret.
+type KeySet struct {
+	keys   map[string]configs.SigningKey
+	order  []string
+	active *configs.SigningKey
+	secret []byte
+}
+
+// New instantiate new KeySet from the auth config.
+func New(cfg configs.AuthConfig) KeySet {
+	ks := KeySet{
+		keys:   make(map[string]configs.SigningKey, len(cfg.Keys)),
+		secret: []byte(cfg.Secret),
+	}
+
+	for _, k := range cfg.Keys {
+		ks.keys[k.ID] = k
+		ks.order = append(ks.order, k.ID)
+	}
+	// config is validated at startup, so there is signing key if keys are configured
+	ks.active, _ = configs.ActiveSigningKey(cfg.Keys, cfg.ActiveKeyID)
+
+	return ks
+}
+
+// IsAsymmetric returns true if tokens are signed with asymmetric keys.
+func (ks KeySet) IsAsymmetric() bool {
+	return len(ks.keys) > 0
+}
+
+// Sign signs claims with the active key and sets its ID into the "kid" header.
+func (ks KeySet) Sign(claims jwt.Claims) (string, error) {
+	if !ks.IsAsymmetric() {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.secret)
+	}
+	if ks.active == nil {
+		return "", ErrNoSigningKey
+	}
+
+	t := jwt.NewWithClaims(jwt.GetSigningMethod(ks.active.Algorithm), claims)
+	t.Header["kid"] = ks.active.ID
+	return t.SignedString(ks.active.PrivateKey)
+}
+
+// Keyfunc resolves the key for verifying the token by its "kid" header.
+// It is meant to be used with jwt parser and jwt middleware.
+func (ks KeySet) Keyfunc(t *jwt.Token) (interface{}, error) {
+	if !ks.IsAsymmetric() {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, ErrKeyAlgorithm
+		}
+		return ks.secret, nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	k, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if t.Method.Alg() != k.Algorithm {
+		return nil, ErrKeyAlgorithm
+	}
+	return k.PublicKey, nil
+}
+
+// Methods returns signing algorithms accepted by the key set.
+func (ks KeySet) Methods() []string {
+	if !ks.IsAsymmetric() {
+		return []string{jwt.SigningMethodHS256.Alg()}
+	}
+
+	var methods []string
+	seen := make(map[string]bool)
+	for _, id := range ks.order {
+		alg := ks.keys[id].Algorithm
+		if !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// JWK represents public JSON Web Key as defined by RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet represents JSON Web Key Set.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKeys returns public parts of all keys, so other services can verify issued tokens.
+// Shared secret is never exposed, so the set is empty when asymmetric keys are not configured.
+func (ks KeySet) PublicKeys() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(ks.order))}
+	for _, id := range ks.order {
+		k := ks.keys[id]
+		jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+
+		switch pub := k.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = encode(pub.N.Bytes())
+			jwk.E = encode(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = pub.Curve.Params().Name
+			jwk.X = encode(pub.X.FillBytes(make([]byte, size)))
+			jwk.Y = encode(pub.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = encode(pub)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,228 @@
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/fmiskovic/go-starter/internal/core/configs"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestKeySet_SignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		key  any
+		alg  string
+	}{
+		{name: "given rsa key should sign with RS256", key: rsaKey, alg: "RS256"},
+		{name: "given P-256 key should sign with ES256", key: ecKey, alg: "ES256"},
+		{name: "given ed25519 key should sign with EdDSA", key: edKey, alg: "EdDSA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks := New(configs.NewAuthConfig(configs.SigningKeys(newKey(t, "key-1", tt.key))))
+
+			signed, err := ks.Sign(claims())
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+
+			token, err := jwt.Parse(signed, ks.Keyfunc, jwt.WithValidMethods(ks.Methods()))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if token.Header["kid"] != "key-1" {
+				t.Errorf("kid header = %v, want key-1", token.Header["kid"])
+			}
+			if token.Method.Alg() != tt.alg {
+				t.Errorf("alg = %s, want %s", token.Method.Alg(), tt.alg)
+			}
+		})
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	oldKey := newKey(t, "old", generateEC(t))
+	current := newKey(t, "new", generateEC(t))
+
+	// token issued before the rotation
+	signed, err := New(configs.NewAuthConfig(configs.SigningKeys(oldKey))).Sign(claims())
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// old key stays for verification only, new key signs
+	verifyOnly := configs.SigningKey{ID: oldKey.ID, Algorithm: oldKey.Algorithm, PublicKey: oldKey.PublicKey}
+	ks := New(configs.NewAuthConfig(configs.SigningKeys(verifyOnly, current)))
+
+	if _, err := jwt.Parse(signed, ks.Keyfunc); err != nil {
+		t.Errorf("Parse() token signed with old key error = %v", err)
+	}
+
+	rotated, err := ks.Sign(claims())
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	token, err := jwt.Parse(rotated, ks.Keyfunc)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if token.Header["kid"] != "new" {
+		t.Errorf("kid header = %v, want new", token.Header["kid"])
+	}
+}
+
+func TestKeySet_ActiveKeyID(t *testing.T) {
+	first := newKey(t, "first", generateEC(t))
+	second := newKey(t, "second", generateEC(t))
+
+	ks := New(configs.NewAuthConfig(configs.SigningKeys(first, second), configs.ActiveKeyID("second")))
+	signed, err := ks.Sign(claims())
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	token, err := jwt.Parse(signed, ks.Keyfunc)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if token.Header["kid"] != "second" {
+		t.Errorf("kid header = %v, want second", token.Header["kid"])
+	}
+}
+
+func TestKeySet_Reject(t *testing.T) {
+	key := newKey(t, "key-1", generateEC(t))
+	ks := New(configs.NewAuthConfig(configs.SigningKeys(key), configs.Secret("secret")))
+
+	t.Run("given token signed with shared secret should be rejected", func(t *testing.T) {
+		hs := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+		hs.Header["kid"] = "key-1"
+		signed, err := hs.SignedString([]byte("secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := jwt.Parse(signed, ks.Keyfunc); err == nil {
+			t.Error("Parse() expected error for HS256 token")
+		}
+	})
+
+	t.Run("given token signed with unknown key should be rejected", func(t *testing.T) {
+		other := New(configs.NewAuthConfig(configs.SigningKeys(newKey(t, "unknown", generateEC(t)))))
+		signed, err := other.Sign(claims())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := jwt.Parse(signed, ks.Keyfunc); err == nil {
+			t.Error("Parse() expected error for unknown kid")
+		}
+	})
+
+	t.Run("given only verification keys should fail to sign", func(t *testing.T) {
+		verifyOnly := configs.SigningKey{ID: key.ID, Algorithm: key.Algorithm, PublicKey: key.PublicKey}
+		if _, err := New(configs.NewAuthConfig(configs.SigningKeys(verifyOnly))).Sign(claims()); err != ErrNoSigningKey {
+			t.Errorf("Sign() error = %v, want %v", err, ErrNoSigningKey)
+		}
+	})
+}
+
+func TestKeySet_SharedSecret(t *testing.T) {
+	ks := New(configs.NewAuthConfig(configs.Secret("secret")))
+
+	signed, err := ks.Sign(claims())
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if _, err := jwt.Parse(signed, ks.Keyfunc, jwt.WithValidMethods(ks.Methods())); err != nil {
+		t.Errorf("Parse() error = %v", err)
+	}
+	if got := len(ks.PublicKeys().Keys); got != 0 {
+		t.Errorf("PublicKeys() len = %d, want 0", got)
+	}
+}
+
+func TestKeySet_PublicKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := New(configs.NewAuthConfig(configs.SigningKeys(
+		newKey(t, "rsa", rsaKey),
+		newKey(t, "ec", generateEC(t)),
+		newKey(t, "ed", edPub),
+	)))
+
+	got := ks.PublicKeys().Keys
+	if len(got) != 3 {
+		t.Fatalf("PublicKeys() len = %d, want 3", len(got))
+	}
+
+	want := []JWK{
+		{Kty: "RSA", Kid: "rsa", Use: "sig", Alg: "RS256", E: "AQAB"},
+		{Kty: "EC", Kid: "ec", Use: "sig", Alg: "ES256", Crv: "P-256"},
+		{Kty: "OKP", Kid: "ed", Use: "sig", Alg: "EdDSA", Crv: "Ed25519"},
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.Kty != w.Kty || g.Kid != w.Kid || g.Use != w.Use || g.Alg != w.Alg || g.Crv != w.Crv {
+			t.Errorf("PublicKeys()[%d] = %+v, want %+v", i, g, w)
+		}
+		if w.E != "" && g.E != w.E {
+			t.Errorf("PublicKeys()[%d] e = %s, want %s", i, g.E, w.E)
+		}
+	}
+	if got[0].N == "" || got[1].X == "" || got[1].Y == "" || got[2].X == "" {
+		t.Errorf("PublicKeys() missing key material: %+v", got)
+	}
+}
+
+func newKey(t *testing.T, id string, key any) configs.SigningKey {
+	t.Helper()
+	k, err := configs.NewSigningKey(id, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func generateEC(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func claims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"sub": "user",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Minute).Unix(),
+	}
+}
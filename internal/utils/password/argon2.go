@@ -0,0 +1,104 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fmiskovic/go-starter/internal/core/configs"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+var ErrInvalidParams = errors.New("invalid argon2id parameters")
+
+// Argon2id is implementation of Hasher interface using argon2id.
+// Hashes are PHC strings, e.g. $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>, salt and hash are base64 encoded without padding.
+type Argon2id struct {
+	memory  uint32 // KiB
+	time    uint32
+	threads uint8
+}
+
+// NewArgon2id instantiate new Argon2id hasher with memory in KiB, number of passes over the memory and degree of parallelism.
+func NewArgon2id(memory uint32, time uint32, threads uint8) Argon2id {
+	return Argon2id{memory: memory, time: time, threads: threads}
+}
+
+// Hash returns argon2id hash of the password with random salt.
+func (a Argon2id) Hash(password string) (string, error) {
+	if a.time < 1 || a.threads < 1 || a.memory < 8*uint32(a.threads) {
+		return "", ErrInvalidParams
+	}
+
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.time, a.memory, a.threads, argon2KeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, a.memory, a.time, a.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify returns true if the password matches the argon2id hash, parameters are taken from the hash.
+func (a Argon2id) Verify(password string, hash string) (bool, error) {
+	if algorithm(hash) != configs.HashArgon2id {
+		return false, ErrUnsupportedHash
+	}
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// NeedsRehash returns true if the hash is not argon2id hash of the hasher parameters.
+func (a Argon2id) NeedsRehash(hash string) bool {
+	if algorithm(hash) != configs.HashArgon2id {
+		return true
+	}
+	params, salt, key, err := parseArgon2id(hash)
+	return err != nil || params != a || len(salt) != argon2SaltLen || len(key) != argon2KeyLen
+}
+
+// parseArgon2id returns parameters, salt and key of the argon2id PHC string.
+func parseArgon2id(hash string) (Argon2id, []byte, []byte, error) {
+	var params Argon2id
+
+	// "", "argon2id", "v=19", "m=65536,t=3,p=4", salt, key
+	fields := strings.Split(hash, "$")
+	if len(fields) != 6 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(fields[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if params.time < 1 || params.threads < 1 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(fields[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+	return params, salt, key, nil
+}
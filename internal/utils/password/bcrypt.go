@@ -0,0 +1,60 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fmiskovic/go-starter/internal/core/configs"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptMaxLength is the number of password bytes bcrypt uses, anything longer would be silently ignored.
+const bcryptMaxLength = 72
+
+// Bcrypt is implementation of Hasher interface using bcrypt.
+// Passwords longer than 72 bytes are rejected instead of being truncated.
+type Bcrypt struct {
+	cost int
+}
+
+// NewBcrypt instantiate new Bcrypt hasher with the cost, from 4 to 31.
+func NewBcrypt(cost int) Bcrypt {
+	return Bcrypt{cost: cost}
+}
+
+// Hash returns bcrypt hash of the password.
+func (b Bcrypt) Hash(password string) (string, error) {
+	if b.cost < bcrypt.MinCost || b.cost > bcrypt.MaxCost {
+		return "", bcrypt.InvalidCostError(b.cost)
+	}
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	return string(bytes), err
+}
+
+// Verify returns true if the password matches the bcrypt hash, the cost is taken from the hash.
+func (b Bcrypt) Verify(password string, hash string) (bool, error) {
+	if algorithm(hash) != configs.HashBcrypt {
+		return false, ErrUnsupportedHash
+	}
+	if len(password) > bcryptMaxLength {
+		return false, nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+	return true, nil
+}
+
+// NeedsRehash returns true if the hash is not bcrypt hash of the hasher cost.
+func (b Bcrypt) NeedsRehash(hash string) bool {
+	if algorithm(hash) != configs.HashBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != b.cost
+}
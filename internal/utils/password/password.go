@@ -1,13 +1,71 @@
 package password
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"errors"
+	"strings"
 
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-	return string(bytes), err
+	"github.com/fmiskovic/go-starter/internal/core/configs"
+)
+
+var (
+	ErrUnsupportedHash = errors.New("password hash algorithm is not supported")
+	ErrInvalidHash     = errors.New("password hash is malformed")
+)
+
+// Hasher hashes passwords into PHC strings which identify the algorithm and its parameters,
+// e.g. $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>. Bcrypt hashes keep their modular crypt format, e.g. $2a$14$<salt+hash>.
+type Hasher interface {
+	// Hash returns hash of the password made with the algorithm and parameters of the hasher.
+	Hash(password string) (string, error)
+	// Verify returns true if the password matches the hash.
+	// ErrUnsupportedHash is returned if the hash is made with algorithm the hasher does not support.
+	Verify(password string, hash string) (bool, error)
+	// NeedsRehash returns true if the hash is not made with the algorithm and parameters of the hasher.
+	NeedsRehash(hash string) bool
+}
+
+// NewHasher instantiate Hasher from the config. New hashes are made with the configured algorithm,
+// hashes of all supported algorithms are verified so they keep working until they are rehashed.
+// Argon2id is used unless bcrypt is configured.
+func NewHasher(cfg configs.PasswordHashing) Hasher {
+	if cfg.Algorithm == configs.HashBcrypt {
+		return hasher{preferred: NewBcrypt(cfg.BcryptCost)}
+	}
+	return hasher{preferred: NewArgon2id(cfg.Argon2Memory, cfg.Argon2Time, cfg.Argon2Threads)}
+}
+
+// hasher hashes with the preferred Hasher and verifies hashes of any supported algorithm.
+type hasher struct {
+	preferred Hasher
+}
+
+func (h hasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+func (h hasher) Verify(password string, hash string) (bool, error) {
+	switch algorithm(hash) {
+	case configs.HashBcrypt:
+		return Bcrypt{}.Verify(password, hash)
+	case configs.HashArgon2id:
+		return Argon2id{}.Verify(password, hash)
+	}
+	return false, ErrUnsupportedHash
+}
+
+func (h hasher) NeedsRehash(hash string) bool {
+	return h.preferred.NeedsRehash(hash)
 }
 
-func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+// algorithm returns name of the algorithm the hash is made with, as identified by its first $ delimited field.
+func algorithm(hash string) string {
+	if !strings.HasPrefix(hash, "$") {
+		return ""
+	}
+	id, _, _ := strings.Cut(hash[1:], "$")
+	switch id {
+	case "2a", "2b", "2y":
+		return configs.HashBcrypt
+	}
+	return id
 }
@@ -1,74 +1,154 @@
 package password
 
 import (
+	"errors"
+	"strings"
 	"testing"
+
+	"github.com/fmiskovic/go-starter/internal/core/configs"
 )
 
-func TestHashPassword(t *testing.T) {
+func TestHasher_Hash(t *testing.T) {
+	hashers := map[string]Hasher{
+		"bcrypt":   NewHasher(configs.PasswordHashing{Algorithm: configs.HashBcrypt, BcryptCost: 4}),
+		"argon2id": NewHasher(configs.PasswordHashing{Algorithm: configs.HashArgon2id, Argon2Memory: 1024, Argon2Time: 1, Argon2Threads: 1}),
+	}
+
 	type args struct {
 		password string
 	}
 	tests := []struct {
 		name    string
+		hasher  string
 		args    args
-		verify  func(password string, hash string, t *testing.T)
+		prefix  string
 		wantErr bool
 	}{
+		{name: "given password should return bcrypt hash", hasher: "bcrypt", args: args{password: "Password1234!"}, prefix: "$2a$04$"},
+		{name: "given empty password should return bcrypt hash", hasher: "bcrypt", args: args{password: ""}, prefix: "$2a$04$"},
+		{name: "given blank password should return bcrypt hash", hasher: "bcrypt", args: args{password: " "}, prefix: "$2a$04$"},
 		{
-			name: "given adminadmin password should return adminadmin hash",
-			args: args{password: "adminadmin"},
-			verify: func(password string, hash string, t *testing.T) {
-				if !CheckPasswordHash(password, hash) {
-					t.Errorf("Verify failed. The password: [%s] doesnot match the hash: [%s].", password, hash)
-				}
-			},
-			wantErr: false,
-		},
-		{
-			name: "given password should return hash",
-			args: args{password: "Password1234!"},
-			verify: func(password string, hash string, t *testing.T) {
-				if !CheckPasswordHash(password, hash) {
-					t.Errorf("Verify failed. The password: [%s] doesnot match the hash: [%s].", password, hash)
-				}
-			},
-			wantErr: false,
-		},
-		{
-			name:    "given long password should return error",
+			name:    "given long password should return bcrypt error",
+			hasher:  "bcrypt",
 			args:    args{password: "Password1234!!!!Password1234!!!!Password1234!!!!Password1234!!!!Password1234!!!!"},
-			verify:  func(password string, hash string, t *testing.T) {},
 			wantErr: true,
 		},
+		{name: "given password should return argon2id hash", hasher: "argon2id", args: args{password: "Password1234!"}, prefix: "$argon2id$v=19$m=1024,t=1,p=1$"},
+		{name: "given empty password should return argon2id hash", hasher: "argon2id", args: args{password: ""}, prefix: "$argon2id$v=19$m=1024,t=1,p=1$"},
 		{
-			name: "given empty password should return hash",
-			args: args{password: ""},
-			verify: func(password string, hash string, t *testing.T) {
-				if !CheckPasswordHash(password, hash) {
-					t.Errorf("Verify failed. The password: [%s] doesnot match the hash: [%s].", password, hash)
-				}
-			},
-			wantErr: false,
-		},
-		{
-			name: "given blank password should return hash",
-			args: args{password: " "},
-			verify: func(password string, hash string, t *testing.T) {
-				if !CheckPasswordHash(password, hash) {
-					t.Errorf("Verify failed. The password: [%s] doesnot match the hash: [%s].", password, hash)
-				}
-			},
-			wantErr: false,
+			name:   "given long password should return argon2id hash",
+			hasher: "argon2id",
+			args:   args{password: "Password1234!!!!Password1234!!!!Password1234!!!!Password1234!!!!Password1234!!!!"},
+			prefix: "$argon2id$v=19$m=1024,t=1,p=1$",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := HashPassword(tt.args.password)
+			h := hashers[tt.hasher]
+
+			got, err := h.Hash(tt.args.password)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("HashPassword() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("Hash() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			tt.verify(tt.args.password, got, t)
+			if tt.wantErr {
+				return
+			}
+			if !strings.HasPrefix(got, tt.prefix) {
+				t.Errorf("Hash() = %s, want prefix %s", got, tt.prefix)
+			}
+
+			ok, err := h.Verify(tt.args.password, got)
+			if err != nil || !ok {
+				t.Errorf("Verify failed. The password: [%s] does not match the hash: [%s], error: %v.", tt.args.password, got, err)
+			}
+			if ok, _ := h.Verify(tt.args.password+"x", got); ok {
+				t.Errorf("Verify failed. Wrong password matches the hash: [%s].", got)
+			}
+			if h.NeedsRehash(got) {
+				t.Errorf("NeedsRehash() = true for the hash just made: [%s]", got)
+			}
+		})
+	}
+}
+
+func TestHasher_Verify(t *testing.T) {
+	h := NewHasher(configs.PasswordHashing{Algorithm: configs.HashArgon2id, Argon2Memory: 1024, Argon2Time: 1, Argon2Threads: 1})
+
+	// bcrypt hash of "password1" with cost 14
+	bcryptHash := "$2a$14$/uuEnoIH.v4TKiIp8x1pze58QvmA.rKpmLLrQ0/8Y910SaTo8UR1K"
+	// argon2id hash of "password1" with other parameters than the hasher ones
+	argonHash, err := NewArgon2id(2048, 2, 2).Hash("password1")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		password    string
+		hash        string
+		want        bool
+		wantErr     error
+		needsRehash bool
+	}{
+		{name: "given bcrypt hash should verify it and need rehash", password: "password1", hash: bcryptHash, want: true, needsRehash: true},
+		{name: "given wrong password and bcrypt hash should return false", password: "password2", hash: bcryptHash, want: false, needsRehash: true},
+		{name: "given password longer than bcrypt limit should return false", password: "password1" + strings.Repeat("x", 72), hash: bcryptHash, want: false, needsRehash: true},
+		{name: "given argon2id hash with other parameters should verify it and need rehash", password: "password1", hash: argonHash, want: true, needsRehash: true},
+		{name: "given unknown algorithm should return error", password: "password1", hash: "$scrypt$ln=15,r=8,p=1$c2FsdA$aGFzaA", wantErr: ErrUnsupportedHash, needsRehash: true},
+		{name: "given plain text should return error", password: "password1", hash: "password1", wantErr: ErrUnsupportedHash, needsRehash: true},
+		{name: "given malformed argon2id hash should return error", password: "password1", hash: "$argon2id$v=19$m=1024,t=1$c2FsdA$aGFzaA", wantErr: ErrInvalidHash, needsRehash: true},
+		{name: "given unknown argon2 version should return error", password: "password1", hash: "$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$aGFzaA", wantErr: ErrInvalidHash, needsRehash: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.Verify(tt.password, tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+			if h.NeedsRehash(tt.hash) != tt.needsRehash {
+				t.Errorf("NeedsRehash() = %v, want %v", !tt.needsRehash, tt.needsRehash)
+			}
+		})
+	}
+}
+
+func TestBcrypt_NeedsRehash(t *testing.T) {
+	hash, err := NewBcrypt(4).Hash("password1")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	if NewBcrypt(4).NeedsRehash(hash) {
+		t.Errorf("NeedsRehash() = true for the same cost")
+	}
+	if !NewBcrypt(5).NeedsRehash(hash) {
+		t.Errorf("NeedsRehash() = false for other cost")
+	}
+	if !NewArgon2id(1024, 1, 1).NeedsRehash(hash) {
+		t.Errorf("NeedsRehash() = false for other algorithm")
+	}
+}
+
+func TestHasher_InvalidParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher Hasher
+	}{
+		{name: "given too low bcrypt cost should return error", hasher: NewBcrypt(3)},
+		{name: "given too high bcrypt cost should return error", hasher: NewBcrypt(32)},
+		{name: "given zero argon2id time should return error", hasher: NewArgon2id(1024, 0, 1)},
+		{name: "given zero argon2id threads should return error", hasher: NewArgon2id(1024, 1, 0)},
+		{name: "given too little argon2id memory should return error", hasher: NewArgon2id(8, 1, 2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.hasher.Hash("password1"); err == nil {
+				t.Errorf("Hash() error = nil, want error")
+			}
 		})
 	}
 }
@@ -0,0 +1,10 @@
+package testx
+
+import "time"
+
+// WaitNextSecond sleeps until the next whole second.
+// Token issue time has second precision, so tokens issued before are covered by revocation of all user's tokens made after.
+func WaitNextSecond() {
+	now := time.Now()
+	time.Sleep(now.Truncate(time.Second).Add(time.Second).Sub(now))
+}
@@ -84,7 +84,25 @@ func SetUpDb() (*TestDB, error) {
 			}
 
 			// seed db
-			bunDb.RegisterModel((*domain.Entity)(nil), (*user.User)(nil), (*security.Role)(nil), (*security.Credentials)(nil))
+			bunDb.RegisterModel(
+				(*domain.Entity)(nil),
+				(*user.User)(nil),
+				(*security.Role)(nil),
+				(*security.Permission)(nil),
+				(*user.UserRole)(nil),
+				(*security.RolePermission)(nil),
+				(*security.Credentials)(nil),
+				(*security.EmailConfirmation)(nil),
+				(*security.RefreshToken)(nil),
+				(*security.PasswordReset)(nil),
+				(*security.PasswordHistory)(nil),
+				(*security.MfaSecret)(nil),
+				(*security.RecoveryCode)(nil),
+				(*security.MfaChallenge)(nil),
+				(*security.ApiKey)(nil),
+				(*security.Identity)(nil),
+				(*security.Session)(nil),
+			)
 			fixture := dbfixture.New(bunDb, dbfixture.WithTruncateTables())
 			err = fixture.Load(ctx, os.DirFS("testdata"), "fixture.yml")
 			if err != nil {
@@ -101,7 +119,7 @@ func SetUpDb() (*TestDB, error) {
 		BunDb: bunDb,
 		Shutdown: func() {
 			if err := terminateContainer(ctx, postgres); err != nil {
-				slog.Warn("failed to terminate container", err)
+				slog.Warn("failed to terminate container", "error", err)
 			}
 			cancel()
 		},
@@ -144,7 +162,7 @@ func migrateDB(ctx context.Context, db *bun.DB) error {
 	}
 
 	if err := migrator.Lock(ctx); err != nil {
-		slog.Warn("lock failed but it's ok, error message:", err)
+		slog.Warn("lock failed but it's ok", "error", err)
 	}
 	defer migrator.Unlock(ctx) //nolint:errcheck
 
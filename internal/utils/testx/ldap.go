@@ -0,0 +1,230 @@
+package testx
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// ldap protocol operations and result codes handled by LdapServer.
+const (
+	ldapBindRequest      = 0
+	ldapBindResponse     = 1
+	ldapUnbindRequest    = 2
+	ldapSearchRequest    = 3
+	ldapSearchResultItem = 4
+	ldapSearchResultDone = 5
+
+	ldapSuccess            = 0
+	ldapProtocolError      = 2
+	ldapInvalidCredentials = 49
+	ldapUnwillingToPerform = 53
+)
+
+// LdapEntry is user or group entry served by LdapServer.
+type LdapEntry struct {
+	DN         string
+	Password   string // Password accepted by simple bind, entry can not bind if it is empty
+	Attributes map[string][]string
+}
+
+// LdapServer is in-process stand-in for LDAP server.
+// It supports simple bind and search with and, or, not, equality and presence filters, which is enough for signing in.
+type LdapServer struct {
+	listener net.Listener
+	Entries  []LdapEntry // Entries served by the server, tests may change them between requests
+	wg       sync.WaitGroup
+}
+
+// SetUpLdapServer starts LDAP server stand-in serving the entries, it should be closed when test is done.
+func SetUpLdapServer(entries ...LdapEntry) (*LdapServer, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &LdapServer{listener: l, Entries: entries}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// URL returns ldap url of the server.
+func (s *LdapServer) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+// Close stops the server.
+func (s *LdapServer) Close() {
+	_ = s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *LdapServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle serves requests of the connection until client unbinds or disconnects.
+func (s *LdapServer) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id := packet.Children[0].Value
+		op := packet.Children[1]
+
+		var responses []*ber.Packet
+		switch op.Tag {
+		case ldapBindRequest:
+			responses = append(responses, ldapResult(ldapBindResponse, s.bind(op), ""))
+		case ldapSearchRequest:
+			responses = append(s.search(op), ldapResult(ldapSearchResultDone, ldapSuccess, ""))
+		case ldapUnbindRequest:
+			return
+		default:
+			responses = append(responses, ldapResult(op.Tag+1, ldapUnwillingToPerform, "operation is not supported"))
+		}
+
+		for _, res := range responses {
+			envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+			envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+			envelope.AppendChild(res)
+			if _, err := conn.Write(envelope.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// bind returns result code of simple bind, anonymous bind is allowed.
+func (s *LdapServer) bind(op *ber.Packet) int {
+	if len(op.Children) < 3 {
+		return ldapProtocolError
+	}
+	dn, _ := op.Children[1].Value.(string)
+	password := op.Children[2].Data.String()
+	if dn == "" && password == "" {
+		return ldapSuccess
+	}
+
+	for _, e := range s.Entries {
+		if strings.EqualFold(e.DN, dn) && e.Password != "" && e.Password == password {
+			return ldapSuccess
+		}
+	}
+	return ldapInvalidCredentials
+}
+
+// search returns entries within the base DN that match the filter.
+func (s *LdapServer) search(op *ber.Packet) []*ber.Packet {
+	if len(op.Children) < 8 {
+		return nil
+	}
+	base, _ := op.Children[0].Value.(string)
+	filter := op.Children[6]
+
+	var attributes []string
+	for _, a := range op.Children[7].Children {
+		if name, ok := a.Value.(string); ok {
+			attributes = append(attributes, name)
+		}
+	}
+
+	var res []*ber.Packet
+	for _, e := range s.Entries {
+		if !strings.HasSuffix(strings.ToLower(e.DN), strings.ToLower(base)) || !ldapMatches(e, filter) {
+			continue
+		}
+
+		item := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapSearchResultItem, nil, "Search Result Entry")
+		item.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "DN"))
+		attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+		for name, values := range e.Attributes {
+			if len(attributes) > 0 && !containsFold(attributes, name) {
+				continue
+			}
+			attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+			attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+			vals := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+			for _, v := range values {
+				vals.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+			}
+			attr.AppendChild(vals)
+			attrs.AppendChild(attr)
+		}
+		item.AppendChild(attrs)
+		res = append(res, item)
+	}
+	return res
+}
+
+// ldapMatches evaluates and, or, not, equality and presence filters against the entry.
+func ldapMatches(e LdapEntry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case 0: // and
+		for _, f := range filter.Children {
+			if !ldapMatches(e, f) {
+				return false
+			}
+		}
+		return true
+	case 1: // or
+		for _, f := range filter.Children {
+			if ldapMatches(e, f) {
+				return true
+			}
+		}
+		return false
+	case 2: // not
+		return len(filter.Children) == 1 && !ldapMatches(e, filter.Children[0])
+	case 3: // equality
+		if len(filter.Children) != 2 {
+			return false
+		}
+		name, _ := filter.Children[0].Value.(string)
+		value, _ := filter.Children[1].Value.(string)
+		return containsFold(attributeValues(e, name), value)
+	case 7: // present
+		return len(attributeValues(e, filter.Data.String())) > 0
+	default:
+		return false
+	}
+}
+
+func attributeValues(e LdapEntry, name string) []string {
+	for k, v := range e.Attributes {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+func containsFold(values []string, v string) bool {
+	for _, s := range values {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// ldapResult creates LDAPResult of the operation.
+func ldapResult(op ber.Tag, code int, msg string) *ber.Packet {
+	res := ber.Encode(ber.ClassApplication, ber.TypeConstructed, op, nil, "Response")
+	res.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, msg, "Diagnostic Message"))
+	return res
+}
@@ -10,14 +10,14 @@ type TestServer struct {
 	App    *fiber.App
 }
 
-// SetUpServer helps to set up test Server.
-func SetUpServer() (*TestServer, error) {
+// SetUpServer helps to set up test Server, optional config is passed to the fiber app, e.g. to render views.
+func SetUpServer(config ...fiber.Config) (*TestServer, error) {
 	testDb, err := SetUpDb()
 	if err != nil {
 		return nil, err
 	}
 
-	app := fiber.New()
+	app := fiber.New(config...)
 	app.Use(recover.New())
 
 	return &TestServer{
@@ -0,0 +1,49 @@
+package token
+
+import (
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	first, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	second, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if first == second {
+		t.Errorf("Generate() returned the same token twice: %s", first)
+	}
+	if len(first) != 43 {
+		t.Errorf("Generate() token length = %d, want 43", len(first))
+	}
+}
+
+func TestHash(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{
+			name:  "given token should return sha256 hex hash",
+			token: "valid-code",
+			want:  "6781d27e8c64ffe9d0837fded2aa2b801ddb3309cda314c11b9ab664d96cfe80",
+		},
+		{
+			name:  "given empty token should return sha256 hex hash of empty string",
+			token: "",
+			want:  "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Hash(tt.token); got != tt.want {
+				t.Errorf("Hash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+// base32 encoded "12345678901234567890", the SHA1 secret from RFC 6238 test vectors
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name string
+		time int64
+		want string
+	}{
+		{name: "given time 59 should return rfc code", time: 59, want: "287082"},
+		{name: "given time 1111111109 should return rfc code", time: 1111111109, want: "081804"},
+		{name: "given time 1111111111 should return rfc code", time: 1111111111, want: "050471"},
+		{name: "given time 1234567890 should return rfc code", time: 1234567890, want: "005924"},
+		{name: "given time 2000000000 should return rfc code", time: 2000000000, want: "279037"},
+		{name: "given time 20000000000 should return rfc code", time: 20000000000, want: "353130"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Code(rfcSecret, Step(time.Unix(tt.time, 0)))
+			if err != nil {
+				t.Fatalf("Code() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Code() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert := is.New(t)
+
+	secret, err := GenerateSecret()
+	assert.NoErr(err)
+	assert.Equal(len(secret), 32)
+
+	now := time.Now()
+	code, err := Code(secret, Step(now))
+	assert.NoErr(err)
+
+	tests := []struct {
+		name   string
+		code   string
+		time   time.Time
+		wantOk bool
+	}{
+		{name: "given current code should be valid", code: code, time: now, wantOk: true},
+		{name: "given code from previous period should be valid", code: code, time: now.Add(Period), wantOk: true},
+		{name: "given code from next period should be valid", code: code, time: now.Add(-Period), wantOk: true},
+		{name: "given outdated code should be invalid", code: code, time: now.Add(3 * Period), wantOk: false},
+		{name: "given wrong code should be invalid", code: "abcdef", time: now, wantOk: false},
+		{name: "given code of wrong length should be invalid", code: code[:5], time: now, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(secret, tt.code, tt.time)
+			assert.Equal(ok, tt.wantOk)
+			if ok {
+				assert.Equal(step, Step(now))
+			}
+		})
+	}
+}
+
+func TestURI(t *testing.T) {
+	assert := is.New(t)
+
+	uri := URI("go-starter", "john@smith.com", rfcSecret)
+	assert.True(strings.HasPrefix(uri, "otpauth://totp/go-starter:john@smith.com?"))
+	assert.True(strings.Contains(uri, "secret="+rfcSecret))
+	assert.True(strings.Contains(uri, "issuer=go-starter"))
+	assert.True(strings.Contains(uri, "digits=6"))
+	assert.True(strings.Contains(uri, "period=30"))
+}
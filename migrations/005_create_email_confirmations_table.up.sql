@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS email_confirmations (
+    id UUID PRIMARY KEY,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id UUID NOT NULL,
+    code_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at timestamp NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX email_confirmations_user_id_index ON email_confirmations (user_id);
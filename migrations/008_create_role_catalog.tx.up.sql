@@ -0,0 +1,77 @@
+-- Converts free-form per user role rows into a managed catalog of roles and permissions.
+-- Catalog ids are derived from names, so existing assignments can be mapped without lookups.
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id UUID PRIMARY KEY,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description VARCHAR(255)
+);
+
+CREATE TABLE IF NOT EXISTS role_catalog (
+    id UUID PRIMARY KEY,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description VARCHAR(255)
+);
+
+INSERT INTO role_catalog (id, name)
+SELECT DISTINCT md5('role:' || name)::uuid, name
+FROM roles;
+
+INSERT INTO role_catalog (id, name, description)
+VALUES
+  (md5('role:ROLE_ADMIN')::uuid, 'ROLE_ADMIN', 'Full access to user and role management'),
+  (md5('role:ROLE_MODERATOR')::uuid, 'ROLE_MODERATOR', 'Can view, enable, disable and sign out users'),
+  (md5('role:ROLE_USER')::uuid, 'ROLE_USER', 'Default role of registered users')
+ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description;
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id UUID NOT NULL,
+    role_id UUID NOT NULL,
+    PRIMARY KEY (user_id, role_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (role_id) REFERENCES role_catalog(id) ON DELETE CASCADE
+);
+
+INSERT INTO user_roles (user_id, role_id)
+SELECT DISTINCT r.user_id, md5('role:' || r.name)::uuid
+FROM roles r
+JOIN users u ON u.id = r.user_id;
+
+DROP TABLE roles;
+ALTER TABLE role_catalog RENAME TO roles;
+ALTER INDEX role_catalog_pkey RENAME TO roles_pkey;
+ALTER INDEX role_catalog_name_key RENAME TO roles_name_key;
+
+CREATE INDEX user_roles_role_id_index ON user_roles (role_id);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id UUID NOT NULL,
+    permission_id UUID NOT NULL,
+    PRIMARY KEY (role_id, permission_id),
+    FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
+
+INSERT INTO permissions (id, name, description)
+VALUES
+  (md5('permission:user:read')::uuid, 'user:read', 'View users'),
+  (md5('permission:user:write')::uuid, 'user:write', 'Create and update users'),
+  (md5('permission:user:delete')::uuid, 'user:delete', 'Delete users'),
+  (md5('permission:user:roles')::uuid, 'user:roles', 'Assign and remove user roles'),
+  (md5('permission:user:enable')::uuid, 'user:enable', 'Enable and disable users'),
+  (md5('permission:user:logout')::uuid, 'user:logout', 'Sign out users from all sessions'),
+  (md5('permission:role:read')::uuid, 'role:read', 'View roles and permissions'),
+  (md5('permission:role:write')::uuid, 'role:write', 'Manage roles and permissions');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT md5('role:ROLE_ADMIN')::uuid, id
+FROM permissions;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT md5('role:ROLE_MODERATOR')::uuid, id
+FROM permissions
+WHERE name IN ('user:read', 'user:enable', 'user:logout');
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key VARCHAR(320) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until timestamp
+);
+
+CREATE INDEX login_attempts_last_failure_at_index ON login_attempts (last_failure_at);
@@ -0,0 +1,7 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS users_email_trgm_index ON users USING gin (email gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS users_full_name_trgm_index ON users USING gin (full_name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS users_location_trgm_index ON users USING gin (location gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS users_created_at_index ON users (created_at);
+CREATE INDEX IF NOT EXISTS users_updated_at_index ON users (updated_at);
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at timestamp;
+
+CREATE INDEX IF NOT EXISTS users_deleted_at_index ON users (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE email_confirmations ADD COLUMN IF NOT EXISTS email VARCHAR(255);
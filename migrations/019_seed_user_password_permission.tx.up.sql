@@ -0,0 +1,9 @@
+INSERT INTO permissions (id, name, description)
+VALUES (md5('permission:user:password')::uuid, 'user:password', 'Force new password of the user')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT md5('role:ROLE_ADMIN')::uuid, id
+FROM permissions
+WHERE name = 'user:password'
+ON CONFLICT DO NOTHING;
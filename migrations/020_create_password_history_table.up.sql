@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS password_history (
+    id UUID PRIMARY KEY,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version bigint NOT NULL DEFAULT 1,
+    user_id UUID NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX password_history_user_id_index ON password_history (user_id, created_at);